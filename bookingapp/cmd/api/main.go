@@ -1,85 +1,264 @@
 package main
 
 import (
-	"bookingapp/internal/infrastructure/db"
-	"bookingapp/internal/infrastructure/db/models"
-	mysqlrepo "bookingapp/internal/infrastructure/repository/mysql"
-	userrepo "bookingapp/internal/infrastructure/repository/mysql/user"
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/gateway"
+	"bookingapp/internal/infrastructure/auth"
+	"bookingapp/internal/infrastructure/payment"
+	"bookingapp/internal/infrastructure/search"
+	"bookingapp/internal/infrastructure/taxconfig"
 	httpi "bookingapp/internal/interface/http"
 	"bookingapp/internal/usecase"
+	"context"
+	"crypto/rand"
 	"log"
 	"net/http"
 	"os"
-	"strconv"
-
-	"gorm.io/gorm"
+	"strings"
+	"time"
+	_ "time/tzdata" // HOTEL_TIMEZONE を tzdata の無いコンテナでも読めるように
 )
 
 func main() {
-	// ---- 環境変数から接続情報 ----
-	host := getEnv("DB_HOST", "127.0.0.1")
-	port := getEnvInt("DB_PORT", 3306)
-	user := getEnv("DB_USER", "root")
-	pass := getEnv("DB_PASS", "password")
-	name := getEnv("DB_NAME", "booking")
-
-	gdb, err := db.Open(db.Config{
-		User: user, Pass: pass, Host: host, Port: port, Name: name,
-	})
+	st, err := openStorage(getEnv("STORAGE", "mysql"))
 	if err != nil {
-		log.Fatalf("open db: %v", err)
-	}
-	if err := db.Ping(gdb); err != nil {
-		log.Fatalf("ping db: %v", err)
+		log.Fatalf("storage: %v", err)
 	}
-	if err := db.Migrate(gdb); err != nil {
-		log.Fatalf("migrate: %v", err)
+
+	taxes := taxRuleSource()
+	reservationUC := &usecase.ReservationUsecase{
+		Plans: st.plans, Resv: st.reservations, Users: st.users, Coupons: st.coupons,
+		Payments: st.payments, Tx: st.tx, Taxes: taxes, Gateway: paymentGateway(),
+		CancellationPolicies: st.policies, Location: hotelLocation(),
 	}
-	if err := seedIfEmpty(gdb); err != nil {
-		log.Fatalf("seed: %v", err)
+	hasher := auth.BcryptHasher{}
+	userUC := &usecase.UserUsecase{Users: st.users, Hasher: hasher}
+	authUC := &usecase.AuthUsecase{
+		Users: st.users, Sessions: st.sessions, Roles: st.roles, Hasher: hasher,
+		Tokens:     auth.HMACSigner{Secret: authSecret()},
+		AccessTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
 	}
 
-	planRepo := mysqlrepo.NewPlanRepo(gdb)
-	resvRepo := mysqlrepo.NewReservationRepo(gdb)
-	userRepo := userrepo.NewUserRepo(gdb)
-
-	reservationUC := &usecase.ReservationUsecase{Plans: planRepo, Resv: resvRepo, Users: userRepo}
-	userUC := &usecase.UserUsecase{Users: userRepo}
+	planUC := &usecase.PlanUsecase{Plans: st.plans, Index: planSearchIndex(), Taxes: taxes, CancellationPolicies: st.policies}
+	if n, err := planUC.Reindex(context.Background()); err != nil {
+		log.Fatalf("index plans: %v", err)
+	} else if planUC.Index != nil {
+		log.Printf("indexed %d plans for search", n)
+		go reindexPlans(planUC, getEnvDuration("PLAN_SEARCH_REINDEX_INTERVAL", time.Minute))
+	}
+	couponUC := &usecase.CouponUsecase{Coupons: st.coupons}
+	policyUC := &usecase.CancellationPolicyUsecase{Policies: st.policies}
+	roleUC := &usecase.RoleUsecase{Users: st.users, Roles: st.roles}
+	idempotencyUC := &usecase.IdempotencyUsecase{Records: st.idempotency, TTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)}
+	go purgeIdempotencyKeys(idempotencyUC, time.Hour)
+	if err := bootstrapAdmin(context.Background(), userUC, st); err != nil {
+		log.Fatalf("bootstrap admin: %v", err)
+	}
 
 	reservationHandler := &httpi.ReservationHandler{UC: reservationUC}
 	userHandler := &httpi.UserHandler{UC: userUC}
+	authHandler := &httpi.AuthHandler{UC: authUC}
+	planHandler := &httpi.PlanHandler{UC: planUC}
+	adminHandler := &httpi.AdminHandler{Roles: roleUC}
+	couponHandler := &httpi.CouponHandler{UC: couponUC}
+	policyHandler := &httpi.CancellationPolicyHandler{UC: policyUC}
+	idem := &httpi.IdempotencyHandler{UC: idempotencyUC}
+	// 認証が必要なルート。権限を並べた場合はそのすべてが必要（本人かどうかはユースケースで判定）
+	require := authHandler.Require
 
 	mux := http.NewServeMux()
 
-	// 予約登録、予約一覧、予約取得、プラン検索、ユーザ登録API
-	mux.HandleFunc("POST /reservations", reservationHandler.Create)
-	mux.HandleFunc("GET /reservations", reservationHandler.List)
-	mux.HandleFunc("GET /reservations/", reservationHandler.Get)
-	mux.HandleFunc("GET /plans", reservationHandler.SearchPlans)
+	// 予約登録、予約一覧、予約取得、予約変更、予約キャンセル、プラン検索、ユーザ登録API
+	// 他人の予約・全件一覧は reservations:manage_all を持つ staff / admin だけ。
+	// 予約・プラン・ユーザーの取得は ETag を返し、変更（PATCH / PUT / DELETE、キャンセル・請求、ロールの付与・剥奪）には If-Match が必要。
+	// 予約登録は Idempotency-Key ヘッダーを付ければ再送しても二重に予約しない
+	mux.HandleFunc("POST /reservations", require(idem.Wrap(reservationHandler.Create), entity.PermReservationsBook))
+	mux.HandleFunc("GET /reservations", require(reservationHandler.List, entity.PermReservationsBook))
+	mux.HandleFunc("GET /reservations/", require(reservationHandler.Get, entity.PermReservationsBook))
+	mux.HandleFunc("PATCH /reservations/{id}", require(reservationHandler.Modify, entity.PermReservationsBook))
+	mux.HandleFunc("POST /reservations/{id}/cancel", require(reservationHandler.Cancel, entity.PermReservationsBook))
+	mux.HandleFunc("GET /reservations/{id}/cancellation-quote", require(reservationHandler.CancellationQuote, entity.PermReservationsBook))
+	mux.HandleFunc("POST /reservations/{id}/capture", require(reservationHandler.Capture, entity.PermReservationsManageAll))
+	mux.HandleFunc("GET /plans", planHandler.Search)
+	mux.HandleFunc("GET /plans/{id}", planHandler.Get)
+	mux.HandleFunc("GET /plans/{id}/availability", reservationHandler.PlanAvailability)
 	mux.HandleFunc("POST /register", userHandler.Register)
 
+	// プラン管理（staff / admin）。削除は論理削除で、既存の予約はそのまま残る
+	mux.HandleFunc("POST /plans", require(planHandler.Create, entity.PermPlansManage))
+	mux.HandleFunc("PUT /plans/{id}", require(planHandler.Update, entity.PermPlansManage))
+	mux.HandleFunc("DELETE /plans/{id}", require(planHandler.Delete, entity.PermPlansManage))
+	// 料金ルール（シーズン・曜日・祝日ごとの料金）
+	mux.HandleFunc("GET /plans/{id}/rates", require(planHandler.ListRates, entity.PermPlansManage))
+	mux.HandleFunc("POST /plans/{id}/rates", require(planHandler.CreateRate, entity.PermPlansManage))
+	mux.HandleFunc("PUT /plans/{id}/rates/{rateID}", require(planHandler.UpdateRate, entity.PermPlansManage))
+	mux.HandleFunc("DELETE /plans/{id}/rates/{rateID}", require(planHandler.DeleteRate, entity.PermPlansManage))
+
+	// クーポンの発行・更新（staff / admin）。利用は POST /reservations の coupon_code で行う
+	mux.HandleFunc("GET /coupons", require(couponHandler.List, entity.PermCouponsManage))
+	mux.HandleFunc("POST /coupons", require(couponHandler.Create, entity.PermCouponsManage))
+	mux.HandleFunc("GET /coupons/{id}", require(couponHandler.Get, entity.PermCouponsManage))
+	mux.HandleFunc("PUT /coupons/{id}", require(couponHandler.Update, entity.PermCouponsManage))
+
+	// キャンセルポリシー（staff / admin）。プランの cancellation_policy_id で参照し、予約時点の内容を予約に写す
+	mux.HandleFunc("GET /cancellation-policies", require(policyHandler.List, entity.PermPlansManage))
+	mux.HandleFunc("POST /cancellation-policies", require(policyHandler.Create, entity.PermPlansManage))
+	mux.HandleFunc("GET /cancellation-policies/{id}", require(policyHandler.Get, entity.PermPlansManage))
+	mux.HandleFunc("PUT /cancellation-policies/{id}", require(policyHandler.Update, entity.PermPlansManage))
+
+	// ログイン・トークン再発行・ログアウト
+	mux.HandleFunc("POST /login", authHandler.Login)
+	mux.HandleFunc("POST /token/refresh", authHandler.Refresh)
+	mux.HandleFunc("POST /logout", require(authHandler.Logout))
+
 	// ユーザ情報取得APIを追加
-	mux.HandleFunc("GET /users/", userHandler.GetUser)
+	mux.HandleFunc("GET /users/", require(userHandler.GetUser))
+
+	// ロールの付与・剥奪（admin）
+	mux.HandleFunc("PUT /admin/users/{id}/roles/{role}", require(adminHandler.GrantRole, entity.PermRolesManage))
+	mux.HandleFunc("DELETE /admin/users/{id}/roles/{role}", require(adminHandler.RevokeRole, entity.PermRolesManage))
 
-	addr := ":8080"
-	log.Printf("listening on %s ...", addr)
-	log.Fatal(http.ListenAndServe(addr, mux))
+	// リクエストごとの期限。超えると r.Context() がキャンセルされ、実行中の DB クエリも打ち切られる
+	timeout := getEnvDuration("REQUEST_TIMEOUT", 10*time.Second)
+	srv := &http.Server{
+		Addr:              ":8080",
+		Handler:           http.TimeoutHandler(mux, timeout, "request timeout"),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	log.Printf("listening on %s (request timeout %s) ...", srv.Addr, timeout)
+	log.Fatal(srv.ListenAndServe())
 }
 
-func seedIfEmpty(gdb *gorm.DB) error {
-	var count int64
-	if err := gdb.Model(&models.PlanModel{}).Count(&count).Error; err != nil {
-		return err
+// アクセストークンの署名鍵。未設定なら起動ごとに乱数で作る（再起動で全トークンが無効になる）
+func authSecret() []byte {
+	if v := os.Getenv("AUTH_SECRET"); v != "" {
+		return []byte(v)
+	}
+	log.Printf("AUTH_SECRET is not set; using a random secret (tokens are invalidated on restart)")
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("generate auth secret: %v", err)
 	}
-	if count > 0 {
+	return b
+}
+
+// プランのキーワード検索に使うインデックス。PLAN_SEARCH_INDEX=none ならリポジトリの部分一致で検索する
+func planSearchIndex() usecase.PlanSearchIndex {
+	switch v := getEnv("PLAN_SEARCH_INDEX", "local"); v {
+	case "local":
+		return search.NewPlanIndex()
+	case "none":
+		return nil
+	default:
+		log.Fatalf("unknown PLAN_SEARCH_INDEX %q (local / none)", v)
 		return nil
 	}
-	seed := []models.PlanModel{
-		{ID: 100, Name: "富士プレミアム", Keyword: "富士 山 静岡", Price: 12000},
-		{ID: 175, Name: "サウスベーシック", Keyword: "サウス 南", Price: 8000},
-		{ID: 200, Name: "北の宿", Keyword: "北海道 北", Price: 10000},
+}
+
+// 消費税・宿泊税のルール。TAX_RULES_FILE を指定するとそのファイル（変更は再起動なしで反映）、
+// 未指定なら埋め込みの既定のルール、none なら課税しない
+func taxRuleSource() usecase.TaxRuleSource {
+	switch path := os.Getenv("TAX_RULES_FILE"); path {
+	case "":
+		return taxconfig.Default()
+	case "none":
+		return nil
+	default:
+		src, err := taxconfig.NewFileSource(path)
+		if err != nil {
+			log.Fatalf("tax rules: %v", err)
+		}
+		log.Printf("loaded tax rules from %s", path)
+		return src
+	}
+}
+
+// 宿のタイムゾーン（キャンセル料の日数を数える日付に使う）。HOTEL_TIMEZONE が未指定なら日本時間
+func hotelLocation() *time.Location {
+	name := os.Getenv("HOTEL_TIMEZONE")
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatalf("HOTEL_TIMEZONE: %v", err)
 	}
-	return gdb.Create(&seed).Error
+	return loc
+}
+
+// 予約の決済に使う決済代行。未指定・none なら決済せずに予約を確定する。
+// fake（テスト・ローカル用で、実際には請求しない）は明示したときだけ使い、
+// FAKE_PAYMENT_DELAY で応答を遅らせ、FAKE_PAYMENT_FAIL（authorize,capture,refund,void）で失敗させられる
+func paymentGateway() gateway.PaymentGateway {
+	switch v := getEnv("PAYMENT_GATEWAY", "none"); v {
+	case "fake":
+		log.Printf("using the fake payment gateway; nothing is actually charged")
+		cfg := payment.FakeConfig{Delay: getEnvDuration("FAKE_PAYMENT_DELAY", 0)}
+		for _, op := range strings.Split(os.Getenv("FAKE_PAYMENT_FAIL"), ",") {
+			switch strings.TrimSpace(op) {
+			case "":
+			case "authorize":
+				cfg.FailAuthorize = true
+			case "capture":
+				cfg.FailCapture = true
+			case "refund":
+				cfg.FailRefund = true
+			case "void":
+				cfg.FailVoid = true
+			default:
+				log.Fatalf("unknown FAKE_PAYMENT_FAIL operation %q (authorize / capture / refund / void)", op)
+			}
+		}
+		return payment.NewFakeGateway(cfg)
+	case "none":
+		return nil
+	default:
+		log.Fatalf("unknown PAYMENT_GATEWAY %q (fake / none)", v)
+		return nil
+	}
+}
+
+// 全文検索インデックスを定期的に作り直し、他のインスタンスでのプランの変更や反映に失敗した更新を取り込む
+func reindexPlans(uc *usecase.PlanUsecase, interval time.Duration) {
+	for range time.Tick(interval) {
+		if _, err := uc.Reindex(context.Background()); err != nil {
+			log.Printf("reindex plans: %v", err)
+		}
+	}
+}
+
+// 期限切れの Idempotency-Key の記録を定期的に消す
+func purgeIdempotencyKeys(uc *usecase.IdempotencyUsecase, interval time.Duration) {
+	for range time.Tick(interval) {
+		if n, err := uc.PurgeExpired(context.Background()); err != nil {
+			log.Printf("purge idempotency keys: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d expired idempotency keys", n)
+		}
+	}
+}
+
+// ADMIN_EMAIL / ADMIN_PASSWORD が設定されていれば、そのユーザーを（いなければ作成して）admin にする
+func bootstrapAdmin(ctx context.Context, users *usecase.UserUsecase, st *storage) error {
+	email, password := os.Getenv("ADMIN_EMAIL"), os.Getenv("ADMIN_PASSWORD")
+	if email == "" {
+		return nil
+	}
+	u, err := st.users.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		if u, err = users.Register(ctx, usecase.RegisterUserInput{Name: "admin", Email: email, Password: password}); err != nil {
+			return err
+		}
+	}
+	if u.HasRole(entity.RoleAdmin) {
+		return nil
+	}
+	log.Printf("granting admin role to %s", email)
+	return st.users.GrantRole(ctx, u.ID, entity.RoleAdmin, 0)
 }
 
 func getEnv(key, def string) string {
@@ -88,10 +267,11 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
-func getEnvInt(key string, def int) int {
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
 	if v := os.Getenv(key); v != "" {
-		if i, err := strconv.Atoi(v); err == nil {
-			return i
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
 		}
 	}
 	return def
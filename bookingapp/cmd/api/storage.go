@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/infrastructure/db"
+	"bookingapp/internal/infrastructure/db/models"
+	"bookingapp/internal/infrastructure/memory"
+	mysqlrepo "bookingapp/internal/infrastructure/repository/mysql"
+	userrepo "bookingapp/internal/infrastructure/repository/mysql/user"
+	"context"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// 永続化の実装一式（STORAGE で切り替える）
+type storage struct {
+	plans        repository.PlanRepository
+	reservations repository.ReservationRepository
+	users        repository.UserRepository
+	roles        repository.RoleRepository
+	sessions     repository.SessionRepository
+	coupons      repository.CouponRepository
+	payments     repository.PaymentRepository
+	policies     repository.CancellationPolicyRepository
+	idempotency  repository.IdempotencyRepository
+	tx           repository.UnitOfWork
+}
+
+// 起動時に投入するプラン（MySQL は plans が空のときのみ）
+var seedPlans = []*entity.Plan{
+	{ID: 100, Name: "富士プレミアム", Keyword: "富士 山 静岡", Price: entity.Yen(12000), Capacity: 5, MinGuests: 1, MaxGuests: 4, ChildRate: 70, InfantRate: 50},
+	{ID: 175, Name: "サウスベーシック", Keyword: "サウス 南", Price: entity.Yen(8000), Capacity: 10, MinGuests: 1, MaxGuests: 2, ChildRate: 70, InfantRate: 50},
+	{ID: 200, Name: "北の宿", Keyword: "北海道 北", Price: entity.Yen(10000), Capacity: 8, MinGuests: 2, MaxGuests: 6, ChildRate: 50, InfantRate: 0},
+}
+
+// mysql: 環境変数の接続情報で MySQL を使う / memory: DB なしでプロセス内に保持（再起動で消える）
+func openStorage(kind string) (*storage, error) {
+	switch kind {
+	case "mysql":
+		return openMySQL()
+	case "memory":
+		log.Printf("using in-memory storage (data is lost on restart)")
+		plans := memory.NewPlanRepoMemory(seedPlans)
+		reservations := memory.NewReservationRepoMemory()
+		users := memory.NewUserRepoMemory()
+		coupons := memory.NewCouponRepoMemory()
+		payments := memory.NewPaymentRepoMemory()
+		return &storage{
+			plans:        plans,
+			reservations: reservations,
+			users:        users,
+			roles:        memory.NewRoleRepoMemory(),
+			sessions:     memory.NewSessionRepoMemory(),
+			coupons:      coupons,
+			payments:     payments,
+			policies:     memory.NewCancellationPolicyRepoMemory(),
+			idempotency:  memory.NewIdempotencyRepoMemory(),
+			tx:           memory.NewUnitOfWork(plans, reservations, users, coupons, payments),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE %q (mysql|memory)", kind)
+	}
+}
+
+func openMySQL() (*storage, error) {
+	// ---- 環境変数から接続情報 ----
+	gdb, err := db.Open(db.ConfigFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("open db: %w", err)
+	}
+	if err := db.Ping(gdb); err != nil {
+		return nil, fmt.Errorf("ping db: %w", err)
+	}
+	if err := migrateOnStart(gdb, getEnv("DB_MIGRATE", "up")); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	if err := seedIfEmpty(gdb); err != nil {
+		return nil, fmt.Errorf("seed: %w", err)
+	}
+	return &storage{
+		plans:        mysqlrepo.NewPlanRepo(gdb),
+		reservations: mysqlrepo.NewReservationRepo(gdb),
+		users:        userrepo.NewUserRepo(gdb),
+		roles:        userrepo.NewRoleRepo(gdb),
+		sessions:     mysqlrepo.NewSessionRepo(gdb),
+		coupons:      mysqlrepo.NewCouponRepo(gdb),
+		payments:     mysqlrepo.NewPaymentRepo(gdb),
+		policies:     mysqlrepo.NewCancellationPolicyRepo(gdb),
+		idempotency:  mysqlrepo.NewIdempotencyRepo(gdb),
+		tx:           mysqlrepo.NewUnitOfWork(gdb),
+	}, nil
+}
+
+// up: 未適用のマイグレーションを適用 / check: 未適用があれば起動しない / skip: 何もしない
+func migrateOnStart(gdb *gorm.DB, mode string) error {
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return err
+	}
+	m, err := db.NewMigrator(sqlDB)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	switch mode {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mg := range applied {
+			log.Printf("migrated %d_%s", mg.Version, mg.Name)
+		}
+		return err
+	case "check":
+		return m.Check(ctx)
+	case "skip":
+		return nil
+	default:
+		return fmt.Errorf("unknown DB_MIGRATE mode %q (up|check|skip)", mode)
+	}
+}
+
+func seedIfEmpty(gdb *gorm.DB) error {
+	var count int64
+	if err := gdb.Model(&models.PlanModel{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	seed := make([]models.PlanModel, 0, len(seedPlans))
+	for _, p := range seedPlans {
+		seed = append(seed, models.PlanModel{ID: p.ID, Name: p.Name, Keyword: p.Keyword, Price: p.Price.Amount, Currency: string(p.Price.Currency), Capacity: p.Capacity,
+			MinGuests: p.MinGuests, MaxGuests: p.MaxGuests, ChildRate: p.ChildRate, InfantRate: p.InfantRate})
+	}
+	return gdb.Create(&seed).Error
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bookingapp/internal/infrastructure/db"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const usage = `usage: migrate <command> [flags]
+
+commands:
+  up                 未適用のマイグレーションをすべて適用
+  down [-steps N]    適用済みのマイグレーションを新しい順に N 件戻す（デフォルト 1）
+  status             各マイグレーションの適用状況を表示
+  create NAME [-dir] 空の up/down SQL ファイルを作成
+
+接続情報は cmd/api と同じ DB_HOST / DB_PORT / DB_USER / DB_PASS / DB_NAME を使う`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+	fset := flag.NewFlagSet(cmd, flag.ExitOnError)
+	steps := fset.Int("steps", 1, "down で戻す件数")
+	dir := fset.String("dir", "internal/infrastructure/db/migrations", "create でファイルを作るディレクトリ")
+
+	if cmd == "create" {
+		if len(args) == 0 {
+			log.Fatal("create: migration name is required")
+		}
+		name := args[0]
+		_ = fset.Parse(args[1:])
+		up, down, err := db.CreateMigration(*dir, name, time.Now())
+		if err != nil {
+			log.Fatalf("create: %v", err)
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return
+	}
+	_ = fset.Parse(args)
+
+	gdb, err := db.Open(db.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		log.Fatalf("open db: %v", err)
+	}
+	defer sqlDB.Close()
+	m, err := db.NewMigrator(sqlDB)
+	if err != nil {
+		log.Fatalf("load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	switch cmd {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mg := range applied {
+			fmt.Printf("applied  %d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			log.Fatalf("up: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		reverted, err := m.Down(ctx, *steps)
+		for _, mg := range reverted {
+			fmt.Printf("reverted %d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			log.Fatalf("down: %v", err)
+		}
+	case "status":
+		st, err := m.Status(ctx)
+		if err != nil {
+			log.Fatalf("status: %v", err)
+		}
+		for _, s := range st {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -3,6 +3,9 @@ module bookingapp
 go 1.24.0
 
 require (
+	github.com/google/uuid v1.6.0
+	golang.org/x/crypto v0.43.0
+	golang.org/x/text v0.30.0
 	gorm.io/driver/mysql v1.6.0
 	gorm.io/gorm v1.31.0
 )
@@ -10,8 +13,6 @@ require (
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/go-sql-driver/mysql v1.9.3 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
-	golang.org/x/text v0.30.0 // indirect
 )
@@ -0,0 +1,98 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCancellationPolicy = errors.New("invalid cancellation policy")
+
+// 1 つのポリシーに置けるルールの数の上限
+const maxCancellationRules = 10
+
+// キャンセル料の規定。プランが参照し、予約時点の内容を予約にも写しておく
+type CancellationPolicy struct {
+	ID    int
+	Name  string
+	Rules []CancellationRule // DaysBefore の降順
+}
+
+// チェックインの DaysBefore 日前以降のキャンセルは合計金額の FeePercent%。
+// DaysBefore が 0 のルールは当日・チェックイン後（不泊を含む）に当たる
+type CancellationRule struct {
+	DaysBefore int
+	FeePercent int
+}
+
+// キャンセルした場合の料金
+type CancellationQuote struct {
+	DaysBefore int // キャンセルする日からチェックインまでの日数（当日は 0、過ぎていれば負）
+	FeePercent int
+	Fee        Money // キャンセル料（端数切り捨て）
+	Refund     Money // 合計金額 - キャンセル料
+}
+
+// 名前を整え、ルールを DaysBefore の降順に並べて検証する
+func (p *CancellationPolicy) Normalize() error {
+	p.Name = strings.TrimSpace(p.Name)
+	sort.Slice(p.Rules, func(i, j int) bool { return p.Rules[i].DaysBefore > p.Rules[j].DaysBefore })
+	switch {
+	case p.Name == "" || len([]rune(p.Name)) > 100:
+		return fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidCancellationPolicy)
+	case len(p.Rules) > maxCancellationRules:
+		return fmt.Errorf("%w: at most %d rules", ErrInvalidCancellationPolicy, maxCancellationRules)
+	}
+	for i, r := range p.Rules {
+		switch {
+		case r.DaysBefore < 0 || r.DaysBefore > 365:
+			return fmt.Errorf("%w: days_before must be between 0 and 365", ErrInvalidCancellationPolicy)
+		case r.FeePercent < 0 || r.FeePercent > 100:
+			return fmt.Errorf("%w: fee_percent must be between 0 and 100", ErrInvalidCancellationPolicy)
+		case i > 0 && r.DaysBefore == p.Rules[i-1].DaysBefore:
+			return fmt.Errorf("%w: duplicate days_before %d", ErrInvalidCancellationPolicy, r.DaysBefore)
+		case i > 0 && r.FeePercent < p.Rules[i-1].FeePercent:
+			return fmt.Errorf("%w: fee_percent must not decrease closer to checkin", ErrInvalidCancellationPolicy)
+		}
+	}
+	return nil
+}
+
+// チェックインの daysBefore 日前のキャンセル料率（当たるルールが無ければ 0）
+func (p *CancellationPolicy) FeePercent(daysBefore int) int {
+	percent := 0
+	for _, r := range p.Rules {
+		if daysBefore <= r.DaysBefore {
+			percent = max(percent, r.FeePercent)
+		}
+	}
+	return percent
+}
+
+// 予約時点のポリシーで、at の日（at のタイムゾーンでの日付）にキャンセルした場合の料金を計算する（ポリシーが無ければ無料）
+func (r *Reservation) CancellationQuote(at time.Time) (CancellationQuote, error) {
+	q := CancellationQuote{
+		DaysBefore: int(DateOf(r.Checkin).Sub(DateOf(at)).Hours() / 24),
+		Fee:        NewMoney(0, r.Total.Currency),
+		Refund:     r.Total,
+	}
+	if r.CancellationPolicy == nil {
+		return q, nil
+	}
+	q.FeePercent = r.CancellationPolicy.FeePercent(q.DaysBefore)
+	var err error
+	if q.Fee, err = percentOf(r.Total, q.FeePercent); err != nil {
+		return CancellationQuote{}, err
+	}
+	q.Refund = NewMoney(r.Total.Amount-q.Fee.Amount, r.Total.Currency)
+	return q, nil
+}
+
+// 予約に写すためのコピー
+func (p *CancellationPolicy) Snapshot() *CancellationPolicy {
+	cp := *p
+	cp.Rules = append([]CancellationRule(nil), p.Rules...)
+	return &cp
+}
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReservationCancellationQuote(t *testing.T) {
+	checkin := time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC)
+	r := &Reservation{
+		Checkin: checkin, Checkout: checkin.AddDate(0, 0, 2), Total: Yen(20001),
+		CancellationPolicy: &CancellationPolicy{Name: "標準", Rules: []CancellationRule{
+			{DaysBefore: 7, FeePercent: 0}, {DaysBefore: 3, FeePercent: 50}, {DaysBefore: 0, FeePercent: 100},
+		}},
+	}
+	cases := []struct {
+		at      time.Time
+		days    int
+		percent int
+		fee     Money
+	}{
+		{checkin.AddDate(0, 0, -8), 8, 0, Yen(0)},
+		{checkin.AddDate(0, 0, -4), 4, 0, Yen(0)},
+		// 時刻によらず日付で数える
+		{checkin.AddDate(0, 0, -3).Add(23 * time.Hour), 3, 50, Yen(10000)},
+		{checkin.AddDate(0, 0, -1), 1, 50, Yen(10000)},
+		{checkin.Add(9 * time.Hour), 0, 100, Yen(20001)},
+		{checkin.AddDate(0, 0, 1), -1, 100, Yen(20001)}, // 不泊
+		// 同じ時刻でも、日付の変わり目をまたぐタイムゾーンでは日数が変わる
+		{time.Date(2030, 1, 6, 23, 30, 0, 0, time.UTC), 4, 0, Yen(0)},
+		{time.Date(2030, 1, 6, 23, 30, 0, 0, time.UTC).In(time.FixedZone("JST", 9*60*60)), 3, 50, Yen(10000)},
+	}
+	for _, tc := range cases {
+		q, err := r.CancellationQuote(tc.at)
+		if err != nil {
+			t.Fatalf("CancellationQuote(%s): %v", tc.at, err)
+		}
+		if q.DaysBefore != tc.days || q.FeePercent != tc.percent || q.Fee != tc.fee || q.Refund.Amount != 20001-tc.fee.Amount {
+			t.Errorf("CancellationQuote(%s) = %+v, want %d days, %d%%, fee %v", tc.at, q, tc.days, tc.percent, tc.fee)
+		}
+	}
+
+	r.CancellationPolicy = nil
+	if q, err := r.CancellationQuote(checkin); err != nil || q.Fee != Yen(0) || q.Refund != Yen(20001) {
+		t.Errorf("CancellationQuote(no policy) = %+v, %v; want free", q, err)
+	}
+}
+
+func TestCancellationPolicyNormalize(t *testing.T) {
+	p := &CancellationPolicy{Name: " 標準 ", Rules: []CancellationRule{{0, 100}, {7, 0}, {3, 50}}}
+	if err := p.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if p.Name != "標準" || p.Rules[0].DaysBefore != 7 || p.Rules[2].DaysBefore != 0 {
+		t.Errorf("Normalize = %+v, want trimmed name and rules by days_before desc", p)
+	}
+	for _, bad := range []*CancellationPolicy{
+		{Name: ""},
+		{Name: "x", Rules: []CancellationRule{{-1, 10}}},
+		{Name: "x", Rules: []CancellationRule{{3, 101}}},
+		{Name: "x", Rules: []CancellationRule{{3, 50}, {3, 60}}},
+		{Name: "x", Rules: []CancellationRule{{7, 80}, {0, 50}}}, // 直前ほど安い
+	} {
+		if err := bad.Normalize(); !errors.Is(err, ErrInvalidCancellationPolicy) {
+			t.Errorf("Normalize(%+v) = %v, want ErrInvalidCancellationPolicy", bad, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCoupon = errors.New("invalid coupon")
+	// 期間外・対象外のプラン・通貨違いなど、この予約には使えない
+	ErrCouponNotApplicable = errors.New("coupon is not applicable")
+)
+
+// クーポンの割引の種類
+type CouponDiscount string
+
+const (
+	CouponPercent CouponDiscount = "percent" // 合計金額の Percent% を引く（端数切り捨て）
+	CouponAmount  CouponDiscount = "amount"  // Amount を引く（合計金額が上限）
+)
+
+// キャンペーンのクーポン。コードは大文字に揃えて一意にする
+type Coupon struct {
+	ID       int
+	Code     string
+	Name     string
+	Discount CouponDiscount
+	Percent  int   // percent の割引率（1〜100）
+	Amount   Money // amount の割引額。合計金額と同じ通貨の予約にだけ使える
+	// 予約する日時がこの範囲にあれば使える（EndsAt は含まない。nil なら制限なし）
+	StartsAt *time.Time
+	EndsAt   *time.Time
+	PlanIDs  []int // 空ならすべてのプラン
+	// 0 なら無制限
+	MaxRedemptions int // 全体の利用回数の上限
+	PerUserLimit   int // 1 ユーザーあたりの利用回数の上限
+	// 利用中の数（予約のキャンセルで戻る）
+	Redemptions int
+}
+
+// 入力されたコードを保存形式に揃える
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (c *Coupon) Validate() error {
+	switch {
+	case c.Code == "" || len(c.Code) > 64 || strings.ContainsAny(c.Code, " \t\r\n"):
+		return fmt.Errorf("%w: code must be 1-64 characters without spaces", ErrInvalidCoupon)
+	case strings.TrimSpace(c.Name) == "":
+		return fmt.Errorf("%w: name must not be empty", ErrInvalidCoupon)
+	case c.Discount == CouponPercent && (c.Percent < 1 || c.Percent > 100):
+		return fmt.Errorf("%w: percent must be between 1 and 100", ErrInvalidCoupon)
+	case c.Discount == CouponAmount && !c.Amount.IsPositive():
+		return fmt.Errorf("%w: amount must be > 0", ErrInvalidCoupon)
+	case c.Discount != CouponPercent && c.Discount != CouponAmount:
+		return fmt.Errorf("%w: unknown discount %q", ErrInvalidCoupon, c.Discount)
+	case c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidCoupon)
+	case c.MaxRedemptions < 0 || c.PerUserLimit < 0:
+		return fmt.Errorf("%w: limits must be >= 0", ErrInvalidCoupon)
+	}
+	return nil
+}
+
+// at の時点で planID のプランの予約に使えるか
+func (c *Coupon) CheckApplicable(planID int, at time.Time) error {
+	switch {
+	case c.StartsAt != nil && at.Before(*c.StartsAt):
+		return fmt.Errorf("%w: not started yet", ErrCouponNotApplicable)
+	case c.EndsAt != nil && !at.Before(*c.EndsAt):
+		return fmt.Errorf("%w: expired", ErrCouponNotApplicable)
+	case len(c.PlanIDs) > 0 && !slices.Contains(c.PlanIDs, planID):
+		return fmt.Errorf("%w: not valid for this plan", ErrCouponNotApplicable)
+	}
+	return nil
+}
+
+// 合計金額 subtotal に対する割引額
+func (c *Coupon) DiscountFor(subtotal Money) (Money, error) {
+	switch c.Discount {
+	case CouponPercent:
+		return percentOf(subtotal, c.Percent)
+	case CouponAmount:
+		if c.Amount.Currency != subtotal.Currency {
+			return Money{}, fmt.Errorf("%w: currency %s differs from %s", ErrCouponNotApplicable, c.Amount.Currency, subtotal.Currency)
+		}
+		return NewMoney(min(c.Amount.Amount, max(subtotal.Amount, 0)), subtotal.Currency), nil
+	}
+	return Money{}, fmt.Errorf("%w: unknown discount %q", ErrInvalidCoupon, c.Discount)
+}
@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCouponDiscountFor(t *testing.T) {
+	percent := &Coupon{Discount: CouponPercent, Percent: 15}
+	if got, err := percent.DiscountFor(Yen(9999)); err != nil || got != Yen(1499) {
+		t.Errorf("percent DiscountFor = %v, %v; want 1499 JPY (rounded down)", got, err)
+	}
+	amount := &Coupon{Discount: CouponAmount, Amount: Yen(5000)}
+	if got, err := amount.DiscountFor(Yen(3000)); err != nil || got != Yen(3000) {
+		t.Errorf("amount DiscountFor = %v, %v; want capped at the subtotal", got, err)
+	}
+	if _, err := amount.DiscountFor(NewMoney(3000, "USD")); !errors.Is(err, ErrCouponNotApplicable) {
+		t.Errorf("amount DiscountFor(USD) = %v, want ErrCouponNotApplicable", err)
+	}
+}
+
+func TestCouponCheckApplicable(t *testing.T) {
+	starts := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	ends := starts.AddDate(0, 1, 0)
+	c := &Coupon{StartsAt: &starts, EndsAt: &ends, PlanIDs: []int{100}}
+	cases := []struct {
+		planID int
+		at     time.Time
+		ok     bool
+	}{
+		{100, starts, true},
+		{100, starts.Add(-time.Second), false},
+		{100, ends, false}, // EndsAt は含まない
+		{200, starts, false},
+	}
+	for _, tc := range cases {
+		err := c.CheckApplicable(tc.planID, tc.at)
+		if tc.ok != (err == nil) || (err != nil && !errors.Is(err, ErrCouponNotApplicable)) {
+			t.Errorf("CheckApplicable(%d, %s) = %v, want ok=%v", tc.planID, tc.at, err, tc.ok)
+		}
+	}
+}
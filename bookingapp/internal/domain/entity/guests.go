@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidAge = errors.New("invalid age")
+
+// 料金の年齢区分。子どもは大人の料金に対する割合（Plan.ChildRate / InfantRate）で料金を決める
+type AgeBand string
+
+const (
+	AgeBandAdult  AgeBand = "adult"  // 13歳以上（中学生以上）
+	AgeBandChild  AgeBand = "child"  // 6〜12歳（小学生）
+	AgeBandInfant AgeBand = "infant" // 0〜5歳（幼児）
+)
+
+// プラン登録時に指定が無い場合の子ども料金（大人の料金に対する %）
+const (
+	DefaultChildRate  = 70
+	DefaultInfantRate = 50
+)
+
+// 宿泊時点の年齢から区分を決める
+func AgeBandOf(age int) (AgeBand, error) {
+	switch {
+	case age < 0 || age > 150:
+		return "", fmt.Errorf("%w: %d", ErrInvalidAge, age)
+	case age <= 5:
+		return AgeBandInfant, nil
+	case age <= 12:
+		return AgeBandChild, nil
+	}
+	return AgeBandAdult, nil
+}
+
+// 1予約の宿泊者の内訳
+type Guests struct {
+	Adults   int
+	Children int // 小学生
+	Infants  int // 幼児
+}
+
+// 大人の人数と子どもの年齢から内訳を作る。13歳以上は大人として数えるので子どもには指定できない
+func GuestsOf(adults int, childAges []int) (Guests, error) {
+	g := Guests{Adults: adults}
+	for _, age := range childAges {
+		band, err := AgeBandOf(age)
+		if err != nil {
+			return Guests{}, err
+		}
+		switch band {
+		case AgeBandInfant:
+			g.Infants++
+		case AgeBandChild:
+			g.Children++
+		default:
+			return Guests{}, fmt.Errorf("%w: %d is an adult age", ErrInvalidAge, age)
+		}
+	}
+	return g, nil
+}
+
+// 合計人数（定員の判定に使う）
+func (g Guests) Total() int {
+	return g.Adults + g.Children + g.Infants
+}
+
+// 大人が 1 人以上いること
+func (g Guests) Valid() bool {
+	return g.Adults >= 1 && g.Children >= 0 && g.Infants >= 0
+}
@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGuestsOf(t *testing.T) {
+	g, err := GuestsOf(2, []int{0, 5, 6, 12})
+	if err != nil {
+		t.Fatalf("GuestsOf: %v", err)
+	}
+	if want := (Guests{Adults: 2, Children: 2, Infants: 2}); g != want {
+		t.Errorf("GuestsOf = %+v, want %+v", g, want)
+	}
+	if g.Total() != 6 || !g.Valid() {
+		t.Errorf("Total = %d, Valid = %v", g.Total(), g.Valid())
+	}
+	for _, age := range []int{-1, 13} {
+		if _, err := GuestsOf(1, []int{age}); !errors.Is(err, ErrInvalidAge) {
+			t.Errorf("GuestsOf(age %d) = %v, want ErrInvalidAge", age, err)
+		}
+	}
+	if (Guests{Children: 2}).Valid() {
+		t.Error("Guests without adults must be invalid")
+	}
+}
@@ -0,0 +1,157 @@
+package entity
+
+import "time"
+
+// 祝日の判定（料金ルールの「祝日」「祝前日」に使う）
+type HolidayCalendar interface {
+	IsHoliday(d time.Time) bool
+}
+
+// 日本の祝日（振替休日・国民の休日を含む）。
+// 現行の祝日法（2020 年以降）の規則で計算するので、それより前の年は実際と異なる日がある
+type JapaneseHolidays struct{}
+
+func (JapaneseHolidays) IsHoliday(d time.Time) bool {
+	_, ok := JapaneseHolidayName(d)
+	return ok
+}
+
+// 祝日なら名前を返す
+func JapaneseHolidayName(d time.Time) (string, bool) {
+	d = DateOf(d)
+	if name := nationalHoliday(d); name != "" {
+		return name, true
+	}
+	// 振替休日: 日曜の祝日から祝日が続いた後の最初の平日
+	for prev := d.AddDate(0, 0, -1); nationalHoliday(prev) != ""; prev = prev.AddDate(0, 0, -1) {
+		if prev.Weekday() == time.Sunday {
+			return "振替休日", true
+		}
+	}
+	// 国民の休日: 前日と翌日がともに祝日の日
+	if nationalHoliday(d.AddDate(0, 0, -1)) != "" && nationalHoliday(d.AddDate(0, 0, 1)) != "" {
+		return "国民の休日", true
+	}
+	return "", false
+}
+
+// 祝日法第 2 条の「国民の祝日」（振替休日・国民の休日を除く）
+func nationalHoliday(d time.Time) string {
+	y, m, day := d.Date()
+	switch m {
+	case time.January:
+		switch day {
+		case 1:
+			return "元日"
+		case nthMonday(y, m, 2):
+			return "成人の日"
+		}
+	case time.February:
+		switch day {
+		case 11:
+			return "建国記念の日"
+		case 23:
+			return "天皇誕生日"
+		}
+	case time.March:
+		if day == equinoxDay(y, 20.8431) {
+			return "春分の日"
+		}
+	case time.April:
+		if day == 29 {
+			return "昭和の日"
+		}
+	case time.May:
+		switch day {
+		case 3:
+			return "憲法記念日"
+		case 4:
+			return "みどりの日"
+		case 5:
+			return "こどもの日"
+		}
+	case time.July:
+		if day == marineDay(y) {
+			return "海の日"
+		}
+		if day == sportsDay(y, m) {
+			return "スポーツの日"
+		}
+	case time.August:
+		if day == mountainDay(y) {
+			return "山の日"
+		}
+	case time.September:
+		switch day {
+		case nthMonday(y, m, 3):
+			return "敬老の日"
+		case equinoxDay(y, 23.2488):
+			return "秋分の日"
+		}
+	case time.October:
+		if day == sportsDay(y, m) {
+			return "スポーツの日"
+		}
+	case time.November:
+		switch day {
+		case 3:
+			return "文化の日"
+		case 23:
+			return "勤労感謝の日"
+		}
+	}
+	return ""
+}
+
+// 東京オリンピック・パラリンピックの特例（2020・2021 年）で移動した祝日
+func marineDay(y int) int {
+	switch y {
+	case 2020:
+		return 23
+	case 2021:
+		return 22
+	}
+	return nthMonday(y, time.July, 3)
+}
+
+func mountainDay(y int) int {
+	switch y {
+	case 2020:
+		return 10
+	case 2021:
+		return 8
+	}
+	return 11
+}
+
+// スポーツの日は通常 10 月の第 2 月曜。特例の年は 7 月
+func sportsDay(y int, m time.Month) int {
+	switch y {
+	case 2020:
+		if m == time.July {
+			return 24
+		}
+		return 0
+	case 2021:
+		if m == time.July {
+			return 23
+		}
+		return 0
+	}
+	if m == time.October {
+		return nthMonday(y, m, 2)
+	}
+	return 0
+}
+
+// その月の第 n 月曜日の日
+func nthMonday(y int, m time.Month, n int) int {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC).Weekday()
+	offset := (int(time.Monday) - int(first) + 7) % 7
+	return 1 + offset + 7*(n-1)
+}
+
+// 春分・秋分の日の近似式（1980〜2099 年で有効）。base は 1980 年の値
+func equinoxDay(y int, base float64) int {
+	return int(base+0.242194*float64(y-1980)) - (y-1980)/4
+}
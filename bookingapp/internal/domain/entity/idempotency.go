@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Idempotency-Key 付きリクエストの記録。同じユーザー・キーの再送には保存したレスポンスを返す
+type IdempotencyRecord struct {
+	ID          string // 記録ごとの ID（同じキーの記録を作り直しても別になる）
+	UserID      string
+	Key         string
+	RequestHash string // メソッド・パス・本文の SHA-256（16 進）
+	// 処理が終わるまでは 0
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+func (r *IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
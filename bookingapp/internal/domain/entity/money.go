@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidCurrency  = errors.New("unsupported currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrMoneyOverflow    = errors.New("amount overflows")
+)
+
+// ISO 4217 の通貨コード
+type Currency string
+
+const (
+	JPY Currency = "JPY"
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+)
+
+// 既存データ（通貨の列が無かった頃の行）と、通貨を省略した入力の通貨
+const DefaultCurrency = JPY
+
+// 扱える通貨と補助単位の桁数（JPY は 1 円、USD・EUR は 1/100）
+var currencyExponents = map[Currency]int{
+	JPY: 0,
+	USD: 2,
+	EUR: 2,
+}
+
+func (c Currency) Valid() bool {
+	_, ok := currencyExponents[c]
+	return ok
+}
+
+// 大文字にそろえて検証する。空なら DefaultCurrency
+func ParseCurrency(s string) (Currency, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return DefaultCurrency, nil
+	}
+	if c := Currency(s); c.Valid() {
+		return c, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, s)
+}
+
+// 金額。Amount は通貨の最小単位（JPY なら円、USD ならセント）で持ち、演算は桁あふれを検出する
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func NewMoney(amount int64, c Currency) Money {
+	return Money{Amount: amount, Currency: c}
+}
+
+// 円建ての金額
+func Yen(amount int64) Money {
+	return Money{Amount: amount, Currency: JPY}
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+
+// 同じ通貨どうしの和
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	sum := m.Amount + o.Amount
+	// 同符号どうしを足して符号が変わったら桁あふれ
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// 整数倍（単価 × 人数 × 泊数など）
+func (m Money) Mul(n int64) (Money, error) {
+	if m.Amount == 0 || n == 0 {
+		return Money{Currency: m.Currency}, nil
+	}
+	p := m.Amount * n
+	if p/n != m.Amount || (m.Amount == -1 && n == math.MinInt64) || (n == -1 && m.Amount == math.MinInt64) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: p, Currency: m.Currency}, nil
+}
+
+// 表示用（例: "12000 JPY", "19.99 USD"）
+func (m Money) String() string {
+	exp := currencyExponents[m.Currency]
+	if exp == 0 {
+		return strconv.FormatInt(m.Amount, 10) + " " + string(m.Currency)
+	}
+	sign := ""
+	a := m.Amount
+	if a < 0 {
+		sign = "-"
+	}
+	unit := int64(math.Pow10(exp))
+	whole, frac := a/unit, a%unit
+	if whole < 0 {
+		whole = -whole
+	}
+	if frac < 0 {
+		frac = -frac
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, whole, exp, frac, m.Currency)
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMoney(t *testing.T) {
+	if got, err := Yen(12000).Mul(3); err != nil || got != Yen(36000) {
+		t.Errorf("Mul = %v, %v", got, err)
+	}
+	if got, err := Yen(100).Add(Yen(-30)); err != nil || got != Yen(70) {
+		t.Errorf("Add = %v, %v", got, err)
+	}
+	if _, err := Yen(100).Add(NewMoney(100, USD)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add(JPY, USD) = %v, want ErrCurrencyMismatch", err)
+	}
+
+	overflows := []func() (Money, error){
+		func() (Money, error) { return Yen(math.MaxInt64).Add(Yen(1)) },
+		func() (Money, error) { return Yen(math.MinInt64).Add(Yen(-1)) },
+		func() (Money, error) { return Yen(math.MaxInt64/2 + 1).Mul(2) },
+		func() (Money, error) { return Yen(math.MinInt64).Mul(-1) },
+		func() (Money, error) { return Yen(-1).Mul(math.MinInt64) },
+	}
+	for i, f := range overflows {
+		if got, err := f(); !errors.Is(err, ErrMoneyOverflow) {
+			t.Errorf("overflow case %d = %v, %v; want ErrMoneyOverflow", i, got, err)
+		}
+	}
+	// 料金計算も桁あふれを返す（人数倍・泊数分の合計・子ども料金の % 計算）
+	var e PricingEngine
+	in := date(2030, 1, 7)
+	quotes := []struct {
+		plan   *Plan
+		guests Guests
+		nights int
+	}{
+		{&Plan{Price: Yen(math.MaxInt64 / 4)}, Guests{Adults: 2}, 3},
+		{&Plan{Price: Yen(math.MaxInt64 / 4)}, Guests{Adults: 5}, 1},
+		{&Plan{Price: Yen(math.MaxInt64 / 2), ChildRate: 70}, Guests{Adults: 1, Children: 1}, 1},
+	}
+	for i, q := range quotes {
+		if _, got, err := e.Quote(q.plan, nil, q.guests, in, in.AddDate(0, 0, q.nights)); !errors.Is(err, ErrMoneyOverflow) {
+			t.Errorf("Quote overflow case %d = %v, %v; want ErrMoneyOverflow", i, got, err)
+		}
+	}
+
+	for _, c := range []struct {
+		m    Money
+		want string
+	}{
+		{Yen(12000), "12000 JPY"},
+		{NewMoney(1999, USD), "19.99 USD"},
+		{NewMoney(-5, EUR), "-0.05 EUR"},
+	} {
+		if got := c.m.String(); got != c.want {
+			t.Errorf("String() = %q, want %q", got, c.want)
+		}
+	}
+	if c, err := ParseCurrency(" usd "); err != nil || c != USD {
+		t.Errorf("ParseCurrency(usd) = %q, %v", c, err)
+	}
+	if c, err := ParseCurrency(""); err != nil || c != DefaultCurrency {
+		t.Errorf("ParseCurrency(\"\") = %q, %v", c, err)
+	}
+	if _, err := ParseCurrency("XYZ"); !errors.Is(err, ErrInvalidCurrency) {
+		t.Errorf("ParseCurrency(XYZ) = %v, want ErrInvalidCurrency", err)
+	}
+}
@@ -0,0 +1,40 @@
+package entity
+
+// 決済の状態
+type PaymentStatus string
+
+const (
+	PaymentPending    PaymentStatus = "pending"    // 与信の結果待ち
+	PaymentAuthorized PaymentStatus = "authorized" // 与信済み（未請求）
+	PaymentProcessing PaymentStatus = "processing" // 請求・取り消し・返金・与信の取り直しを決済代行に依頼中（依頼前に切り替え、操作を 1 つに限る）
+	PaymentCaptured   PaymentStatus = "captured"   // 請求済み（キャンセル料だけを請求した場合も含む）
+	PaymentRefunded   PaymentStatus = "refunded"   // 請求後に返金済み（Refunded が返金額。キャンセル料を除いた一部のこともある）
+	PaymentVoided     PaymentStatus = "voided"     // 与信を取り消した
+	PaymentFailed     PaymentStatus = "failed"     // 与信に失敗した
+)
+
+// 予約 1 件につき 1 つの決済
+type Payment struct {
+	ID            int
+	ReservationID int
+	Amount        Money // 与信額
+	Captured      Money // 請求額（Amount 以下）
+	Refunded      Money // 返金額（Captured 以下）
+	Status        PaymentStatus
+	// 決済代行の取引 ID（与信に成功した場合だけ）
+	GatewayRef    string
+	FailureReason string
+	// 楽観的排他制御のバージョン（保存のたびに 1 増える）
+	Version int
+}
+
+// キャンセル料 fee の予約をキャンセルしたときに、与信の取り消し・キャンセル料の請求・返金が残っているか
+func (p *Payment) NeedsSettlement(fee Money) bool {
+	switch p.Status {
+	case PaymentAuthorized:
+		return true
+	case PaymentCaptured:
+		return p.Captured.Amount-p.Refunded.Amount > fee.Amount
+	}
+	return false
+}
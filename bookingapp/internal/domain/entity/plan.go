@@ -1,8 +1,35 @@
 package entity
 
+import "time"
+
 type Plan struct {
-	ID      int
-	Name    string
-	Keyword string // 簡易検索用
-	Price   int
+	ID        int
+	Name      string
+	Keyword   string // 簡易検索用
+	Price     Money  // 大人1人1泊あたりの基本価格（料金ルールで泊ごとに変わる）
+	Capacity  int    // 1泊あたりの販売室数
+	MinGuests int    // 1予約あたりの人数下限（子どもを含む）
+	MaxGuests int    // 1予約あたりの人数上限（子どもを含む）
+	// 子ども料金（大人の料金に対する %。0 なら無料）
+	ChildRate  int // 小学生
+	InfantRate int // 幼児
+	// 課税地域（TaxRules の地域コード）。空なら TaxRules.Default
+	Jurisdiction string
+	// キャンセルポリシー（0 ならキャンセル料なし）
+	CancellationPolicyID int
+	// 削除日時（論理削除）。削除済みのプランは検索・新規予約の対象外だが、既存の予約からは参照できる
+	DeletedAt *time.Time
+	// 楽観的排他制御のバージョン。作成時は 1 で、更新・削除するたびに 1 ずつ増える
+	Version int
+}
+
+func (p *Plan) Deleted() bool {
+	return p.DeletedAt != nil
+}
+
+// 1泊分の空き状況
+type NightAvailability struct {
+	Date      time.Time
+	Remaining int   // 残室数
+	Price     Money // その泊の実効価格（大人1人あたり、料金ルール適用後）
 }
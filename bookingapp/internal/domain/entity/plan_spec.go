@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// 人数上限を指定しなかったプランの上限（plans.max_guests の既定値と同じ）
+const DefaultMaxGuests = 4
+
+type PlanSort string
+
+const (
+	PlanSortRelevance PlanSort = "relevance" // キーワードの一致度の高い順（キーワードなしなら ID 順）
+	PlanSortPrice     PlanSort = "price"     // 通貨ごとに価格の安い順（通貨コード順）
+	PlanSortName      PlanSort = "name"      // 名前順（文字コード順）
+)
+
+func (s PlanSort) Valid() bool {
+	switch s {
+	case PlanSortRelevance, PlanSortPrice, PlanSortName:
+		return true
+	}
+	return false
+}
+
+// プラン検索の条件（仕様）。ゼロ値の項目は絞り込まない。
+// メモリ実装は Matches / Relevance / Less をそのまま使い、MySQL 実装は同じ意味の SQL に置き換える
+type PlanSpec struct {
+	Keyword  string // 名前かキーワードに含む（大文字小文字だけ区別しない。濁点・かななどは区別する）
+	IDs      []int  // nil でなければこの ID のプランだけ（全文検索インデックスの結果で絞るときに使う）
+	Currency Currency
+	MinPrice int64 // 価格（Currency の最小単位）の範囲。指定すると Currency（空なら DefaultCurrency）のプランだけ
+	MaxPrice int64
+	Guests   int // この人数で泊まれる（MinGuests <= Guests <= MaxGuests）
+	// 両方指定した場合、Checkin〜Checkout前日の全泊に空きがあるプランだけ
+	Checkin  *time.Time
+	Checkout *time.Time
+
+	Sort   PlanSort // 空なら PlanSortRelevance
+	Offset int
+	Limit  int // 0 なら全件
+}
+
+// 正規化したキーワード（比較用に小文字）
+func (s PlanSpec) NormalizedKeyword() string {
+	return strings.ToLower(strings.TrimSpace(s.Keyword))
+}
+
+// 価格で絞り込む通貨。Currency も価格の範囲も指定が無ければ空（通貨を問わない）
+func (s PlanSpec) PriceCurrency() Currency {
+	if s.Currency == "" && (s.MinPrice > 0 || s.MaxPrice > 0) {
+		return DefaultCurrency
+	}
+	return s.Currency
+}
+
+// 在庫以外の条件を満たすか。削除済みのプランは常に対象外
+func (s PlanSpec) Matches(p *Plan) bool {
+	if p.Deleted() {
+		return false
+	}
+	if s.IDs != nil && !slices.Contains(s.IDs, p.ID) {
+		return false
+	}
+	if kw := s.NormalizedKeyword(); kw != "" &&
+		!strings.Contains(strings.ToLower(p.Name), kw) && !strings.Contains(strings.ToLower(p.Keyword), kw) {
+		return false
+	}
+	switch c := s.PriceCurrency(); {
+	case c != "" && p.Price.Currency != c,
+		s.MinPrice > 0 && p.Price.Amount < s.MinPrice,
+		s.MaxPrice > 0 && p.Price.Amount > s.MaxPrice,
+		s.Guests > 0 && (p.MaxGuests < s.Guests || p.MinGuests > s.Guests):
+		return false
+	}
+	return true
+}
+
+// キーワードの一致度。名前に含めば 2、キーワード欄に含めば 1 を足す
+func (s PlanSpec) Relevance(p *Plan) int {
+	kw := s.NormalizedKeyword()
+	if kw == "" {
+		return 0
+	}
+	score := 0
+	if strings.Contains(strings.ToLower(p.Name), kw) {
+		score += 2
+	}
+	if strings.Contains(strings.ToLower(p.Keyword), kw) {
+		score++
+	}
+	return score
+}
+
+// Sort の順で a が b より前に並ぶか。同順位は ID 順
+func (s PlanSpec) Less(a, b *Plan) bool {
+	switch s.Sort {
+	case PlanSortPrice:
+		if a.Price.Currency != b.Price.Currency {
+			return a.Price.Currency < b.Price.Currency
+		}
+		if a.Price.Amount != b.Price.Amount {
+			return a.Price.Amount < b.Price.Amount
+		}
+	case PlanSortName:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	default:
+		if ra, rb := s.Relevance(a), s.Relevance(b); ra != rb {
+			return ra > rb
+		}
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"sort"
+	"time"
+)
+
+// 1泊分の料金
+type NightCharge struct {
+	Date       time.Time
+	Rate       Money // 大人1人あたりの価格
+	ChildRate  Money // 小学生1人あたり（Rate × Plan.ChildRate%）
+	InfantRate Money // 幼児1人あたり（Rate × Plan.InfantRate%）
+	Amount     Money // 区分ごとの価格 × 人数の合計
+}
+
+// 料金ルールから各泊の価格を決める
+type PricingEngine struct {
+	Holidays HolidayCalendar // nil なら JapaneseHolidays
+}
+
+// night の泊の大人 1 人あたりの価格。
+// 当たった fixed のうち優先度の最も高いもので基本価格（Plan.Price）を置き換え、
+// そこへ当たった amount / percent をすべて加算する。percent の端数は切り捨て、0 未満にはしない
+func (e PricingEngine) NightlyRate(plan *Plan, rules []RateRule, night time.Time) (Money, error) {
+	cal := e.Holidays
+	if cal == nil {
+		cal = JapaneseHolidays{}
+	}
+	var applied []*RateRule
+	for i := range rules {
+		if rules[i].AppliesTo(night, cal) {
+			applied = append(applied, &rules[i])
+		}
+	}
+	sort.SliceStable(applied, func(i, j int) bool {
+		if applied[i].Priority != applied[j].Priority {
+			return applied[i].Priority > applied[j].Priority
+		}
+		return applied[i].ID > applied[j].ID
+	})
+
+	base := plan.Price
+	for _, r := range applied {
+		if r.Adjustment == RateFixed {
+			base = NewMoney(r.Amount, plan.Price.Currency)
+			break
+		}
+	}
+	rate := base
+	for _, r := range applied {
+		var delta Money
+		switch r.Adjustment {
+		case RateAmount:
+			delta = NewMoney(r.Amount, base.Currency)
+		case RatePercent:
+			var err error
+			if delta, err = percentOf(base, r.Percent); err != nil {
+				return Money{}, err
+			}
+		default:
+			continue
+		}
+		var err error
+		if rate, err = rate.Add(delta); err != nil {
+			return Money{}, err
+		}
+	}
+	if rate.Amount < 0 {
+		rate.Amount = 0
+	}
+	return rate, nil
+}
+
+// checkin〜checkout前日の各泊の料金と、その合計（guests 全員分）
+func (e PricingEngine) Quote(plan *Plan, rules []RateRule, guests Guests, checkin, checkout time.Time) ([]NightCharge, Money, error) {
+	total := NewMoney(0, plan.Price.Currency)
+	var nights []NightCharge
+	for _, d := range StayDates(checkin, checkout) {
+		rate, err := e.NightlyRate(plan, rules, d)
+		if err != nil {
+			return nil, Money{}, err
+		}
+		n := NightCharge{Date: d, Rate: rate}
+		if n.ChildRate, err = percentOf(rate, plan.ChildRate); err != nil {
+			return nil, Money{}, err
+		}
+		if n.InfantRate, err = percentOf(rate, plan.InfantRate); err != nil {
+			return nil, Money{}, err
+		}
+		n.Amount = NewMoney(0, rate.Currency)
+		for _, g := range []struct {
+			rate  Money
+			count int
+		}{
+			{n.Rate, guests.Adults},
+			{n.ChildRate, guests.Children},
+			{n.InfantRate, guests.Infants},
+		} {
+			amount, err := g.rate.Mul(int64(g.count))
+			if err != nil {
+				return nil, Money{}, err
+			}
+			if n.Amount, err = n.Amount.Add(amount); err != nil {
+				return nil, Money{}, err
+			}
+		}
+		if total, err = total.Add(n.Amount); err != nil {
+			return nil, Money{}, err
+		}
+		nights = append(nights, n)
+	}
+	return nights, total, nil
+}
+
+// m の percent%（端数切り捨て）
+func percentOf(m Money, percent int) (Money, error) {
+	scaled, err := m.Mul(int64(percent))
+	if err != nil {
+		return Money{}, err
+	}
+	return NewMoney(scaled.Amount/100, m.Currency), nil
+}
@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestJapaneseHolidays(t *testing.T) {
+	cases := []struct {
+		d    time.Time
+		want string
+	}{
+		{date(2025, 1, 1), "元日"},
+		{date(2025, 1, 13), "成人の日"},
+		{date(2025, 2, 24), "振替休日"},
+		{date(2025, 3, 20), "春分の日"},
+		{date(2025, 5, 6), "振替休日"}, // 5/4（日）から祝日が続いた翌日
+		{date(2025, 9, 23), "秋分の日"},
+		{date(2025, 10, 13), "スポーツの日"},
+		{date(2026, 9, 22), "国民の休日"},
+		{date(2020, 7, 24), "スポーツの日"},
+		{date(2021, 8, 9), "振替休日"},
+		{date(2025, 5, 7), ""},
+		{date(2025, 10, 10), ""},
+	}
+	for _, c := range cases {
+		if got, _ := JapaneseHolidayName(c.d); got != c.want {
+			t.Errorf("JapaneseHolidayName(%s) = %q, want %q", c.d.Format("2006-01-02"), got, c.want)
+		}
+	}
+}
+
+func TestPricingEngine(t *testing.T) {
+	plan := &Plan{ID: 1, Price: Yen(10000), ChildRate: 70, InfantRate: 50}
+	start, end := date(2025, 12, 28), date(2026, 1, 3)
+	rules := []RateRule{
+		{ID: 1, Name: "週末・祝前日", Days: []RateDay{RateDaySat, RateDayHolidayEve}, Adjustment: RateAmount, Amount: 3000},
+		{ID: 2, Name: "年末年始", StartDate: &start, EndDate: &end, Adjustment: RateFixed, Amount: 15000, Priority: 1},
+		{ID: 3, Name: "月曜割", Days: []RateDay{RateDayMon}, Adjustment: RatePercent, Percent: -10},
+	}
+	var engine PricingEngine
+
+	nights, total, err := engine.Quote(plan, rules, Guests{Adults: 2}, date(2025, 12, 26), date(2025, 12, 30))
+	if err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	// 金・土（+3000）・日（年末年始）・月（年末年始の 10% 引き）
+	wantRates := []int64{10000, 13000, 15000, 13500}
+	if len(nights) != len(wantRates) {
+		t.Fatalf("Quote returned %d nights, want %d", len(nights), len(wantRates))
+	}
+	for i, n := range nights {
+		if n.Rate != Yen(wantRates[i]) || n.Amount != Yen(wantRates[i]*2) {
+			t.Errorf("night %s = %v / %v, want %d per person", n.Date.Format("2006-01-02"), n.Rate, n.Amount, wantRates[i])
+		}
+	}
+	if total != Yen(103000) {
+		t.Errorf("total = %v, want 103000 JPY", total)
+	}
+
+	// 子どもは大人の料金の割合（端数切り捨て）
+	nights, total, err = engine.Quote(plan, rules, Guests{Adults: 1, Children: 1, Infants: 1}, date(2025, 12, 27), date(2025, 12, 29))
+	if err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	if n := nights[0]; n.Rate != Yen(13000) || n.ChildRate != Yen(9100) || n.InfantRate != Yen(6500) || n.Amount != Yen(28600) {
+		t.Errorf("night with children = %+v", n)
+	}
+	if total != Yen(28600+33000) {
+		t.Errorf("total with children = %v, want 61600 JPY", total)
+	}
+
+	// 2026-01-12 は成人の日なので、前日の日曜は祝前日
+	for _, c := range []struct {
+		night time.Time
+		want  int64
+	}{
+		{date(2026, 1, 11), 13000},
+		{date(2026, 1, 12), 9000},
+		{date(2026, 1, 13), 10000},
+	} {
+		if got, err := engine.NightlyRate(plan, rules, c.night); err != nil || got != Yen(c.want) {
+			t.Errorf("NightlyRate(%s) = %v, %v; want %d", c.night.Format("2006-01-02"), got, err, c.want)
+		}
+	}
+
+	// 割引で 0 未満にはならない
+	discount := []RateRule{{ID: 1, Name: "大幅割引", Adjustment: RateAmount, Amount: -20000}}
+	if got, _ := engine.NightlyRate(plan, discount, date(2026, 1, 13)); got != Yen(0) {
+		t.Errorf("NightlyRate with discount = %v, want 0", got)
+	}
+}
@@ -0,0 +1,118 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRateRule = errors.New("invalid rate rule")
+
+// 料金ルールを適用する日の種類。泊の日付（チェックインした日）で判定する
+type RateDay string
+
+const (
+	RateDaySun        RateDay = "sun"
+	RateDayMon        RateDay = "mon"
+	RateDayTue        RateDay = "tue"
+	RateDayWed        RateDay = "wed"
+	RateDayThu        RateDay = "thu"
+	RateDayFri        RateDay = "fri"
+	RateDaySat        RateDay = "sat"
+	RateDayHoliday    RateDay = "holiday"     // 祝日
+	RateDayHolidayEve RateDay = "holiday_eve" // 祝前日（翌日が祝日の泊）
+)
+
+// time.Weekday の順
+var weekdayRateDays = [...]RateDay{RateDaySun, RateDayMon, RateDayTue, RateDayWed, RateDayThu, RateDayFri, RateDaySat}
+
+func (d RateDay) Valid() bool {
+	return d == RateDayHoliday || d == RateDayHolidayEve || slices.Contains(weekdayRateDays[:], d)
+}
+
+// 料金ルールによる価格の変え方
+type RateAdjustment string
+
+const (
+	RateFixed   RateAdjustment = "fixed"   // 1人1泊の価格を Amount にする
+	RateAmount  RateAdjustment = "amount"  // Amount を加算する（負なら割引）
+	RatePercent RateAdjustment = "percent" // 基本価格の Percent% を加算する（負なら割引）
+)
+
+func (a RateAdjustment) Valid() bool {
+	switch a {
+	case RateFixed, RateAmount, RatePercent:
+		return true
+	}
+	return false
+}
+
+// プランの料金ルール（シーズン料金・週末や祝前日の加算など）
+type RateRule struct {
+	ID        int
+	PlanID    int
+	Name      string
+	StartDate *time.Time // この日の泊から適用（nil なら制限なし）
+	EndDate   *time.Time // この日の泊まで適用（この日を含む。nil なら制限なし）
+	// 空なら毎日。複数指定した場合はいずれかに当たれば適用
+	Days       []RateDay
+	Adjustment RateAdjustment
+	Amount     int64 // fixed / amount の金額（プランの通貨の最小単位）
+	Percent    int   // percent の割合
+	// fixed が複数当たった場合は大きいほうを使う（同じなら ID の大きいほう）
+	Priority int
+}
+
+func (r *RateRule) Validate() error {
+	switch {
+	case strings.TrimSpace(r.Name) == "":
+		return fmt.Errorf("%w: name must not be empty", ErrInvalidRateRule)
+	case !r.Adjustment.Valid():
+		return fmt.Errorf("%w: unknown adjustment %q", ErrInvalidRateRule, r.Adjustment)
+	case r.Adjustment == RateFixed && r.Amount <= 0:
+		return fmt.Errorf("%w: fixed amount must be > 0", ErrInvalidRateRule)
+	case r.Adjustment == RatePercent && r.Percent < -100:
+		return fmt.Errorf("%w: percent must be >= -100", ErrInvalidRateRule)
+	case r.StartDate != nil && r.EndDate != nil && DateOf(*r.EndDate).Before(DateOf(*r.StartDate)):
+		return fmt.Errorf("%w: end_date is before start_date", ErrInvalidRateRule)
+	}
+	for _, d := range r.Days {
+		if !d.Valid() {
+			return fmt.Errorf("%w: unknown day %q", ErrInvalidRateRule, d)
+		}
+	}
+	return nil
+}
+
+// night の泊に適用するか
+func (r *RateRule) AppliesTo(night time.Time, cal HolidayCalendar) bool {
+	night = DateOf(night)
+	if r.StartDate != nil && night.Before(DateOf(*r.StartDate)) {
+		return false
+	}
+	if r.EndDate != nil && night.After(DateOf(*r.EndDate)) {
+		return false
+	}
+	if len(r.Days) == 0 {
+		return true
+	}
+	for _, d := range r.Days {
+		switch d {
+		case RateDayHoliday:
+			if cal.IsHoliday(night) {
+				return true
+			}
+		case RateDayHolidayEve:
+			if cal.IsHoliday(night.AddDate(0, 0, 1)) {
+				return true
+			}
+		default:
+			if weekdayRateDays[night.Weekday()] == d {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,15 +1,74 @@
 package entity
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// 予約ステータス
+type ReservationStatus string
+
+const (
+	ReservationPending    ReservationStatus = "pending"     // 仮予約
+	ReservationConfirmed  ReservationStatus = "confirmed"   // 確定
+	ReservationCancelled  ReservationStatus = "cancelled"   // キャンセル済み
+	ReservationCheckedIn  ReservationStatus = "checked_in"  // チェックイン済み
+	ReservationCheckedOut ReservationStatus = "checked_out" // チェックアウト済み
+	ReservationNoShow     ReservationStatus = "no_show"     // 不泊
+)
+
+var ErrInvalidStatusTransition = errors.New("invalid reservation status transition")
+
+// 許可されるステータス遷移（ここに無い遷移はすべて不正）
+var reservationTransitions = map[ReservationStatus][]ReservationStatus{
+	ReservationPending:   {ReservationConfirmed, ReservationCancelled},
+	ReservationConfirmed: {ReservationCancelled, ReservationCheckedIn, ReservationNoShow},
+	ReservationCheckedIn: {ReservationCheckedOut},
+}
+
+func (s ReservationStatus) Valid() bool {
+	switch s {
+	case ReservationPending, ReservationConfirmed, ReservationCancelled,
+		ReservationCheckedIn, ReservationCheckedOut, ReservationNoShow:
+		return true
+	}
+	return false
+}
+
+func (s ReservationStatus) CanTransitionTo(next ReservationStatus) bool {
+	for _, v := range reservationTransitions[s] {
+		if v == next {
+			return true
+		}
+	}
+	return false
+}
 
 type Reservation struct {
 	ID       int
 	UserID   string
 	PlanID   int
-	Number   int
+	Number   int    // 合計人数（Guests.Total()）
+	Guests   Guests // 大人・子どもの内訳
 	Checkin  time.Time
 	Checkout time.Time
-	Total    int // 計算済み合計金額
+	Total    Money // 計算済み合計金額（プランの通貨、クーポンの割引後・税込み）
+	// 税の内訳。Total = Subtotal + ConsumptionTax + AccommodationTax
+	Subtotal         Money
+	ConsumptionTax   Money
+	AccommodationTax Money
+	// 使ったクーポン（CouponID が 0 なら無し）と割引額。割引前の金額は Subtotal + Discount
+	CouponID   int
+	CouponCode string
+	Discount   Money
+	Status     ReservationStatus
+	// 予約時点のキャンセルポリシー（nil ならキャンセル料なし）と、キャンセルしたときに確定したキャンセル料
+	CancellationPolicy *CancellationPolicy
+	CancellationFee    Money
+	// 泊ごとの料金の内訳（合計は Subtotal + Discount）。予約時点の料金ルールで計算したもの
+	Breakdown []NightCharge
+	// 楽観的排他制御のバージョン。作成時は 1 で、保存するたびに 1 ずつ増える
+	Version int
 }
 
 func (r *Reservation) Nights() int {
@@ -19,3 +78,21 @@ func (r *Reservation) Nights() int {
 	}
 	return int(d)
 }
+
+// ステータスを遷移させる。状態機械に反する遷移は ErrInvalidStatusTransition
+func (r *Reservation) TransitionTo(next ReservationStatus) error {
+	if !r.Status.CanTransitionTo(next) {
+		return ErrInvalidStatusTransition
+	}
+	r.Status = next
+	return nil
+}
+
+// 日程・人数を変更できるのは宿泊前の有効な予約のみ
+func (r *Reservation) CanModify() bool {
+	return r.Status == ReservationPending || r.Status == ReservationConfirmed
+}
+
+func (r *Reservation) Cancel() error {
+	return r.TransitionTo(ReservationCancelled)
+}
@@ -0,0 +1,28 @@
+package entity
+
+type Role string
+
+const (
+	RoleGuest Role = "guest" // 登録時に付与。自分の予約・プロフィールだけを扱える
+	RoleStaff Role = "staff" // すべての予約・ユーザーの参照と変更、プラン・クーポン管理
+	RoleAdmin Role = "admin" // スタッフの権限に加えてロールの付与・剥奪
+)
+
+// ルートやユースケースが要求する操作の単位。ロールにひも付けて永続化する
+type Permission string
+
+const (
+	PermReservationsBook      Permission = "reservations:book"       // 予約の作成と自分の予約の参照・変更
+	PermReservationsManageAll Permission = "reservations:manage_all" // 全ユーザーの予約の参照・変更
+	PermUsersReadAll          Permission = "users:read_all"          // 他人のプロフィールの参照
+	PermPlansManage           Permission = "plans:manage"            // プランの登録・変更・削除
+	PermRolesManage           Permission = "roles:manage"            // ロールの付与・剥奪
+	PermCouponsManage         Permission = "coupons:manage"          // クーポンの発行・変更
+)
+
+// 初期状態のロールと権限。MySQL ではマイグレーションで同じ内容を投入する
+var DefaultRolePermissions = map[Role][]Permission{
+	RoleGuest: {PermReservationsBook},
+	RoleStaff: {PermReservationsBook, PermReservationsManageAll, PermUsersReadAll, PermPlansManage, PermCouponsManage},
+	RoleAdmin: {PermReservationsBook, PermReservationsManageAll, PermUsersReadAll, PermPlansManage, PermCouponsManage, PermRolesManage},
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// ログインセッション。アクセストークンは SessionID を持ち、リフレッシュトークンはハッシュだけを保存する
+type Session struct {
+	ID               string
+	UserID           string
+	RefreshTokenHash string
+	ExpiresAt        time.Time // リフレッシュトークンの有効期限
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+}
+
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// checkin から checkout 前日までの宿泊日を返す（checkout 当日は含まない）
+func StayDates(checkin, checkout time.Time) []time.Time {
+	start := DateOf(checkin)
+	end := DateOf(checkout)
+	var out []time.Time
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		out = append(out, d)
+	}
+	return out
+}
+
+// checkin から checkout までの泊数。StayDates と違い日付の一覧を作らないので、範囲が大きくても一定時間で返る
+func NightsBetween(checkin, checkout time.Time) int {
+	// Sub は約 292 年で飽和するが、上限の判定に使う分には十分
+	return int(DateOf(checkout).Sub(DateOf(checkin)).Hours() / 24)
+}
+
+// 時刻とタイムゾーンを落として日付だけにする（UTC の 0 時に正規化）
+func DateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
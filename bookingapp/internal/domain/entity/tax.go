@@ -0,0 +1,142 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrInvalidTaxRules     = errors.New("invalid tax rules")
+	ErrUnknownJurisdiction = errors.New("unknown tax jurisdiction")
+)
+
+// 課税地域（都道府県・市区町村など）ごとの税のルール
+type TaxRules struct {
+	// Plan.Jurisdiction が空のプランに使う地域
+	Default       string
+	Jurisdictions map[string]Jurisdiction
+}
+
+type Jurisdiction struct {
+	Name string
+	// 消費税率（割引後の料金に対する %、端数切り捨て）
+	ConsumptionTaxPercent int
+	// 宿泊税。1人1泊の料金で税額が決まる（空なら課税しない）
+	AccommodationTax []AccommodationTaxBracket
+}
+
+// 1人1泊の料金が MinRate 以上なら 1人1泊 Tax を課税する（当たる段階のうち MinRate の最も大きいもの）
+type AccommodationTaxBracket struct {
+	MinRate Money
+	Tax     Money
+}
+
+// 予約の税の内訳
+type TaxBreakdown struct {
+	Subtotal         Money // 税抜（クーポンの割引後）
+	ConsumptionTax   Money
+	AccommodationTax Money // 消費税の対象外
+	Total            Money // Subtotal + 消費税 + 宿泊税
+}
+
+func (t *TaxRules) Validate() error {
+	if _, ok := t.Jurisdictions[t.Default]; !ok {
+		return fmt.Errorf("%w: default jurisdiction %q is not defined", ErrInvalidTaxRules, t.Default)
+	}
+	for code, j := range t.Jurisdictions {
+		if code == "" {
+			return fmt.Errorf("%w: jurisdiction code must not be empty", ErrInvalidTaxRules)
+		}
+		if j.ConsumptionTaxPercent < 0 || j.ConsumptionTaxPercent > 100 {
+			return fmt.Errorf("%w: %s: consumption tax must be between 0 and 100", ErrInvalidTaxRules, code)
+		}
+		for _, b := range j.AccommodationTax {
+			if b.MinRate.Amount < 0 || b.Tax.Amount < 0 {
+				return fmt.Errorf("%w: %s: accommodation tax must be >= 0", ErrInvalidTaxRules, code)
+			}
+			if b.MinRate.Currency != b.Tax.Currency || b.Tax.Currency != j.AccommodationTax[0].Tax.Currency {
+				return fmt.Errorf("%w: %s: accommodation tax must use a single currency", ErrInvalidTaxRules, code)
+			}
+		}
+	}
+	return nil
+}
+
+// code の地域のルール（空なら Default）
+func (t *TaxRules) Jurisdiction(code string) (*Jurisdiction, error) {
+	if code == "" {
+		code = t.Default
+	}
+	j, ok := t.Jurisdictions[code]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownJurisdiction, code)
+	}
+	return &j, nil
+}
+
+// 料金の通貨 c で宿泊税を計算できるか（宿泊税の無い地域はどの通貨でもよい）
+func (j *Jurisdiction) CheckCurrency(c Currency) error {
+	if len(j.AccommodationTax) == 0 || j.AccommodationTax[0].MinRate.Currency == c {
+		return nil
+	}
+	return fmt.Errorf("%w: accommodation tax in %s for a rate in %s", ErrCurrencyMismatch, j.AccommodationTax[0].MinRate.Currency, c)
+}
+
+// subtotal（割引後の料金）に消費税を、nights の区分ごとの 1人1泊の料金に宿泊税をかける
+func (j *Jurisdiction) Calculate(subtotal Money, nights []NightCharge, guests Guests) (TaxBreakdown, error) {
+	out := TaxBreakdown{Subtotal: subtotal, AccommodationTax: NewMoney(0, subtotal.Currency)}
+	var err error
+	if out.ConsumptionTax, err = percentOf(subtotal, j.ConsumptionTaxPercent); err != nil {
+		return TaxBreakdown{}, err
+	}
+	for _, n := range nights {
+		for _, g := range []struct {
+			rate  Money
+			count int
+		}{
+			{n.Rate, guests.Adults},
+			{n.ChildRate, guests.Children},
+			{n.InfantRate, guests.Infants},
+		} {
+			if g.count == 0 {
+				continue
+			}
+			tax, err := j.accommodationTax(g.rate)
+			if err != nil {
+				return TaxBreakdown{}, err
+			}
+			if tax, err = tax.Mul(int64(g.count)); err != nil {
+				return TaxBreakdown{}, err
+			}
+			if out.AccommodationTax, err = out.AccommodationTax.Add(tax); err != nil {
+				return TaxBreakdown{}, err
+			}
+		}
+	}
+	if out.Total, err = subtotal.Add(out.ConsumptionTax); err != nil {
+		return TaxBreakdown{}, err
+	}
+	if out.Total, err = out.Total.Add(out.AccommodationTax); err != nil {
+		return TaxBreakdown{}, err
+	}
+	return out, nil
+}
+
+// 1人1泊の料金 rate にかかる宿泊税
+func (j *Jurisdiction) accommodationTax(rate Money) (Money, error) {
+	if len(j.AccommodationTax) == 0 {
+		return NewMoney(0, rate.Currency), nil
+	}
+	if err := j.CheckCurrency(rate.Currency); err != nil {
+		return Money{}, err
+	}
+	brackets := append([]AccommodationTaxBracket(nil), j.AccommodationTax...)
+	sort.Slice(brackets, func(a, b int) bool { return brackets[a].MinRate.Amount > brackets[b].MinRate.Amount })
+	for _, b := range brackets {
+		if rate.Amount >= b.MinRate.Amount {
+			return b.Tax, nil
+		}
+	}
+	return NewMoney(0, rate.Currency), nil
+}
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJurisdictionCalculate(t *testing.T) {
+	j := &Jurisdiction{
+		ConsumptionTaxPercent: 10,
+		AccommodationTax: []AccommodationTaxBracket{
+			{MinRate: Yen(10000), Tax: Yen(100)},
+			{MinRate: Yen(15000), Tax: Yen(200)},
+		},
+	}
+	d := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	nights := []NightCharge{
+		{Date: d, Rate: Yen(15000), ChildRate: Yen(10500), InfantRate: Yen(7500)},
+		{Date: d.AddDate(0, 0, 1), Rate: Yen(12000), ChildRate: Yen(8400), InfantRate: Yen(6000)},
+	}
+	// 1泊目: 大人 200×2 + 小学生 100 + 幼児 0、2泊目: 大人 100×2
+	got, err := j.Calculate(Yen(70005), nights, Guests{Adults: 2, Children: 1, Infants: 1})
+	if err != nil {
+		t.Fatalf("Calculate: %v", err)
+	}
+	want := TaxBreakdown{Subtotal: Yen(70005), ConsumptionTax: Yen(7000), AccommodationTax: Yen(700), Total: Yen(77705)}
+	if got != want {
+		t.Errorf("Calculate = %+v, want %+v", got, want)
+	}
+
+	if _, err := j.Calculate(NewMoney(100, USD), []NightCharge{{Rate: NewMoney(100, USD)}}, Guests{Adults: 1}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Calculate(USD) = %v, want ErrCurrencyMismatch", err)
+	}
+	// 宿泊税の通貨と違う料金は計算できない。宿泊税の無い地域はどの通貨でもよい
+	if err := j.CheckCurrency(USD); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("CheckCurrency(USD) = %v, want ErrCurrencyMismatch", err)
+	}
+	if err := j.CheckCurrency(JPY); err != nil {
+		t.Errorf("CheckCurrency(JPY) = %v", err)
+	}
+	if err := (&Jurisdiction{ConsumptionTaxPercent: 10}).CheckCurrency(USD); err != nil {
+		t.Errorf("CheckCurrency(USD) without accommodation tax = %v", err)
+	}
+}
+
+func TestTaxRulesJurisdiction(t *testing.T) {
+	rules := &TaxRules{Default: "jp", Jurisdictions: map[string]Jurisdiction{"jp": {ConsumptionTaxPercent: 10}}}
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if j, err := rules.Jurisdiction(""); err != nil || j.ConsumptionTaxPercent != 10 {
+		t.Errorf("Jurisdiction(\"\") = %+v, %v; want the default", j, err)
+	}
+	if _, err := rules.Jurisdiction("mars"); !errors.Is(err, ErrUnknownJurisdiction) {
+		t.Errorf("Jurisdiction(unknown) = %v, want ErrUnknownJurisdiction", err)
+	}
+	rules.Default = "tokyo"
+	if err := rules.Validate(); !errors.Is(err, ErrInvalidTaxRules) {
+		t.Errorf("Validate(missing default) = %v, want ErrInvalidTaxRules", err)
+	}
+}
@@ -1,6 +1,9 @@
 package entity
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 type User struct {
 	ID           string    // ユーザーID
@@ -11,4 +14,11 @@ type User struct {
 	DateOfBirth  time.Time // 生年月日
 	RegisteredAt time.Time // 登録日
 	Status       string    // アカウントステータス（例: "active", "inactive"）
+	PasswordHash string    // パスワードのハッシュ（平文は保持しない）
+	Roles        []Role    // 付与されているロール（名前順）
+	Version      int       // 楽観的排他制御のバージョン（作成時は 1、ロールを変えるたびに増える）
+}
+
+func (u *User) HasRole(role Role) bool {
+	return u != nil && slices.Contains(u.Roles, role)
 }
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"bookingapp/internal/domain/entity"
+	"context"
+	"errors"
+)
+
+var (
+	// カード会社などに断られた（支払い手段を変えれば通る可能性がある）
+	ErrPaymentDeclined = errors.New("payment declined")
+	// 状態に合わない操作（与信額を超える請求、請求済みの与信の取り消しなど）
+	ErrInvalidPaymentOperation = errors.New("invalid payment operation")
+)
+
+type AuthorizeRequest struct {
+	ReservationID int
+	Amount        entity.Money
+	// 支払い手段（カードのトークンなど）。扱いは実装による
+	Token string
+}
+
+// 決済代行のポート（実装は infrastructure/payment）。
+// ref は Authorize が返す取引 ID で、以後の操作はこれで指定する
+type PaymentGateway interface {
+	// 与信を取る。断られた場合は ErrPaymentDeclined
+	Authorize(ctx context.Context, req AuthorizeRequest) (ref string, err error)
+	// 与信額以下の金額を請求する
+	Capture(ctx context.Context, ref string, amount entity.Money) error
+	// 請求額以下の金額を返金する
+	Refund(ctx context.Context, ref string, amount entity.Money) error
+	// 請求前の与信を取り消す
+	Void(ctx context.Context, ref string) error
+}
@@ -1,21 +1,147 @@
 package repository
 
-import "bookingapp/internal/domain/entity"
+import (
+	"bookingapp/internal/domain/entity"
+	"context"
+	"errors"
+	"time"
+)
+
+// 指定したプランが存在しない場合に返す
+var ErrPlanNotFound = errors.New("plan not found")
+
+// 在庫が1泊でも不足している場合に返す
+var ErrSoldOut = errors.New("plan is sold out for the requested dates")
+
+// メールアドレスの一意制約に違反した場合に返す
+var ErrDuplicateEmail = errors.New("duplicate email")
+
+// 定義されていないロールを指定した場合に返す
+var ErrRoleNotFound = errors.New("role not found")
+
+// クーポンのコードの一意制約に違反した場合に返す
+var ErrDuplicateCouponCode = errors.New("duplicate coupon code")
+
+// 保存しようとした行のバージョンが読み込んだ時点から変わっていた（他の更新と競合した）場合に返す
+var ErrConcurrentModification = errors.New("resource was modified concurrently")
+
+// クーポンの利用回数が上限に達している場合に返す
+var (
+	ErrCouponExhausted = errors.New("coupon has reached its redemption limit")
+	ErrCouponUserLimit = errors.New("coupon has reached its per-user limit")
+)
 
 type PlanRepository interface {
-	FindByID(id int) (*entity.Plan, error)
-	SearchByKeyword(keyword string) ([]*entity.Plan, error)
+	// 削除済みのプランも返す（既存の予約から参照するため）
+	FindByID(ctx context.Context, id int) (*entity.Plan, error)
+	// spec に合うプランを spec.Sort の順に返す。削除済みのプランは含めない
+	Search(ctx context.Context, spec entity.PlanSpec) ([]*entity.Plan, error)
+	// ID が 0 なら採番して新規作成、それ以外は名前・キーワード・価格・販売室数を上書きする。
+	// 販売室数の変更は作成済みの在庫にも反映する。
+	// 上書きは plan.Version が保存されているバージョンと一致する場合だけで、違えば ErrConcurrentModification。
+	// ID のプランが無ければ ErrPlanNotFound。
+	// 返すプランの Version は保存後のバージョン
+	Save(ctx context.Context, plan *entity.Plan) (*entity.Plan, error)
+	// 論理削除してバージョンを上げる。削除済み・存在しなければ何もしない。
+	// 削除は version が保存されているバージョンと一致する場合だけで、違えば ErrConcurrentModification
+	Delete(ctx context.Context, id, version int, at time.Time) error
+	// checkin〜checkout前日の各泊について在庫を1室ずつ確保する。
+	// 1泊でも空きが無ければ何も確保せず ErrSoldOut を返す。プランが無ければ ErrPlanNotFound
+	ReserveNights(ctx context.Context, planID int, checkin, checkout time.Time) error
+	// ReserveNights で確保した在庫を戻す
+	ReleaseNights(ctx context.Context, planID int, checkin, checkout time.Time) error
+	// 旧日程の在庫を戻して新日程を確保する。新日程が満室なら何も変えずに ErrSoldOut
+	RebookNights(ctx context.Context, planID int, oldCheckin, oldCheckout, newCheckin, newCheckout time.Time) error
+	// from〜to前日の各泊の残室数を返す（Price は呼び出し側で埋める）。プランが無ければ ErrPlanNotFound
+	Availability(ctx context.Context, planID int, from, to time.Time) ([]entity.NightAvailability, error)
+
+	// プランの料金ルールを ID 順に返す
+	RateRules(ctx context.Context, planID int) ([]entity.RateRule, error)
+	// ID が 0 なら採番して新規作成、それ以外は上書きする
+	SaveRateRule(ctx context.Context, rule *entity.RateRule) (*entity.RateRule, error)
+	// 無ければ何もしない
+	DeleteRateRule(ctx context.Context, planID, ruleID int) error
 }
 
 type ReservationRepository interface {
-	NextID() int
-	Save(reservation *entity.Reservation) (*entity.Reservation, error)
-	FindByID(id int) (*entity.Reservation, error)
-	List() ([]*entity.Reservation, error)
+	// ID が 0 なら採番して新規作成、それ以外は上書き保存する。料金の内訳（Breakdown）も置き換える。
+	// 上書きは reservation.Version が保存されているバージョンと一致する場合だけで、違えば（削除済みも含めて）
+	// ErrConcurrentModification。保存できれば reservation の ID と Version を保存後の値にする
+	Save(ctx context.Context, reservation *entity.Reservation) (*entity.Reservation, error)
+	// 料金の内訳も返す
+	FindByID(ctx context.Context, id int) (*entity.Reservation, error)
+	// 条件に合う予約を q.Sort の順に最大 q.Limit 件返す。FindByID と同じく料金の内訳も返す
+	List(ctx context.Context, q ReservationQuery) ([]*entity.Reservation, error)
+}
+
+type CouponRepository interface {
+	// 無ければ nil。code は entity.NormalizeCouponCode 済みであること
+	FindByCode(ctx context.Context, code string) (*entity.Coupon, error)
+	// 無ければ nil
+	FindByID(ctx context.Context, id int) (*entity.Coupon, error)
+	// ID 順
+	List(ctx context.Context) ([]*entity.Coupon, error)
+	// ID が 0 なら採番して新規作成、それ以外は定義を上書きする（利用数は変えない）。
+	// コードが重複すれば ErrDuplicateCouponCode
+	Save(ctx context.Context, coupon *entity.Coupon) (*entity.Coupon, error)
+	// 予約 reservationID の分の利用枠を 1 つ確保する。全体の上限に達していれば ErrCouponExhausted、
+	// userID の利用数が上限に達していれば ErrCouponUserLimit（どちらも何も変えない）。同時に呼ばれても上限を超えない
+	Redeem(ctx context.Context, couponID int, userID string, reservationID int) error
+	// 予約の利用枠を戻す。確保していなければ何もしない
+	Release(ctx context.Context, reservationID int) error
+}
+
+type CancellationPolicyRepository interface {
+	// 無ければ nil
+	FindByID(ctx context.Context, id int) (*entity.CancellationPolicy, error)
+	// ID 順
+	List(ctx context.Context) ([]*entity.CancellationPolicy, error)
+	// ID が 0 なら採番して新規作成、それ以外は上書きする（既存の予約に写した内容は変わらない）
+	Save(ctx context.Context, policy *entity.CancellationPolicy) (*entity.CancellationPolicy, error)
+}
+
+type PaymentRepository interface {
+	// 予約の決済。無ければ nil
+	FindByReservation(ctx context.Context, reservationID int) (*entity.Payment, error)
+	// ID が 0 なら採番して新規作成、それ以外は上書き保存してバージョンを上げた決済を返す。
+	// 読み込んだ後に他で保存されていれば（Version が違えば）ErrConcurrentModification
+	Save(ctx context.Context, payment *entity.Payment) (*entity.Payment, error)
+}
+
+type IdempotencyRepository interface {
+	// rec を保存する。同じユーザー・キーの記録が既にあれば保存せずにその記録を返す（無ければ nil）。
+	// 同時に呼ばれても保存されるのは 1 つだけ
+	Create(ctx context.Context, rec *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error)
+	// 記録 id に処理結果を保存する
+	Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error
+	// 記録 id を消す。無ければ何もしない
+	Delete(ctx context.Context, id string) error
+	// ExpiresAt が at 以前の記録を消し、消した件数を返す
+	DeleteExpired(ctx context.Context, at time.Time) (int, error)
 }
 
 type UserRepository interface {
-	Create(user *entity.User) (*entity.User, error)
-	FindByEmail(email string) (*entity.User, error)
-	Get(id string) (*entity.User, error)
+	// Roles が空ならゲストとして作成する
+	Create(ctx context.Context, user *entity.User) (*entity.User, error)
+	FindByEmail(ctx context.Context, email string) (*entity.User, error)
+	Get(ctx context.Context, id string) (*entity.User, error)
+	// ロールを付与してユーザーのバージョンを上げる（付与済みでも上げる）。
+	// version が 0 でなければ、ユーザーのバージョンが一致しない場合に何もせず ErrConcurrentModification
+	GrantRole(ctx context.Context, userID string, role entity.Role, version int) error
+	// ロールを剥奪してユーザーのバージョンを上げる（付与されていなくても上げる）。version は GrantRole と同じ
+	RevokeRole(ctx context.Context, userID string, role entity.Role, version int) error
+}
+
+type RoleRepository interface {
+	// ロールに付いている権限。定義されていないロールなら ErrRoleNotFound
+	Permissions(ctx context.Context, role entity.Role) ([]entity.Permission, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *entity.Session) error
+	Get(ctx context.Context, id string) (*entity.Session, error)
+	FindByRefreshTokenHash(ctx context.Context, hash string) (*entity.Session, error)
+	// セッションを失効させ、この呼び出しで失効させたかを返す。
+	// 失効済み・存在しないセッションに対しては何もせず false（同時に呼ばれても true になるのは 1 回だけ）
+	Revoke(ctx context.Context, id string, at time.Time) (bool, error)
 }
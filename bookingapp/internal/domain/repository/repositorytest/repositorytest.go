@@ -0,0 +1,1319 @@
+// Package repositorytest は repository パッケージのインターフェースに対する共通の適合テスト。
+// インメモリ実装・MySQL 実装の両方を同じテストで検証し、振る舞いの差異を検出する。
+package repositorytest
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// テスト対象のリポジトリ一式。Tx はトランザクションを検証するときに使う
+type Backend struct {
+	repository.Repositories
+	Roles                repository.RoleRepository
+	Sessions             repository.SessionRepository
+	CancellationPolicies repository.CancellationPolicyRepository
+	Idempotency          repository.IdempotencyRepository
+	Tx                   repository.UnitOfWork
+}
+
+// 空の状態に plans だけを投入した Backend を返す。呼び出しごとに独立した状態であること
+type Factory func(t *testing.T, plans []*entity.Plan) Backend
+
+// テスト共通の初期プラン
+var SeedPlans = []*entity.Plan{
+	{ID: 100, Name: "富士プレミアム", Keyword: "富士 山 静岡", Price: entity.Yen(12000), Capacity: 2, MaxGuests: 4, ChildRate: 70, InfantRate: 50},
+	{ID: 175, Name: "South Basic", Keyword: "サウス 南", Price: entity.Yen(8000), Capacity: 1, MaxGuests: 2},
+	{ID: 200, Name: "北の宿", Keyword: "北海道 北", Price: entity.Yen(10000), Capacity: 3, MinGuests: 2, MaxGuests: 6, ChildRate: 50},
+}
+
+func Run(t *testing.T, newBackend Factory) {
+	t.Run("Plans", func(t *testing.T) { testPlans(t, newBackend) })
+	t.Run("PlanSearch", func(t *testing.T) { testPlanSearch(t, newBackend) })
+	t.Run("PlanWrites", func(t *testing.T) { testPlanWrites(t, newBackend) })
+	t.Run("RateRules", func(t *testing.T) { testRateRules(t, newBackend) })
+	t.Run("Inventory", func(t *testing.T) { testInventory(t, newBackend) })
+	t.Run("Reservations", func(t *testing.T) { testReservations(t, newBackend) })
+	t.Run("ReservationQuery", func(t *testing.T) { testReservationQuery(t, newBackend) })
+	t.Run("Coupons", func(t *testing.T) { testCoupons(t, newBackend) })
+	t.Run("Payments", func(t *testing.T) { testPayments(t, newBackend) })
+	t.Run("CancellationPolicies", func(t *testing.T) { testCancellationPolicies(t, newBackend) })
+	t.Run("Idempotency", func(t *testing.T) { testIdempotency(t, newBackend) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, newBackend) })
+	t.Run("Roles", func(t *testing.T) { testRoles(t, newBackend) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newBackend) })
+	t.Run("UnitOfWork", func(t *testing.T) { testUnitOfWork(t, newBackend) })
+}
+
+func testPlans(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	p, err := b.Plans.FindByID(ctx, 100)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if p == nil || p.Name != "富士プレミアム" || p.Price != entity.Yen(12000) || p.Capacity != 2 ||
+		p.MinGuests != 1 || p.MaxGuests != 4 || p.ChildRate != 70 || p.InfantRate != 50 {
+		t.Fatalf("FindByID(100) = %+v", p)
+	}
+	// 子ども料金 0%（無料）も保存できる
+	if p, err := b.Plans.FindByID(ctx, 200); err != nil || p == nil || p.MinGuests != 2 || p.ChildRate != 50 || p.InfantRate != 0 {
+		t.Fatalf("FindByID(200) = %+v, %v", p, err)
+	}
+	if p, err := b.Plans.FindByID(ctx, 999); err != nil || p != nil {
+		t.Fatalf("FindByID(missing) = %+v, %v; want nil, nil", p, err)
+	}
+
+	cases := []struct {
+		keyword string
+		want    []int
+	}{
+		{"", []int{100, 175, 200}},
+		{"   ", []int{100, 175, 200}},
+		{"富士", []int{100}},
+		{"北", []int{200}},
+		{"south", []int{175}}, // 大文字小文字を区別しない
+		{"該当なし", []int{}},
+	}
+	for _, c := range cases {
+		got, err := b.Plans.Search(ctx, entity.PlanSpec{Keyword: c.keyword})
+		if err != nil {
+			t.Fatalf("Search(%q): %v", c.keyword, err)
+		}
+		if ids := planIDs(got); !equalInts(ids, c.want) {
+			t.Errorf("Search(%q) = %v, want %v (id asc)", c.keyword, ids, c.want)
+		}
+	}
+}
+
+func testPlanSearch(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	plans := append(slices.Clone(SeedPlans),
+		&entity.Plan{ID: 300, Name: "静岡 Mt. Fuji view", Keyword: "眺望", Price: entity.NewMoney(8000, entity.USD), Capacity: 1, MaxGuests: 2},
+		&entity.Plan{ID: 400, Name: "100%天然温泉", Keyword: "温泉 露天", Price: entity.Yen(15000), Capacity: 1, MaxGuests: 3},
+		&entity.Plan{ID: 500, Name: "Café もみじ", Keyword: "かえで", Price: entity.Yen(20000), Capacity: 1, MaxGuests: 2},
+	)
+	b := newBackend(t, plans)
+	// 300 は day(1) の泊が満室
+	if err := b.Plans.ReserveNights(ctx, 300, day(1), day(2)); err != nil {
+		t.Fatalf("ReserveNights: %v", err)
+	}
+	in, out := day(0), day(2)
+
+	cases := []struct {
+		name string
+		spec entity.PlanSpec
+		want []int
+	}{
+		{"all", entity.PlanSpec{}, []int{100, 175, 200, 300, 400, 500}},
+		// 価格の範囲は指定した通貨（省略時は JPY）のプランだけで比べる
+		{"price range", entity.PlanSpec{MinPrice: 8000, MaxPrice: 12000}, []int{100, 175, 200}},
+		{"price range usd", entity.PlanSpec{MinPrice: 8000, MaxPrice: 12000, Currency: entity.USD}, []int{300}},
+		{"min price only", entity.PlanSpec{MinPrice: 12000}, []int{100, 400, 500}},
+		{"currency", entity.PlanSpec{Currency: entity.USD}, []int{300}},
+		{"guests", entity.PlanSpec{Guests: 3}, []int{100, 200, 400}},
+		{"guests below min", entity.PlanSpec{Guests: 1}, []int{100, 175, 300, 400, 500}},
+		{"ids", entity.PlanSpec{IDs: []int{400, 100, 999}}, []int{100, 400}},
+		{"empty ids", entity.PlanSpec{IDs: []int{}}, []int{}},
+		{"available", entity.PlanSpec{Checkin: &in, Checkout: &out}, []int{100, 175, 200, 400, 500}},
+		// 名前に含む 300 が、キーワード欄だけに含む 100 より前
+		{"relevance", entity.PlanSpec{Keyword: "静岡"}, []int{300, 100}},
+		{"relevance keyword case", entity.PlanSpec{Keyword: "FUJI", Sort: entity.PlanSortRelevance}, []int{300}},
+		// 大文字小文字以外（アクセント・ひらがなとカタカナ）は区別する
+		{"accent", entity.PlanSpec{Keyword: "CAFÉ"}, []int{500}},
+		{"accent is distinct", entity.PlanSpec{Keyword: "cafe"}, []int{}},
+		{"kana is distinct", entity.PlanSpec{Keyword: "カエデ"}, []int{}},
+		// 通貨コード順に、通貨ごとに安い順
+		{"sort price", entity.PlanSpec{Sort: entity.PlanSortPrice}, []int{175, 200, 100, 400, 500, 300}},
+		{"sort name", entity.PlanSpec{Sort: entity.PlanSortName}, []int{400, 500, 175, 200, 100, 300}},
+		{"offset limit", entity.PlanSpec{Sort: entity.PlanSortPrice, Offset: 1, Limit: 2}, []int{200, 100}},
+		{"offset past end", entity.PlanSpec{Offset: 10}, []int{}},
+		// LIKE のワイルドカードは文字として扱う
+		{"literal percent", entity.PlanSpec{Keyword: "0%"}, []int{400}},
+		{"literal underscore", entity.PlanSpec{Keyword: "_"}, []int{}},
+	}
+	for _, c := range cases {
+		got, err := b.Plans.Search(ctx, c.spec)
+		if err != nil {
+			t.Fatalf("%s: Search: %v", c.name, err)
+		}
+		if ids := planIDs(got); !equalInts(ids, c.want) {
+			t.Errorf("%s: Search = %v, want %v", c.name, ids, c.want)
+		}
+	}
+}
+
+func testPlanWrites(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	created, err := b.Plans.Save(ctx, &entity.Plan{Name: "湖畔の宿", Keyword: "湖", Price: entity.Yen(9000), Capacity: 2, Jurisdiction: "kyoto", CancellationPolicyID: 3})
+	if err != nil {
+		t.Fatalf("Save(new): %v", err)
+	}
+	if created.ID <= 200 || created.Version != 1 {
+		t.Fatalf("Save(new) assigned id %d, version %d; want > existing ids, version 1", created.ID, created.Version)
+	}
+	if got, err := b.Plans.FindByID(ctx, created.ID); err != nil || got == nil || got.Name != "湖畔の宿" || got.Jurisdiction != "kyoto" ||
+		got.CancellationPolicyID != 3 || got.Deleted() {
+		t.Fatalf("FindByID(created) = %+v, %v", got, err)
+	}
+
+	// 販売室数の変更は確保済みの在庫にも反映される
+	if err := b.Plans.ReserveNights(ctx, created.ID, day(1), day(2)); err != nil {
+		t.Fatalf("ReserveNights: %v", err)
+	}
+	created.Price = entity.NewMoney(9500, entity.EUR)
+	created.Capacity = 3
+	created.Jurisdiction = ""
+	created.CancellationPolicyID = 0
+	updated, err := b.Plans.Save(ctx, created)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("Save(update) = %+v, %v; want version 2", updated, err)
+	}
+	if got, _ := b.Plans.FindByID(ctx, created.ID); got == nil || got.Price != entity.NewMoney(9500, entity.EUR) || got.Capacity != 3 || got.Jurisdiction != "" ||
+		got.CancellationPolicyID != 0 || got.Version != 2 {
+		t.Fatalf("FindByID after update = %+v", got)
+	}
+	// 読み込んだ後に更新されたプランは上書きしない
+	stale := *created
+	stale.Name = "古い内容"
+	if _, err := b.Plans.Save(ctx, &stale); !errors.Is(err, repository.ErrConcurrentModification) {
+		t.Fatalf("Save(stale version) = %v, want ErrConcurrentModification", err)
+	}
+	if got, _ := b.Plans.FindByID(ctx, created.ID); got == nil || got.Name != "湖畔の宿" {
+		t.Fatalf("stale Save overwrote the plan: %+v", got)
+	}
+	assertRemaining(t, b, created.ID, day(1), day(3), []int{2, 3})
+
+	at := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	// 読み込んだ後に更新されたプランは削除しない
+	if err := b.Plans.Delete(ctx, created.ID, 1, at); !errors.Is(err, repository.ErrConcurrentModification) {
+		t.Fatalf("Delete(stale version) = %v, want ErrConcurrentModification", err)
+	}
+	if got, _ := b.Plans.FindByID(ctx, created.ID); got == nil || got.Deleted() || got.Version != 2 {
+		t.Fatalf("stale Delete changed the plan: %+v", got)
+	}
+	// 削除済みのプランをもう一度削除しても何もしない
+	for i := 0; i < 2; i++ {
+		if err := b.Plans.Delete(ctx, created.ID, 2, at.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	if err := b.Plans.Delete(ctx, 12345, 1, at); err != nil {
+		t.Fatalf("Delete(missing) = %v, want nil", err)
+	}
+	// 削除済みでも ID では引ける（削除日時は最初の削除のまま）。バージョンは削除で上がる
+	got, err := b.Plans.FindByID(ctx, created.ID)
+	if err != nil || got == nil || !got.Deleted() || !got.DeletedAt.Equal(at) || got.Version != 3 {
+		t.Fatalf("FindByID(deleted) = %+v, %v", got, err)
+	}
+	// 検索からは外れる
+	for _, kw := range []string{"", "湖"} {
+		list, err := b.Plans.Search(ctx, entity.PlanSpec{Keyword: kw})
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if slices.Contains(planIDs(list), created.ID) {
+			t.Errorf("Search(%q) returned deleted plan %d", kw, created.ID)
+		}
+	}
+	// 上書き保存しても削除状態は変わらない
+	got.Name = "湖畔の宿（改）"
+	if _, err := b.Plans.Save(ctx, got); err != nil {
+		t.Fatalf("Save(deleted): %v", err)
+	}
+	if got, _ := b.Plans.FindByID(ctx, created.ID); got == nil || !got.Deleted() {
+		t.Errorf("Save cleared deleted_at: %+v", got)
+	}
+
+	// 存在しないプランは共通の ErrPlanNotFound
+	if _, err := b.Plans.Save(ctx, &entity.Plan{ID: 999, Name: "なし", Price: entity.Yen(1000), Capacity: 1, Version: 1}); !errors.Is(err, repository.ErrPlanNotFound) {
+		t.Errorf("Save(missing) = %v, want ErrPlanNotFound", err)
+	}
+	if err := b.Plans.ReserveNights(ctx, 999, day(1), day(2)); !errors.Is(err, repository.ErrPlanNotFound) {
+		t.Errorf("ReserveNights(missing) = %v, want ErrPlanNotFound", err)
+	}
+	if _, err := b.Plans.Availability(ctx, 999, day(1), day(2)); !errors.Is(err, repository.ErrPlanNotFound) {
+		t.Errorf("Availability(missing) = %v, want ErrPlanNotFound", err)
+	}
+}
+
+func testInventory(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	t.Run("reserve until sold out", func(t *testing.T) {
+		b := newBackend(t, SeedPlans)
+		for i := 0; i < 2; i++ {
+			if err := b.Plans.ReserveNights(ctx, 100, day(1), day(3)); err != nil {
+				t.Fatalf("ReserveNights #%d: %v", i+1, err)
+			}
+		}
+		if err := b.Plans.ReserveNights(ctx, 100, day(2), day(4)); !errors.Is(err, repository.ErrSoldOut) {
+			t.Fatalf("ReserveNights over capacity = %v, want ErrSoldOut", err)
+		}
+		// 売り切れで失敗した場合、空いていた day(3) も確保されていないこと
+		assertRemaining(t, b, 100, day(0), day(4), []int{2, 0, 0, 2})
+	})
+
+	t.Run("release", func(t *testing.T) {
+		b := newBackend(t, SeedPlans)
+		if err := b.Plans.ReserveNights(ctx, 175, day(1), day(3)); err != nil {
+			t.Fatalf("ReserveNights: %v", err)
+		}
+		if err := b.Plans.ReleaseNights(ctx, 175, day(1), day(3)); err != nil {
+			t.Fatalf("ReleaseNights: %v", err)
+		}
+		// 確保していない泊を戻しても在庫は増えない
+		if err := b.Plans.ReleaseNights(ctx, 175, day(1), day(3)); err != nil {
+			t.Fatalf("ReleaseNights twice: %v", err)
+		}
+		assertRemaining(t, b, 175, day(1), day(3), []int{1, 1})
+	})
+
+	t.Run("rebook", func(t *testing.T) {
+		b := newBackend(t, SeedPlans)
+		if err := b.Plans.ReserveNights(ctx, 175, day(1), day(3)); err != nil {
+			t.Fatalf("ReserveNights: %v", err)
+		}
+		// 重なる日程への付け替えは旧日程を戻してから確保するので成功する
+		if err := b.Plans.RebookNights(ctx, 175, day(1), day(3), day(2), day(4)); err != nil {
+			t.Fatalf("RebookNights: %v", err)
+		}
+		assertRemaining(t, b, 175, day(1), day(4), []int{1, 0, 0})
+
+		if err := b.Plans.ReserveNights(ctx, 175, day(5), day(6)); err != nil {
+			t.Fatalf("ReserveNights: %v", err)
+		}
+		if err := b.Plans.RebookNights(ctx, 175, day(2), day(4), day(4), day(6)); !errors.Is(err, repository.ErrSoldOut) {
+			t.Fatalf("RebookNights into sold out = %v, want ErrSoldOut", err)
+		}
+		// 失敗したら旧日程の確保はそのまま
+		assertRemaining(t, b, 175, day(1), day(6), []int{1, 0, 0, 1, 0})
+	})
+
+	t.Run("concurrent reservations never overbook", func(t *testing.T) {
+		b := newBackend(t, SeedPlans)
+		const workers = 8
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		ok := 0
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := b.Plans.ReserveNights(ctx, 200, day(1), day(3))
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					ok++
+				case !errors.Is(err, repository.ErrSoldOut):
+					t.Errorf("ReserveNights: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if ok != 3 {
+			t.Fatalf("%d reservations succeeded, want 3 (capacity)", ok)
+		}
+		assertRemaining(t, b, 200, day(1), day(3), []int{0, 0})
+	})
+}
+
+func testRateRules(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	if list, err := b.Plans.RateRules(ctx, 100); err != nil || len(list) != 0 {
+		t.Fatalf("RateRules(empty) = %+v, %v", list, err)
+	}
+	start, end := day(10), day(20)
+	season, err := b.Plans.SaveRateRule(ctx, &entity.RateRule{
+		PlanID: 100, Name: "繁忙期", StartDate: &start, EndDate: &end,
+		Adjustment: entity.RateFixed, Amount: 15000, Priority: 2,
+	})
+	if err != nil {
+		t.Fatalf("SaveRateRule: %v", err)
+	}
+	weekend, err := b.Plans.SaveRateRule(ctx, &entity.RateRule{
+		PlanID: 100, Name: "週末", Days: []entity.RateDay{entity.RateDaySat, entity.RateDayHolidayEve},
+		Adjustment: entity.RatePercent, Percent: 20,
+	})
+	if err != nil {
+		t.Fatalf("SaveRateRule: %v", err)
+	}
+	other, err := b.Plans.SaveRateRule(ctx, &entity.RateRule{PlanID: 200, Name: "割引", Adjustment: entity.RateAmount, Amount: -1000})
+	if err != nil {
+		t.Fatalf("SaveRateRule: %v", err)
+	}
+	if season.ID == 0 || weekend.ID <= season.ID || other.ID <= weekend.ID {
+		t.Fatalf("SaveRateRule assigned ids %d, %d, %d; want increasing non-zero ids", season.ID, weekend.ID, other.ID)
+	}
+
+	list, err := b.Plans.RateRules(ctx, 100)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("RateRules(100) = %+v, %v; want 2 rules", list, err)
+	}
+	got := list[0]
+	if got.ID != season.ID || got.Name != "繁忙期" || got.Adjustment != entity.RateFixed || got.Amount != 15000 ||
+		got.Priority != 2 || len(got.Days) != 0 || got.StartDate == nil || got.EndDate == nil ||
+		!entity.DateOf(*got.StartDate).Equal(start) || !entity.DateOf(*got.EndDate).Equal(end) {
+		t.Fatalf("RateRules(100)[0] = %+v", got)
+	}
+	if got := list[1]; got.ID != weekend.ID || got.Percent != 20 || got.StartDate != nil || got.EndDate != nil ||
+		!slices.Equal(got.Days, []entity.RateDay{entity.RateDaySat, entity.RateDayHolidayEve}) {
+		t.Fatalf("RateRules(100)[1] = %+v", got)
+	}
+
+	// 更新は全項目の置き換え
+	got.StartDate, got.EndDate, got.Days = nil, nil, []entity.RateDay{entity.RateDayHoliday}
+	got.Amount = 14000
+	if _, err := b.Plans.SaveRateRule(ctx, &got); err != nil {
+		t.Fatalf("SaveRateRule(update): %v", err)
+	}
+	list, _ = b.Plans.RateRules(ctx, 100)
+	if len(list) != 2 || list[0].Amount != 14000 || list[0].StartDate != nil || list[0].EndDate != nil ||
+		!slices.Equal(list[0].Days, []entity.RateDay{entity.RateDayHoliday}) {
+		t.Fatalf("RateRules after update = %+v", list)
+	}
+
+	// 別のプランの ID を指定しても消えない
+	if err := b.Plans.DeleteRateRule(ctx, 100, other.ID); err != nil {
+		t.Fatalf("DeleteRateRule(other plan): %v", err)
+	}
+	if list, _ := b.Plans.RateRules(ctx, 200); len(list) != 1 {
+		t.Fatalf("RateRules(200) = %+v; rule of another plan was deleted", list)
+	}
+	if err := b.Plans.DeleteRateRule(ctx, 100, season.ID); err != nil {
+		t.Fatalf("DeleteRateRule: %v", err)
+	}
+	if err := b.Plans.DeleteRateRule(ctx, 100, season.ID); err != nil {
+		t.Fatalf("DeleteRateRule(missing): %v", err)
+	}
+	if list, _ := b.Plans.RateRules(ctx, 100); len(list) != 1 || list[0].ID != weekend.ID {
+		t.Fatalf("RateRules after delete = %+v", list)
+	}
+}
+
+func testReservations(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	if r, err := b.Reservations.FindByID(ctx, 12345); err != nil || r != nil {
+		t.Fatalf("FindByID(missing) = %+v, %v; want nil, nil", r, err)
+	}
+
+	first, err := b.Reservations.Save(ctx, newReservation(100, day(1), day(3)))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	second, err := b.Reservations.Save(ctx, newReservation(175, day(2), day(3)))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if first.ID == 0 || second.ID <= first.ID {
+		t.Fatalf("Save assigned ids %d, %d; want increasing non-zero ids", first.ID, second.ID)
+	}
+	if first.Version != 1 {
+		t.Fatalf("Save(new) version = %d, want 1", first.Version)
+	}
+
+	got, err := b.Reservations.FindByID(ctx, first.ID)
+	if err != nil || got == nil {
+		t.Fatalf("FindByID(%d) = %+v, %v", first.ID, got, err)
+	}
+	assertReservation(t, got, first)
+
+	// 既存の予約を上書き保存
+	got.Status = entity.ReservationCancelled
+	got.Checkout = day(4)
+	got.Total = entity.Yen(36900)
+	got.Guests = entity.Guests{Adults: 1, Children: 1}
+	// 内訳は丸ごと置き換わる
+	got.Breakdown = breakdown(day(1), 12000, 12000, 12000)
+	got.Breakdown[0].ChildRate, got.Breakdown[0].InfantRate = entity.Yen(8400), entity.Yen(6000)
+	got.CouponID, got.CouponCode, got.Discount = 7, "WELCOME", entity.Yen(3000)
+	got.Subtotal, got.ConsumptionTax, got.AccommodationTax = entity.Yen(33000), entity.Yen(3300), entity.Yen(600)
+	// 予約時点のポリシーの写しとキャンセル料
+	got.CancellationPolicy = &entity.CancellationPolicy{ID: 4, Name: "標準", Rules: []entity.CancellationRule{{DaysBefore: 3, FeePercent: 50}, {DaysBefore: 0, FeePercent: 100}}}
+	got.CancellationFee = entity.Yen(18450)
+	stale := *got
+	if _, err := b.Reservations.Save(ctx, got); err != nil {
+		t.Fatalf("Save(update): %v", err)
+	}
+	if got.Version != 2 {
+		t.Fatalf("Save(update) set version %d, want 2", got.Version)
+	}
+	updated, err := b.Reservations.FindByID(ctx, first.ID)
+	if err != nil || updated == nil {
+		t.Fatalf("FindByID after update = %+v, %v", updated, err)
+	}
+	assertReservation(t, updated, got)
+
+	// 読み込んだ後に更新された予約・存在しない予約は上書きしない
+	stale.Status = entity.ReservationConfirmed
+	if _, err := b.Reservations.Save(ctx, &stale); !errors.Is(err, repository.ErrConcurrentModification) {
+		t.Fatalf("Save(stale version) = %v, want ErrConcurrentModification", err)
+	}
+	missing := newReservation(100, day(1), day(2))
+	missing.ID, missing.Version = 12345, 1
+	if _, err := b.Reservations.Save(ctx, missing); !errors.Is(err, repository.ErrConcurrentModification) {
+		t.Fatalf("Save(missing) = %v, want ErrConcurrentModification", err)
+	}
+	if r, _ := b.Reservations.FindByID(ctx, first.ID); r == nil || r.Status != entity.ReservationCancelled || r.Version != 2 {
+		t.Fatalf("stale Save overwrote the reservation: %+v", r)
+	}
+
+	list, err := b.Reservations.List(ctx, repository.ReservationQuery{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+		t.Fatalf("List = %v, want [%d %d] (id asc)", reservationIDs(list), first.ID, second.ID)
+	}
+	// 一覧も内訳・キャンセルポリシーの写しまで返す
+	assertReservation(t, list[0], got)
+	assertReservation(t, list[1], second)
+
+	// 内訳を変えない保存では内訳はそのまま、空にすると消える
+	got.Status = entity.ReservationConfirmed
+	if _, err := b.Reservations.Save(ctx, got); err != nil {
+		t.Fatalf("Save(status only): %v", err)
+	}
+	if r, _ := b.Reservations.FindByID(ctx, first.ID); r == nil {
+		t.Fatal("FindByID after status-only Save = nil")
+	} else {
+		assertReservation(t, r, got)
+	}
+	got.Breakdown = nil
+	if _, err := b.Reservations.Save(ctx, got); err != nil {
+		t.Fatalf("Save(no breakdown): %v", err)
+	}
+	if r, _ := b.Reservations.FindByID(ctx, first.ID); r == nil || len(r.Breakdown) != 0 {
+		t.Fatalf("Save(no breakdown) kept %+v", r)
+	}
+}
+
+func testReservationQuery(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	const alice, bob = "6f1c1f8e-9f6b-4a51-9f0a-0c7c2a1e5b11", "0b7e3f4a-5c1d-4e8f-9a2b-3c4d5e6f7a8b"
+	// id 昇順に作成。checkin は id の順と一致しないようにする
+	seed := []struct {
+		user    string
+		plan    int
+		checkin int
+		status  entity.ReservationStatus
+	}{
+		{alice, 100, 5, entity.ReservationConfirmed},
+		{bob, 100, 1, entity.ReservationConfirmed},
+		{alice, 175, 3, entity.ReservationCancelled},
+		{alice, 100, 3, entity.ReservationConfirmed},
+		{bob, 200, 7, entity.ReservationConfirmed},
+	}
+	ids := make([]int, 0, len(seed))
+	for _, r := range seed {
+		res := newReservation(r.plan, day(r.checkin), day(r.checkin+1))
+		res.UserID = r.user
+		res.Status = r.status
+		saved, err := b.Reservations.Save(ctx, res)
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		ids = append(ids, saved.ID)
+	}
+	from, to := day(3), day(5)
+	cases := []struct {
+		name string
+		q    repository.ReservationQuery
+		want []int // seed の添字
+	}{
+		{"all", repository.ReservationQuery{}, []int{0, 1, 2, 3, 4}},
+		{"user", repository.ReservationQuery{UserID: alice}, []int{0, 2, 3}},
+		{"plan", repository.ReservationQuery{PlanID: 100}, []int{0, 1, 3}},
+		{"status", repository.ReservationQuery{Status: entity.ReservationCancelled}, []int{2}},
+		{"checkin range (inclusive)", repository.ReservationQuery{CheckinFrom: &from, CheckinTo: &to}, []int{0, 2, 3}},
+		{"combined", repository.ReservationQuery{UserID: alice, PlanID: 100, CheckinTo: &to}, []int{0, 3}},
+		{"id desc", repository.ReservationQuery{Sort: repository.SortByIDDesc}, []int{4, 3, 2, 1, 0}},
+		{"checkin asc", repository.ReservationQuery{Sort: repository.SortByCheckinAsc}, []int{1, 2, 3, 0, 4}},
+		{"checkin desc", repository.ReservationQuery{Sort: repository.SortByCheckinDesc}, []int{4, 0, 3, 2, 1}},
+		{"limit", repository.ReservationQuery{Limit: 2}, []int{0, 1}},
+	}
+	for _, c := range cases {
+		list, err := b.Reservations.List(ctx, c.q)
+		if err != nil {
+			t.Fatalf("List(%s): %v", c.name, err)
+		}
+		want := make([]int, 0, len(c.want))
+		for _, i := range c.want {
+			want = append(want, ids[i])
+		}
+		if got := reservationIDs(list); !equalInts(got, want) {
+			t.Errorf("List(%s) = %v, want %v", c.name, got, want)
+		}
+	}
+
+	// どの並び順でも、カーソルで 2 件ずつたどると一度に取った結果と同じになる
+	for _, sort := range []repository.ReservationSort{repository.SortByIDAsc, repository.SortByIDDesc, repository.SortByCheckinAsc, repository.SortByCheckinDesc} {
+		all, err := b.Reservations.List(ctx, repository.ReservationQuery{Sort: sort})
+		if err != nil {
+			t.Fatalf("List(%s): %v", sort, err)
+		}
+		var paged []*entity.Reservation
+		q := repository.ReservationQuery{Sort: sort, Limit: 2}
+		for i := 0; i < 10; i++ {
+			page, err := b.Reservations.List(ctx, q)
+			if err != nil {
+				t.Fatalf("List(%s, page %d): %v", sort, i, err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			paged = append(paged, page...)
+			last := page[len(page)-1]
+			q.After = &repository.ReservationCursor{ID: last.ID, Checkin: last.Checkin}
+		}
+		if got, want := reservationIDs(paged), reservationIDs(all); !equalInts(got, want) {
+			t.Errorf("paged List(%s) = %v, want %v", sort, got, want)
+		}
+	}
+}
+
+func testCoupons(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+	const alice, bob = "6f1c1f8e-9f6b-4a51-9f0a-0c7c2a1e5b11", "0b7e3f4a-5c1d-4e8f-9a2b-3c4d5e6f7a8b"
+
+	if c, err := b.Coupons.FindByCode(ctx, "MISSING"); err != nil || c != nil {
+		t.Fatalf("FindByCode(missing) = %+v, %v; want nil, nil", c, err)
+	}
+	starts, ends := day(0), day(30)
+	percent, err := b.Coupons.Save(ctx, &entity.Coupon{
+		Code: "SUMMER10", Name: "夏のキャンペーン", Discount: entity.CouponPercent, Percent: 10,
+		StartsAt: &starts, EndsAt: &ends, PlanIDs: []int{100, 200}, MaxRedemptions: 2, PerUserLimit: 1,
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	amount, err := b.Coupons.Save(ctx, &entity.Coupon{
+		Code: "WELCOME", Name: "初回", Discount: entity.CouponAmount, Amount: entity.Yen(3000),
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if percent.ID == 0 || amount.ID <= percent.ID {
+		t.Fatalf("Save assigned ids %d, %d; want increasing non-zero ids", percent.ID, amount.ID)
+	}
+	if _, err := b.Coupons.Save(ctx, &entity.Coupon{Code: "WELCOME", Name: "dup", Discount: entity.CouponPercent, Percent: 5}); !errors.Is(err, repository.ErrDuplicateCouponCode) {
+		t.Fatalf("Save(duplicate code) = %v, want ErrDuplicateCouponCode", err)
+	}
+
+	got, err := b.Coupons.FindByCode(ctx, "SUMMER10")
+	if err != nil || got == nil {
+		t.Fatalf("FindByCode = %+v, %v", got, err)
+	}
+	if got.ID != percent.ID || got.Name != "夏のキャンペーン" || got.Discount != entity.CouponPercent || got.Percent != 10 ||
+		got.StartsAt == nil || !got.StartsAt.Equal(starts) || got.EndsAt == nil || !got.EndsAt.Equal(ends) ||
+		!equalInts(got.PlanIDs, []int{100, 200}) || got.MaxRedemptions != 2 || got.PerUserLimit != 1 {
+		t.Fatalf("FindByCode = %+v, want %+v", got, percent)
+	}
+	if got, err := b.Coupons.FindByID(ctx, amount.ID); err != nil || got == nil ||
+		got.Amount != entity.Yen(3000) || got.StartsAt != nil || len(got.PlanIDs) != 0 {
+		t.Fatalf("FindByID = %+v, %v", got, err)
+	}
+	list, err := b.Coupons.List(ctx)
+	if err != nil || len(list) != 2 || list[0].ID != percent.ID || list[1].ID != amount.ID {
+		t.Fatalf("List = %+v, %v; want [%d %d]", list, err, percent.ID, amount.ID)
+	}
+
+	t.Run("redeem within limits", func(t *testing.T) {
+		if err := b.Coupons.Redeem(ctx, percent.ID, alice, 1); err != nil {
+			t.Fatalf("Redeem: %v", err)
+		}
+		if err := b.Coupons.Redeem(ctx, percent.ID, alice, 2); !errors.Is(err, repository.ErrCouponUserLimit) {
+			t.Fatalf("Redeem(per user limit) = %v, want ErrCouponUserLimit", err)
+		}
+		if err := b.Coupons.Redeem(ctx, percent.ID, bob, 3); err != nil {
+			t.Fatalf("Redeem: %v", err)
+		}
+		if err := b.Coupons.Redeem(ctx, percent.ID, "1d2c3b4a-0000-4000-8000-000000000000", 4); !errors.Is(err, repository.ErrCouponExhausted) {
+			t.Fatalf("Redeem(max redemptions) = %v, want ErrCouponExhausted", err)
+		}
+		assertRedemptions(t, b, percent.ID, 2)
+	})
+
+	t.Run("release frees a slot", func(t *testing.T) {
+		if err := b.Coupons.Release(ctx, 1); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+		// 利用の無い予約は何もしない
+		if err := b.Coupons.Release(ctx, 999); err != nil {
+			t.Fatalf("Release(unknown) = %v, want nil", err)
+		}
+		assertRedemptions(t, b, percent.ID, 1)
+		if err := b.Coupons.Redeem(ctx, percent.ID, alice, 5); err != nil {
+			t.Fatalf("Redeem after release: %v", err)
+		}
+		assertRedemptions(t, b, percent.ID, 2)
+	})
+
+	t.Run("update keeps redemptions", func(t *testing.T) {
+		c, _ := b.Coupons.FindByID(ctx, percent.ID)
+		c.Name, c.Percent, c.PlanIDs, c.EndsAt, c.Redemptions = "延長", 15, nil, nil, 0
+		saved, err := b.Coupons.Save(ctx, c)
+		if err != nil {
+			t.Fatalf("Save(update): %v", err)
+		}
+		if saved.Redemptions != 2 {
+			t.Errorf("Save(update).Redemptions = %d, want 2", saved.Redemptions)
+		}
+		got, _ := b.Coupons.FindByID(ctx, percent.ID)
+		if got.Name != "延長" || got.Percent != 15 || len(got.PlanIDs) != 0 || got.EndsAt != nil || got.Redemptions != 2 {
+			t.Fatalf("FindByID after update = %+v", got)
+		}
+	})
+
+	t.Run("rolled back redemption is not counted", func(t *testing.T) {
+		if b.Tx == nil {
+			t.Skip("backend has no UnitOfWork")
+		}
+		boom := errors.New("boom")
+		err := b.Tx.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			if err := repos.Coupons.Redeem(ctx, amount.ID, alice, 6); err != nil {
+				return err
+			}
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("Do = %v, want the error returned by fn", err)
+		}
+		assertRedemptions(t, b, amount.ID, 0)
+	})
+}
+
+func assertRedemptions(t *testing.T, b Backend, couponID, want int) {
+	t.Helper()
+	c, err := b.Coupons.FindByID(context.Background(), couponID)
+	if err != nil || c == nil {
+		t.Fatalf("FindByID(%d) = %+v, %v", couponID, c, err)
+	}
+	if c.Redemptions != want {
+		t.Fatalf("redemptions = %d, want %d", c.Redemptions, want)
+	}
+}
+
+func testPayments(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	if p, err := b.Payments.FindByReservation(ctx, 1); err != nil || p != nil {
+		t.Fatalf("FindByReservation(missing) = %+v, %v; want nil, nil", p, err)
+	}
+	pay, err := b.Payments.Save(ctx, &entity.Payment{
+		ReservationID: 1, Amount: entity.Yen(24000), Captured: entity.Yen(0), Refunded: entity.Yen(0),
+		Status: entity.PaymentPending,
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if pay.ID == 0 || pay.Version != 1 {
+		t.Fatalf("Save = %+v, want an id and version 1", pay)
+	}
+	got, err := b.Payments.FindByReservation(ctx, 1)
+	if err != nil || got == nil || *got != *pay {
+		t.Fatalf("FindByReservation = %+v, %v; want %+v", got, err, pay)
+	}
+
+	got.Status, got.GatewayRef, got.Captured = entity.PaymentCaptured, "ref_1", entity.Yen(24000)
+	updated, err := b.Payments.Save(ctx, got)
+	if err != nil {
+		t.Fatalf("Save(update): %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("version after update = %d, want 2", updated.Version)
+	}
+	if again, err := b.Payments.FindByReservation(ctx, 1); err != nil || again == nil || *again != *updated {
+		t.Fatalf("FindByReservation after update = %+v, %v; want %+v", again, err, updated)
+	}
+	// 読み込んだ後に他で保存された決済は上書きしない
+	got.Status = entity.PaymentVoided
+	if _, err := b.Payments.Save(ctx, got); !errors.Is(err, repository.ErrConcurrentModification) {
+		t.Fatalf("Save(stale version) = %v, want ErrConcurrentModification", err)
+	}
+	if again, _ := b.Payments.FindByReservation(ctx, 1); again == nil || *again != *updated {
+		t.Fatalf("stale save changed the payment: %+v", again)
+	}
+
+	t.Run("rolled back payment is not saved", func(t *testing.T) {
+		if b.Tx == nil {
+			t.Skip("backend has no UnitOfWork")
+		}
+		boom := errors.New("boom")
+		err := b.Tx.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			if _, err := repos.Payments.Save(ctx, &entity.Payment{
+				ReservationID: 2, Amount: entity.Yen(8000), Captured: entity.Yen(0), Refunded: entity.Yen(0),
+				Status: entity.PaymentPending,
+			}); err != nil {
+				return err
+			}
+			p, _ := repos.Payments.FindByReservation(ctx, 1)
+			p.Status = entity.PaymentRefunded
+			if _, err := repos.Payments.Save(ctx, p); err != nil {
+				return err
+			}
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("Do = %v, want the error returned by fn", err)
+		}
+		if p, err := b.Payments.FindByReservation(ctx, 2); err != nil || p != nil {
+			t.Fatalf("FindByReservation(rolled back) = %+v, %v; want nil, nil", p, err)
+		}
+		if p, _ := b.Payments.FindByReservation(ctx, 1); p == nil || p.Status != entity.PaymentCaptured {
+			t.Fatalf("rolled back update is visible: %+v", p)
+		}
+	})
+}
+
+func testCancellationPolicies(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+	if b.CancellationPolicies == nil {
+		t.Skip("backend has no CancellationPolicyRepository")
+	}
+
+	if p, err := b.CancellationPolicies.FindByID(ctx, 1); err != nil || p != nil {
+		t.Fatalf("FindByID(missing) = %+v, %v; want nil, nil", p, err)
+	}
+	standard, err := b.CancellationPolicies.Save(ctx, &entity.CancellationPolicy{
+		Name: "標準", Rules: []entity.CancellationRule{{DaysBefore: 7, FeePercent: 0}, {DaysBefore: 3, FeePercent: 50}, {DaysBefore: 0, FeePercent: 100}},
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	free, err := b.CancellationPolicies.Save(ctx, &entity.CancellationPolicy{Name: "無料"})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if standard.ID == 0 || free.ID <= standard.ID {
+		t.Fatalf("Save assigned ids %d, %d; want increasing non-zero ids", standard.ID, free.ID)
+	}
+	got, err := b.CancellationPolicies.FindByID(ctx, standard.ID)
+	if err != nil || !equalPolicies(got, standard) {
+		t.Fatalf("FindByID = %+v, %v; want %+v", got, err, standard)
+	}
+
+	got.Name, got.Rules = "厳しめ", []entity.CancellationRule{{DaysBefore: 14, FeePercent: 30}, {DaysBefore: 0, FeePercent: 100}}
+	if _, err := b.CancellationPolicies.Save(ctx, got); err != nil {
+		t.Fatalf("Save(update): %v", err)
+	}
+	list, err := b.CancellationPolicies.List(ctx)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %+v, %v; want 2 policies", list, err)
+	}
+	if !equalPolicies(list[0], got) || !equalPolicies(list[1], free) {
+		t.Fatalf("List = [%+v %+v], want [%+v %+v] (id asc)", list[0], list[1], got, free)
+	}
+}
+
+// ルールは空と nil を区別しない
+func equalPolicies(a, b *entity.CancellationPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID && a.Name == b.Name && slices.Equal(a.Rules, b.Rules)
+}
+
+func testIdempotency(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+	if b.Idempotency == nil {
+		t.Skip("backend has no IdempotencyRepository")
+	}
+	now := time.Now().Truncate(time.Second)
+	newRecord := func(userID, key string, expiresAt time.Time) *entity.IdempotencyRecord {
+		return &entity.IdempotencyRecord{
+			ID: uuid.NewString(), UserID: userID, Key: key, RequestHash: strings.Repeat("a", 64),
+			CreatedAt: now, ExpiresAt: expiresAt,
+		}
+	}
+	userA, userB := uuid.NewString(), uuid.NewString()
+
+	rec := newRecord(userA, "key-1", now.Add(time.Hour))
+	if existing, err := b.Idempotency.Create(ctx, rec); err != nil || existing != nil {
+		t.Fatalf("Create = %+v, %v; want nil, nil", existing, err)
+	}
+	// キーはユーザーごとに別
+	if existing, err := b.Idempotency.Create(ctx, newRecord(userB, "key-1", now.Add(time.Hour))); err != nil || existing != nil {
+		t.Fatalf("Create(other user) = %+v, %v; want nil, nil", existing, err)
+	}
+	existing, err := b.Idempotency.Create(ctx, newRecord(userA, "key-1", now.Add(time.Hour)))
+	if err != nil || existing == nil || existing.ID != rec.ID || existing.Completed() {
+		t.Fatalf("Create(duplicate) = %+v, %v; want in-progress record %s", existing, err, rec.ID)
+	}
+
+	body := []byte(`{"id":1}`)
+	if err := b.Idempotency.Complete(ctx, rec.ID, 201, "application/json", body); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	existing, err = b.Idempotency.Create(ctx, newRecord(userA, "key-1", now.Add(time.Hour)))
+	if err != nil || existing == nil {
+		t.Fatalf("Create(duplicate) = %+v, %v; want completed record", existing, err)
+	}
+	if existing.StatusCode != 201 || existing.ContentType != "application/json" || !bytes.Equal(existing.Body, body) ||
+		existing.RequestHash != rec.RequestHash || !existing.ExpiresAt.Equal(rec.ExpiresAt) {
+		t.Fatalf("stored record = %+v, want %+v with response", existing, rec)
+	}
+
+	// 別の ID の削除では消えない
+	if err := b.Idempotency.Delete(ctx, uuid.NewString()); err != nil {
+		t.Fatalf("Delete(missing): %v", err)
+	}
+	if existing, _ := b.Idempotency.Create(ctx, newRecord(userA, "key-1", now.Add(time.Hour))); existing == nil {
+		t.Fatalf("record was deleted by another id")
+	}
+	if err := b.Idempotency.Delete(ctx, rec.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if existing, err := b.Idempotency.Create(ctx, newRecord(userA, "key-1", now.Add(time.Hour))); err != nil || existing != nil {
+		t.Fatalf("Create(after delete) = %+v, %v; want nil, nil", existing, err)
+	}
+
+	t.Run("delete expired", func(t *testing.T) {
+		if _, err := b.Idempotency.Create(ctx, newRecord(userA, "old", now.Add(-time.Minute))); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		n, err := b.Idempotency.DeleteExpired(ctx, now)
+		if err != nil || n != 1 {
+			t.Fatalf("DeleteExpired = %d, %v; want 1", n, err)
+		}
+		if existing, _ := b.Idempotency.Create(ctx, newRecord(userA, "old", now.Add(time.Hour))); existing != nil {
+			t.Fatalf("expired record was not deleted: %+v", existing)
+		}
+		if existing, _ := b.Idempotency.Create(ctx, newRecord(userB, "key-1", now.Add(time.Hour))); existing == nil {
+			t.Fatalf("unexpired record was deleted")
+		}
+	})
+
+	t.Run("concurrent creates keep one record", func(t *testing.T) {
+		const workers = 8
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		created := 0
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				existing, err := b.Idempotency.Create(ctx, newRecord(userA, "race", now.Add(time.Hour)))
+				if err != nil {
+					t.Errorf("Create: %v", err)
+					return
+				}
+				if existing == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if created != 1 {
+			t.Fatalf("%d creates succeeded, want 1", created)
+		}
+	})
+}
+
+func testUsers(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	u := &entity.User{
+		Name:         "山田太郎",
+		Email:        "taro@example.com",
+		PhoneNumber:  "090-0000-0000",
+		DateOfBirth:  time.Date(1990, 4, 1, 0, 0, 0, 0, time.UTC),
+		RegisteredAt: time.Now().Truncate(time.Second),
+		Status:       "active",
+	}
+	created, err := b.Users.Create(ctx, u)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID == "" {
+		t.Fatal("Create did not assign an id")
+	}
+
+	got, err := b.Users.Get(ctx, created.ID)
+	if err != nil || got == nil {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if got.Name != u.Name || got.Email != u.Email || got.Status != u.Status ||
+		got.DateOfBirth.Format("2006-01-02") != "1990-04-01" {
+		t.Errorf("Get = %+v, want %+v", got, u)
+	}
+	// ロールを指定しなければゲスト
+	if !slices.Equal(got.Roles, []entity.Role{entity.RoleGuest}) {
+		t.Errorf("Get().Roles = %v, want [guest]", got.Roles)
+	}
+	if created.Version != 1 || got.Version != 1 {
+		t.Errorf("Version after create = %d (returned), %d (stored); want 1", created.Version, got.Version)
+	}
+
+	// 付与・剥奪はどちらも冪等で、ロールは名前順に返る
+	for i := 0; i < 2; i++ {
+		if err := b.Users.GrantRole(ctx, created.ID, entity.RoleStaff, 0); err != nil {
+			t.Fatalf("GrantRole: %v", err)
+		}
+	}
+	if err := b.Users.GrantRole(ctx, created.ID, entity.RoleAdmin, 0); err != nil {
+		t.Fatalf("GrantRole: %v", err)
+	}
+	got, err = b.Users.Get(ctx, created.ID)
+	if err != nil || !slices.Equal(got.Roles, []entity.Role{entity.RoleAdmin, entity.RoleGuest, entity.RoleStaff}) {
+		t.Errorf("Roles after grant = %v, %v; want [admin guest staff]", got.Roles, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := b.Users.RevokeRole(ctx, created.ID, entity.RoleStaff, 0); err != nil {
+			t.Fatalf("RevokeRole: %v", err)
+		}
+	}
+	got, err = b.Users.FindByEmail(ctx, u.Email)
+	if err != nil || !slices.Equal(got.Roles, []entity.Role{entity.RoleAdmin, entity.RoleGuest}) {
+		t.Errorf("Roles after revoke = %v, %v; want [admin guest]", got.Roles, err)
+	}
+	// 付与・剥奪のたびにバージョンが上がり、古いバージョンを指定すると何も変えない
+	if got.Version != 6 {
+		t.Errorf("Version after 5 role changes = %d, want 6", got.Version)
+	}
+	if err := b.Users.GrantRole(ctx, created.ID, entity.RoleStaff, got.Version-1); !errors.Is(err, repository.ErrConcurrentModification) {
+		t.Errorf("GrantRole(stale version) = %v, want ErrConcurrentModification", err)
+	}
+	if err := b.Users.RevokeRole(ctx, created.ID, entity.RoleAdmin, got.Version-1); !errors.Is(err, repository.ErrConcurrentModification) {
+		t.Errorf("RevokeRole(stale version) = %v, want ErrConcurrentModification", err)
+	}
+	if err := b.Users.GrantRole(ctx, created.ID, entity.RoleStaff, got.Version); err != nil {
+		t.Fatalf("GrantRole(current version): %v", err)
+	}
+	got, err = b.Users.Get(ctx, created.ID)
+	if err != nil || got.Version != 7 || !slices.Equal(got.Roles, []entity.Role{entity.RoleAdmin, entity.RoleGuest, entity.RoleStaff}) {
+		t.Errorf("after versioned grant = %+v, %v; want version 7 and [admin guest staff]", got, err)
+	}
+
+	byEmail, err := b.Users.FindByEmail(ctx, "taro@example.com")
+	if err != nil || byEmail == nil || byEmail.ID != created.ID {
+		t.Fatalf("FindByEmail = %+v, %v", byEmail, err)
+	}
+	if u, err := b.Users.FindByEmail(ctx, "nobody@example.com"); err != nil || u != nil {
+		t.Errorf("FindByEmail(missing) = %+v, %v; want nil, nil", u, err)
+	}
+	if u, err := b.Users.FindByEmail(ctx, " "); err != nil || u != nil {
+		t.Errorf("FindByEmail(blank) = %+v, %v; want nil, nil", u, err)
+	}
+	if u, err := b.Users.Get(ctx, "00000000-0000-0000-0000-000000000000"); err != nil || u != nil {
+		t.Errorf("Get(missing) = %+v, %v; want nil, nil", u, err)
+	}
+	if _, err := b.Users.Get(ctx, ""); err == nil {
+		t.Error("Get(\"\") should fail")
+	}
+
+	dup := &entity.User{Name: "別人", Email: "taro@example.com", RegisteredAt: time.Now(), Status: "active"}
+	if _, err := b.Users.Create(ctx, dup); !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Errorf("Create(duplicate email) = %v, want ErrDuplicateEmail", err)
+	}
+}
+
+func testRoles(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	for role, want := range entity.DefaultRolePermissions {
+		got, err := b.Roles.Permissions(ctx, role)
+		if err != nil {
+			t.Fatalf("Permissions(%s): %v", role, err)
+		}
+		if len(got) != len(want) {
+			t.Errorf("Permissions(%s) = %v, want %v", role, got, want)
+		}
+		for _, p := range want {
+			if !slices.Contains(got, p) {
+				t.Errorf("Permissions(%s) = %v, missing %s", role, got, p)
+			}
+		}
+	}
+	if _, err := b.Roles.Permissions(ctx, "owner"); !errors.Is(err, repository.ErrRoleNotFound) {
+		t.Errorf("Permissions(undefined) = %v, want ErrRoleNotFound", err)
+	}
+}
+
+func testSessions(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+
+	now := time.Now().Truncate(time.Second)
+	s := &entity.Session{
+		ID:               "0b7e3f4a-5c1d-4e8f-9a2b-3c4d5e6f7a8b",
+		UserID:           "6f1c1f8e-9f6b-4a51-9f0a-0c7c2a1e5b11",
+		RefreshTokenHash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		ExpiresAt:        now.Add(time.Hour),
+		CreatedAt:        now,
+	}
+	if err := b.Sessions.Create(ctx, s); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := b.Sessions.Get(ctx, s.ID)
+	if err != nil || got == nil || got.UserID != s.UserID || !got.Active(now) {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	byHash, err := b.Sessions.FindByRefreshTokenHash(ctx, s.RefreshTokenHash)
+	if err != nil || byHash == nil || byHash.ID != s.ID {
+		t.Fatalf("FindByRefreshTokenHash = %+v, %v", byHash, err)
+	}
+	if got, err := b.Sessions.FindByRefreshTokenHash(ctx, "unknown"); err != nil || got != nil {
+		t.Errorf("FindByRefreshTokenHash(missing) = %+v, %v; want nil, nil", got, err)
+	}
+
+	if revoked, err := b.Sessions.Revoke(ctx, s.ID, now); err != nil || !revoked {
+		t.Fatalf("Revoke = %v, %v; want true", revoked, err)
+	}
+	// 2回目の失効では何もしない（失効日時も変わらない）
+	if revoked, err := b.Sessions.Revoke(ctx, s.ID, now.Add(time.Minute)); err != nil || revoked {
+		t.Fatalf("Revoke twice = %v, %v; want false", revoked, err)
+	}
+	if revoked, err := b.Sessions.Revoke(ctx, "00000000-0000-0000-0000-000000000000", now); err != nil || revoked {
+		t.Errorf("Revoke(missing) = %v, %v; want false", revoked, err)
+	}
+	got, err = b.Sessions.Get(ctx, s.ID)
+	if err != nil || got == nil || got.RevokedAt == nil || !got.RevokedAt.Equal(now) || got.Active(now) {
+		t.Fatalf("Get after revoke = %+v, %v", got, err)
+	}
+
+	t.Run("concurrent revokes succeed once", func(t *testing.T) {
+		s2 := *s
+		s2.ID, s2.RefreshTokenHash = "1c8e4f5b-6d2e-4f90-8b3c-4d5e6f7a8b9c", "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+		if err := b.Sessions.Create(ctx, &s2); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		const n = 8
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		succeeded := 0
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				revoked, err := b.Sessions.Revoke(ctx, s2.ID, now)
+				if err != nil {
+					t.Errorf("Revoke: %v", err)
+				}
+				if revoked {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if succeeded != 1 {
+			t.Fatalf("%d of %d concurrent revokes succeeded, want 1", succeeded, n)
+		}
+	})
+}
+
+func testUnitOfWork(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	b := newBackend(t, SeedPlans)
+	if b.Tx == nil {
+		t.Skip("backend has no UnitOfWork")
+	}
+
+	boom := errors.New("boom")
+	err := b.Tx.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := repos.Plans.ReserveNights(ctx, 100, day(1), day(2)); err != nil {
+			return err
+		}
+		if _, err := repos.Reservations.Save(ctx, newReservation(100, day(1), day(2))); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Do = %v, want the error returned by fn", err)
+	}
+	if list, _ := b.Reservations.List(ctx, repository.ReservationQuery{}); len(list) != 0 {
+		t.Errorf("reservation saved in rolled back tx is visible: %v", reservationIDs(list))
+	}
+	assertRemaining(t, b, 100, day(1), day(2), []int{2})
+
+	err = b.Tx.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := repos.Plans.ReserveNights(ctx, 100, day(1), day(2)); err != nil {
+			return err
+		}
+		_, err := repos.Reservations.Save(ctx, newReservation(100, day(1), day(2)))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if list, _ := b.Reservations.List(ctx, repository.ReservationQuery{}); len(list) != 1 {
+		t.Errorf("committed reservation count = %d, want 1", len(list))
+	}
+	assertRemaining(t, b, 100, day(1), day(2), []int{1})
+
+	// ロールバックで取り消すのは fn 内の書き込みだけ。同じ時期に UnitOfWork の外で行われた書き込みは残る
+	var outside *entity.User
+	err = b.Tx.Do(ctx, func(txCtx context.Context, repos repository.Repositories) error {
+		if err := repos.Plans.ReserveNights(txCtx, 200, day(1), day(2)); err != nil {
+			return err
+		}
+		if _, err := repos.Reservations.Save(txCtx, newReservation(200, day(1), day(2))); err != nil {
+			return err
+		}
+		var err error
+		outside, err = b.Users.Create(ctx, &entity.User{Name: "外", Email: "outside@example.com", RegisteredAt: time.Now(), Status: "active"})
+		if err != nil {
+			return err
+		}
+		plan, err := b.Plans.FindByID(ctx, 175)
+		if err != nil {
+			return err
+		}
+		plan.Name = "South Basic 2"
+		if _, err := b.Plans.Save(ctx, plan); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Do = %v, want the error returned by fn", err)
+	}
+	if list, _ := b.Reservations.List(ctx, repository.ReservationQuery{}); len(list) != 1 {
+		t.Errorf("reservation count after rollback = %d, want 1", len(list))
+	}
+	assertRemaining(t, b, 200, day(1), day(2), []int{3})
+	if u, err := b.Users.Get(ctx, outside.ID); err != nil || u == nil {
+		t.Errorf("user created outside the tx = %+v, %v; want it kept", u, err)
+	}
+	if p, err := b.Plans.FindByID(ctx, 175); err != nil || p.Name != "South Basic 2" {
+		t.Errorf("plan saved outside the tx = %+v, %v; want it kept", p, err)
+	}
+}
+
+// 基準日（2030-01-01）から n 日後
+func day(n int) time.Time {
+	return time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func newReservation(planID int, checkin, checkout time.Time) *entity.Reservation {
+	r := &entity.Reservation{
+		UserID:   "6f1c1f8e-9f6b-4a51-9f0a-0c7c2a1e5b11",
+		PlanID:   planID,
+		Number:   2,
+		Guests:   entity.Guests{Adults: 2},
+		Checkin:  checkin,
+		Checkout: checkout,
+		Status:   entity.ReservationConfirmed,
+	}
+	r.Total = entity.Yen(int64(10000 * r.Number * r.Nights()))
+	r.Subtotal, r.ConsumptionTax, r.AccommodationTax = r.Total, entity.Yen(0), entity.Yen(0)
+	r.CancellationFee = entity.Yen(0)
+	rates := make([]int64, r.Nights())
+	for i := range rates {
+		rates[i] = 10000
+	}
+	r.Breakdown = breakdown(checkin, rates...)
+	return r
+}
+
+// checkin から 1 泊ずつ、1人あたり rates の内訳（2名分）
+func breakdown(checkin time.Time, rates ...int64) []entity.NightCharge {
+	out := make([]entity.NightCharge, 0, len(rates))
+	for i, rate := range rates {
+		out = append(out, entity.NightCharge{
+			Date: checkin.AddDate(0, 0, i), Rate: entity.Yen(rate), ChildRate: entity.Yen(0), InfantRate: entity.Yen(0), Amount: entity.Yen(rate * 2),
+		})
+	}
+	return out
+}
+
+func assertRemaining(t *testing.T, b Backend, planID int, from, to time.Time, want []int) {
+	t.Helper()
+	nights, err := b.Plans.Availability(context.Background(), planID, from, to)
+	if err != nil {
+		t.Fatalf("Availability: %v", err)
+	}
+	got := make([]int, 0, len(nights))
+	for i, n := range nights {
+		if !n.Date.Equal(from.AddDate(0, 0, i)) {
+			t.Fatalf("Availability[%d].Date = %s, want %s", i, n.Date, from.AddDate(0, 0, i))
+		}
+		got = append(got, n.Remaining)
+	}
+	if !equalInts(got, want) {
+		t.Fatalf("remaining = %v, want %v", got, want)
+	}
+}
+
+// 日付カラムはタイムゾーンが実装によって異なるので日付だけで比較する
+func assertReservation(t *testing.T, got, want *entity.Reservation) {
+	t.Helper()
+	if got.ID != want.ID || got.UserID != want.UserID || got.PlanID != want.PlanID ||
+		got.Number != want.Number || got.Guests != want.Guests || got.Total != want.Total || got.Status != want.Status ||
+		got.CouponID != want.CouponID || got.CouponCode != want.CouponCode || got.Discount != want.Discount ||
+		got.Subtotal != want.Subtotal || got.ConsumptionTax != want.ConsumptionTax || got.AccommodationTax != want.AccommodationTax ||
+		got.CancellationFee != want.CancellationFee || !equalPolicies(got.CancellationPolicy, want.CancellationPolicy) || got.Version != want.Version ||
+		!entity.DateOf(got.Checkin).Equal(entity.DateOf(want.Checkin)) ||
+		!entity.DateOf(got.Checkout).Equal(entity.DateOf(want.Checkout)) {
+		t.Fatalf("reservation = %+v, want %+v", got, want)
+	}
+	if len(got.Breakdown) != len(want.Breakdown) {
+		t.Fatalf("breakdown = %+v, want %+v", got.Breakdown, want.Breakdown)
+	}
+	for i, n := range got.Breakdown {
+		w := want.Breakdown[i]
+		if !entity.DateOf(n.Date).Equal(entity.DateOf(w.Date)) || n.Rate != w.Rate ||
+			n.ChildRate != w.ChildRate || n.InfantRate != w.InfantRate || n.Amount != w.Amount {
+			t.Fatalf("breakdown[%d] = %+v, want %+v", i, n, w)
+		}
+	}
+}
+
+func planIDs(ps []*entity.Plan) []int {
+	out := make([]int, 0, len(ps))
+	for _, p := range ps {
+		out = append(out, p.ID)
+	}
+	return out
+}
+
+func reservationIDs(rs []*entity.Reservation) []int {
+	out := make([]int, 0, len(rs))
+	for _, r := range rs {
+		out = append(out, r.ID)
+	}
+	return out
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"bookingapp/internal/domain/entity"
+	"time"
+)
+
+// 予約一覧の並び順。同じ値の予約は ID で順序を決める
+type ReservationSort string
+
+const (
+	SortByIDAsc       ReservationSort = "id"
+	SortByIDDesc      ReservationSort = "-id"
+	SortByCheckinAsc  ReservationSort = "checkin"
+	SortByCheckinDesc ReservationSort = "-checkin"
+)
+
+func (s ReservationSort) Valid() bool {
+	switch s {
+	case SortByIDAsc, SortByIDDesc, SortByCheckinAsc, SortByCheckinDesc:
+		return true
+	}
+	return false
+}
+
+// 前ページ最後の予約の位置。この位置より後ろ（Sort の順で）から返す
+type ReservationCursor struct {
+	ID      int
+	Checkin time.Time // checkin で並べる場合のみ使う
+}
+
+// 予約一覧の条件。ゼロ値の項目は絞り込まない
+type ReservationQuery struct {
+	UserID      string
+	PlanID      int
+	Status      entity.ReservationStatus
+	CheckinFrom *time.Time // この日以降にチェックイン（含む）
+	CheckinTo   *time.Time // この日までにチェックイン（含む）
+
+	Sort  ReservationSort // 空なら SortByIDAsc
+	After *ReservationCursor
+	Limit int // 0 なら全件
+}
@@ -0,0 +1,18 @@
+package repository
+
+import "context"
+
+// トランザクションに束縛されたリポジトリ一式
+type Repositories struct {
+	Plans        PlanRepository
+	Reservations ReservationRepository
+	Users        UserRepository
+	Coupons      CouponRepository
+	Payments     PaymentRepository
+}
+
+// 複数リポジトリにまたがる処理を1トランザクションで実行する。
+// fn がエラーを返すと fn 内で行った変更はすべて取り消される
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcrypt によるパスワードハッシュ
+type BcryptHasher struct {
+	Cost int // 0 なら bcrypt.DefaultCost
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	cost := h.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	b, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (BcryptHasher) Compare(hash, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
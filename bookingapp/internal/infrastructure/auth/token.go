@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"bookingapp/internal/usecase"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// HS256 の JWT としてアクセストークンを署名・検証する
+type HMACSigner struct {
+	Secret []byte
+	Now    func() time.Time
+}
+
+// JWT ヘッダは固定なので事前にエンコードしておく
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type jwtClaims struct {
+	Sub string `json:"sub"`
+	Sid string `json:"sid"`
+	Iat int64  `json:"iat"`
+	Exp int64  `json:"exp"`
+}
+
+func (s HMACSigner) Sign(c usecase.AccessClaims) (string, error) {
+	payload, err := json.Marshal(jwtClaims{
+		Sub: c.UserID,
+		Sid: c.SessionID,
+		Iat: s.now().Unix(),
+		Exp: c.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.sign(unsigned), nil
+}
+
+func (s HMACSigner) Verify(token string) (*usecase.AccessClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(parts[0]+"."+parts[1]))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var c jwtClaims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidToken
+	}
+	exp := time.Unix(c.Exp, 0)
+	if !s.now().Before(exp) {
+		return nil, ErrInvalidToken
+	}
+	return &usecase.AccessClaims{UserID: c.Sub, SessionID: c.Sid, ExpiresAt: exp}, nil
+}
+
+func (s HMACSigner) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s HMACSigner) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"bookingapp/internal/usecase"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMACSigner(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	signer := HMACSigner{Secret: []byte("secret"), Now: clock}
+	claims := usecase.AccessClaims{UserID: "u1", SessionID: "s1", ExpiresAt: now.Add(15 * time.Minute)}
+
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	got, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.UserID != claims.UserID || got.SessionID != claims.SessionID || !got.ExpiresAt.Equal(claims.ExpiresAt) {
+		t.Fatalf("Verify = %+v, want %+v", got, claims)
+	}
+
+	parts := strings.Split(token, ".")
+	forged, _ := HMACSigner{Secret: []byte("other"), Now: clock}.Sign(usecase.AccessClaims{UserID: "admin", SessionID: "s1", ExpiresAt: claims.ExpiresAt})
+	cases := map[string]string{
+		"empty":             "",
+		"two parts":         parts[0] + "." + parts[1],
+		"other header":      "eyJhbGciOiJub25lIn0." + parts[1] + "." + parts[2],
+		"tampered payload":  parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2],
+		"tampered sig":      parts[0] + "." + parts[1] + "." + parts[2][:len(parts[2])-2] + "AA",
+		"other secret":      forged,
+		"unsigned alg none": parts[0] + "." + parts[1] + ".",
+	}
+	for name, tok := range cases {
+		if _, err := signer.Verify(tok); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Verify(%s) = %v, want ErrInvalidToken", name, err)
+		}
+	}
+
+	// 有効期限ちょうどで無効になる
+	expired := HMACSigner{Secret: []byte("secret"), Now: func() time.Time { return claims.ExpiresAt }}
+	if _, err := expired.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(at expiry) = %v, want ErrInvalidToken", err)
+	}
+}
@@ -1,15 +1,314 @@
 package db
 
 import (
-	"bookingapp/internal/infrastructure/db/models"
-	"bookingapp/internal/infrastructure/db/models/user" // UserModel をインポート
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func Migrate(db any) error {
-	gdb := db.(interface{ AutoMigrate(...any) error })
-	return gdb.AutoMigrate(
-		&models.PlanModel{},
-		&models.ReservationModel{},
-		&user.UserModel{}, // UserModel を追加
-	)
+// マイグレーション SQL はバイナリに埋め込む（<version>_<name>.up.sql / .down.sql）
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+var ErrSchemaBehind = errors.New("database schema is behind; run `go run ./cmd/migrate up`")
+
+// 複数インスタンスが同時に起動してもマイグレーションを実行するのは1つだけにする
+const (
+	migrationLockName    = "bookingapp_schema_migrations"
+	migrationLockTimeout = 60 // 秒
+)
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // 未適用なら nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(sqlDB *sql.DB) (*Migrator, error) {
+	ms, err := loadMigrations(migrationFS)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: sqlDB, migrations: ms}, nil
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, "migrations/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mg
+		} else if mg.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names: %s, %s", version, mg.Name, m[2])
+		}
+		if m[3] == "up" {
+			mg.Up = string(body)
+		} else {
+			mg.Down = string(body)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if strings.TrimSpace(mg.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", mg.Version, mg.Name)
+		}
+		out = append(out, *mg)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// 未適用のマイグレーションをすべて適用する
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if _, ok := done[mg.Version]; ok {
+				continue
+			}
+			if err := execScript(ctx, conn, mg.Up); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", mg.Version, mg.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				mg.Version, mg.Name, time.Now()); err != nil {
+				return err
+			}
+			applied = append(applied, mg)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// 適用済みのマイグレーションを新しい順に steps 件戻す
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mg := m.migrations[i]
+			if _, ok := done[mg.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(mg.Down) == "" {
+				return fmt.Errorf("migration %d_%s is irreversible (no down script)", mg.Version, mg.Name)
+			}
+			if err := execScript(ctx, conn, mg.Down); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", mg.Version, mg.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"DELETE FROM schema_migrations WHERE version = ?", mg.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, mg)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := ensureMigrationTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		st := MigrationStatus{Migration: mg}
+		if at, ok := done[mg.Version]; ok {
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// 未適用のマイグレーションが残っていれば ErrSchemaBehind を返す
+func (m *Migrator) Check(ctx context.Context) error {
+	st, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, s := range st {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w (%d pending)", ErrSchemaBehind, pending)
+	}
+	return nil
+}
+
+// MySQL の名前付きロック（GET_LOCK）は接続単位なので、専用の接続を確保してから実行する
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&got); err != nil {
+		return err
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return fmt.Errorf("could not acquire migration lock %q", migrationLockName)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT RELEASE_LOCK(?)", migrationLockName)
+
+	if err := ensureMigrationTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureMigrationTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version bigint NOT NULL,
+  name varchar(255) NOT NULL,
+  applied_at datetime(3) NOT NULL,
+  PRIMARY KEY (version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	return err
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]time.Time{}
+	for rows.Next() {
+		var v int64
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+// DSN で multiStatements を有効にしていないので、1文ずつに分けて実行する
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 行末の ; で文を区切る（-- で始まる行はコメントとして捨てる）
+func splitStatements(script string) []string {
+	var out []string
+	var b strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			out = append(out, strings.TrimSuffix(strings.TrimSpace(b.String()), ";"))
+			b.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(b.String()); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+// dir に空の up/down ファイルを作る。バージョンは作成時刻（UTC）
+func CreateMigration(dir, name string, now time.Time) (up, down string, err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "", "", errors.New("migration name is empty")
+	}
+	base := fmt.Sprintf("%s_%s", now.UTC().Format("20060102150405"), name)
+	up = filepath.Join(dir, base+".up.sql")
+	down = filepath.Join(dir, base+".down.sql")
+	if err := createEmptyFile(up); err != nil {
+		return "", "", err
+	}
+	// down を作れなければ up も消し、片方だけのマイグレーションを残さない
+	if err := createEmptyFile(down); err != nil {
+		os.Remove(up)
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+// 既にあれば作らずにエラーを返す
+func createEmptyFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- テーブルを作る
+CREATE TABLE a (
+  id INT -- 行の途中のコメントは残す
+);
+
+  -- 字下げしたコメント
+INSERT INTO a VALUES (1);
+UPDATE a SET id = 2`
+	want := []string{
+		"CREATE TABLE a (\n  id INT -- 行の途中のコメントは残す\n)",
+		"INSERT INTO a VALUES (1)",
+		// 最後の ; が無い文も返す
+		"UPDATE a SET id = 2",
+	}
+	if got := splitStatements(script); !slices.Equal(got, want) {
+		t.Errorf("splitStatements = %q, want %q", got, want)
+	}
+	if got := splitStatements("-- コメントだけ\n\n"); len(got) != 0 {
+		t.Errorf("splitStatements(comments only) = %q, want none", got)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+	fsys := fstest.MapFS{
+		"migrations/10_add_b.up.sql":     file("CREATE TABLE b (id INT);"),
+		"migrations/10_add_b.down.sql":   file("DROP TABLE b;"),
+		"migrations/9_create_a.up.sql":   file("CREATE TABLE a (id INT);"),
+		"migrations/9_create_a.down.sql": file("DROP TABLE a;"),
+		"migrations/11_no_down.up.sql":   file("SELECT 1;"),
+	}
+	ms, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	// バージョンは文字列ではなく数値の順
+	want := []Migration{
+		{Version: 9, Name: "create_a", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"},
+		{Version: 10, Name: "add_b", Up: "CREATE TABLE b (id INT);", Down: "DROP TABLE b;"},
+		{Version: 11, Name: "no_down", Up: "SELECT 1;"},
+	}
+	if !slices.Equal(ms, want) {
+		t.Errorf("loadMigrations = %+v, want %+v", ms, want)
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"bad file name":     {"migrations/1_Create-A.up.sql": file("SELECT 1;")},
+		"conflicting names": {"migrations/1_a.up.sql": file("SELECT 1;"), "migrations/1_b.down.sql": file("SELECT 1;")},
+		"down only":         {"migrations/1_a.down.sql": file("SELECT 1;")},
+		"empty up":          {"migrations/1_a.up.sql": file(" \n"), "migrations/1_a.down.sql": file("SELECT 1;")},
+	}
+	for name, fsys := range invalid {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("loadMigrations(%s) succeeded", name)
+		}
+	}
+}
+
+// 埋め込みのマイグレーションがすべて読め、up / down が揃っている
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := loadMigrations(migrationFS)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for _, m := range ms {
+		if strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2030, 1, 2, 12, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+	up, down, err := CreateMigration(dir, "  Add Users/Email index! ", now)
+	if err != nil {
+		t.Fatalf("CreateMigration: %v", err)
+	}
+	// 名前は英小文字・数字と _ に揃え、バージョンは UTC の作成時刻
+	if want := filepath.Join(dir, "20300102030405_add_users_email_index.up.sql"); up != want {
+		t.Errorf("up = %s, want %s", up, want)
+	}
+	if want := filepath.Join(dir, "20300102030405_add_users_email_index.down.sql"); down != want {
+		t.Errorf("down = %s, want %s", down, want)
+	}
+	for _, p := range []string{up, down} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("stat %s: %v", p, err)
+		}
+	}
+
+	if _, _, err := CreateMigration(dir, " !! ", now); err == nil {
+		t.Error("CreateMigration(empty name) succeeded")
+	}
+	// 既存のファイルは上書きしない
+	if _, _, err := CreateMigration(dir, "add users email index", now); err == nil {
+		t.Error("CreateMigration(existing) succeeded")
+	}
+
+	// down を作れなければ up も残さない
+	later := now.Add(time.Second)
+	if err := os.WriteFile(filepath.Join(dir, "20300102030406_add_b.down.sql"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := CreateMigration(dir, "add_b", later); err == nil {
+		t.Fatal("CreateMigration(existing down) succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20300102030406_add_b.up.sql")); !os.IsNotExist(err) {
+		t.Errorf("CreateMigration left the up file behind: %v", err)
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS plan_inventories;
+DROP TABLE IF EXISTS plans;
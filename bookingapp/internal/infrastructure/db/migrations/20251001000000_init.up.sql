@@ -0,0 +1,54 @@
+-- AutoMigrate 時代のスキーマをそのまま引き継ぐため IF NOT EXISTS で作成する
+CREATE TABLE IF NOT EXISTS plans (
+  id bigint NOT NULL AUTO_INCREMENT,
+  name varchar(255) NOT NULL,
+  keyword varchar(255) NULL,
+  price bigint NOT NULL,
+  capacity bigint NOT NULL DEFAULT 10,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_plans_keyword (keyword)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS plan_inventories (
+  plan_id bigint NOT NULL,
+  date date NOT NULL,
+  capacity bigint NOT NULL,
+  reserved bigint NOT NULL DEFAULT 0,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (plan_id, date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS reservations (
+  id bigint NOT NULL AUTO_INCREMENT,
+  user_id char(36) NOT NULL,
+  plan_id bigint NOT NULL,
+  number bigint NOT NULL,
+  checkin date NOT NULL,
+  checkout date NOT NULL,
+  total bigint NOT NULL,
+  status varchar(20) NOT NULL DEFAULT 'confirmed',
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_reservations_user_id (user_id),
+  INDEX idx_reservations_plan_id (plan_id),
+  INDEX idx_reservations_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS users (
+  id char(36) NOT NULL,
+  name varchar(255) NOT NULL,
+  email varchar(255) NOT NULL,
+  phone_number varchar(50) NULL,
+  address varchar(255) NULL,
+  date_of_birth date NULL,
+  registered_at datetime(3) NOT NULL,
+  status varchar(50) NOT NULL,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  UNIQUE INDEX idx_users_email (email)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
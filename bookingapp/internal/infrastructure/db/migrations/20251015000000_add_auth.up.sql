@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN password_hash varchar(255) NULL;
+
+CREATE TABLE sessions (
+  id char(36) NOT NULL,
+  user_id char(36) NOT NULL,
+  refresh_token_hash char(64) NOT NULL,
+  expires_at datetime(3) NOT NULL,
+  revoked_at datetime(3) NULL,
+  created_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_sessions_user_id (user_id),
+  UNIQUE INDEX idx_sessions_refresh_token_hash (refresh_token_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+-- 既存ユーザーはすべてゲスト。スタッフは UPDATE users SET role = 'staff' で昇格させる
+ALTER TABLE users ADD COLUMN role varchar(20) NOT NULL DEFAULT 'guest';
@@ -0,0 +1,9 @@
+-- 複数ロールは戻せないので、スタッフ以上の権限を持つユーザーを staff にする
+ALTER TABLE users ADD COLUMN role varchar(20) NOT NULL DEFAULT 'guest';
+
+UPDATE users SET role = 'staff'
+  WHERE id IN (SELECT user_id FROM user_roles WHERE role IN ('staff', 'admin'));
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
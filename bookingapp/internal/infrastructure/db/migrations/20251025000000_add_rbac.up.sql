@@ -0,0 +1,46 @@
+-- users.role（1ユーザー1ロール）を user_roles（複数ロール）へ移す
+CREATE TABLE roles (
+  name varchar(20) NOT NULL,
+  created_at datetime(3) NULL,
+  PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE role_permissions (
+  role varchar(20) NOT NULL,
+  permission varchar(50) NOT NULL,
+  PRIMARY KEY (role, permission)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE user_roles (
+  user_id char(36) NOT NULL,
+  role varchar(20) NOT NULL,
+  created_at datetime(3) NULL,
+  PRIMARY KEY (user_id, role),
+  INDEX idx_user_roles_role (role)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- entity.DefaultRolePermissions と同じ内容
+INSERT INTO roles (name, created_at) VALUES
+  ('guest', NOW(3)),
+  ('staff', NOW(3)),
+  ('admin', NOW(3));
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('guest', 'reservations:book'),
+  ('staff', 'reservations:book'),
+  ('staff', 'reservations:manage_all'),
+  ('staff', 'users:read_all'),
+  ('staff', 'plans:manage'),
+  ('admin', 'reservations:book'),
+  ('admin', 'reservations:manage_all'),
+  ('admin', 'users:read_all'),
+  ('admin', 'plans:manage'),
+  ('admin', 'roles:manage');
+
+INSERT INTO user_roles (user_id, role, created_at)
+  SELECT id, 'guest', NOW(3) FROM users;
+
+INSERT INTO user_roles (user_id, role, created_at)
+  SELECT id, role, NOW(3) FROM users WHERE role <> 'guest';
+
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,3 @@
+DROP INDEX idx_plans_deleted_at ON plans;
+
+ALTER TABLE plans DROP COLUMN deleted_at;
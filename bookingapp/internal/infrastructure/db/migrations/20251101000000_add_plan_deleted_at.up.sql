@@ -0,0 +1,3 @@
+ALTER TABLE plans ADD COLUMN deleted_at datetime(3) NULL;
+
+CREATE INDEX idx_plans_deleted_at ON plans (deleted_at);
@@ -0,0 +1,5 @@
+DROP INDEX idx_reservations_plan_checkin ON reservations;
+
+DROP INDEX idx_reservations_user_checkin ON reservations;
+
+DROP INDEX idx_reservations_checkin ON reservations;
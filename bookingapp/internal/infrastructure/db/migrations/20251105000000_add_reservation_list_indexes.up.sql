@@ -0,0 +1,7 @@
+-- 予約一覧の絞り込み・並び替え・キーセットページング用。
+-- InnoDB のセカンダリインデックスは末尾に主キー（id）を含むので (checkin, id) の順で引ける
+CREATE INDEX idx_reservations_checkin ON reservations (checkin);
+
+CREATE INDEX idx_reservations_user_checkin ON reservations (user_id, checkin);
+
+CREATE INDEX idx_reservations_plan_checkin ON reservations (plan_id, checkin);
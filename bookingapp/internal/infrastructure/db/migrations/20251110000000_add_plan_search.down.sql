@@ -0,0 +1,3 @@
+DROP INDEX idx_plans_price ON plans;
+
+ALTER TABLE plans DROP COLUMN max_guests;
@@ -0,0 +1,3 @@
+ALTER TABLE plans ADD COLUMN max_guests bigint NOT NULL DEFAULT 4;
+
+CREATE INDEX idx_plans_price ON plans (price);
@@ -0,0 +1,3 @@
+ALTER TABLE reservations DROP COLUMN currency;
+
+ALTER TABLE plans DROP COLUMN currency;
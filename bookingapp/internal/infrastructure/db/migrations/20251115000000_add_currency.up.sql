@@ -0,0 +1,4 @@
+-- 金額は通貨の最小単位で持ち、通貨コード（ISO 4217）を並べて保存する。既存の行は円
+ALTER TABLE plans ADD COLUMN currency char(3) NOT NULL DEFAULT 'JPY';
+
+ALTER TABLE reservations ADD COLUMN currency char(3) NOT NULL DEFAULT 'JPY';
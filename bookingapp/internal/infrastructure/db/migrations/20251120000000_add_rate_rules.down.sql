@@ -0,0 +1,3 @@
+DROP TABLE reservation_nights;
+
+DROP TABLE plan_rate_rules;
@@ -0,0 +1,27 @@
+-- プランの料金ルール（シーズン・曜日・祝日）。days はカンマ区切りで、空なら毎日
+CREATE TABLE plan_rate_rules (
+  id bigint NOT NULL AUTO_INCREMENT,
+  plan_id bigint NOT NULL,
+  name varchar(255) NOT NULL,
+  start_date date NULL,
+  end_date date NULL,
+  days varchar(100) NOT NULL DEFAULT '',
+  adjustment varchar(10) NOT NULL,
+  amount bigint NOT NULL DEFAULT 0,
+  percent bigint NOT NULL DEFAULT 0,
+  priority bigint NOT NULL DEFAULT 0,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_plan_rate_rules_plan_id (plan_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 予約の泊ごとの料金の内訳（予約時点の価格を残す）
+CREATE TABLE reservation_nights (
+  reservation_id bigint NOT NULL,
+  date date NOT NULL,
+  rate bigint NOT NULL,
+  amount bigint NOT NULL,
+  currency char(3) NOT NULL DEFAULT 'JPY',
+  PRIMARY KEY (reservation_id, date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
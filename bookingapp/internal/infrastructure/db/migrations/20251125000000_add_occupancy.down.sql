@@ -0,0 +1,15 @@
+ALTER TABLE reservation_nights DROP COLUMN infant_rate;
+
+ALTER TABLE reservation_nights DROP COLUMN child_rate;
+
+ALTER TABLE reservations DROP COLUMN infants;
+
+ALTER TABLE reservations DROP COLUMN children;
+
+ALTER TABLE reservations DROP COLUMN adults;
+
+ALTER TABLE plans DROP COLUMN infant_rate;
+
+ALTER TABLE plans DROP COLUMN child_rate;
+
+ALTER TABLE plans DROP COLUMN min_guests;
@@ -0,0 +1,19 @@
+-- 人数の範囲と子ども料金（大人の料金に対する %）
+ALTER TABLE plans ADD COLUMN min_guests bigint NOT NULL DEFAULT 1;
+
+ALTER TABLE plans ADD COLUMN child_rate bigint NOT NULL DEFAULT 70;
+
+ALTER TABLE plans ADD COLUMN infant_rate bigint NOT NULL DEFAULT 50;
+
+-- 予約の大人・子どもの内訳。既存の予約は全員大人として扱う
+ALTER TABLE reservations ADD COLUMN adults bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE reservations ADD COLUMN children bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE reservations ADD COLUMN infants bigint NOT NULL DEFAULT 0;
+
+UPDATE reservations SET adults = number;
+
+ALTER TABLE reservation_nights ADD COLUMN child_rate bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE reservation_nights ADD COLUMN infant_rate bigint NOT NULL DEFAULT 0;
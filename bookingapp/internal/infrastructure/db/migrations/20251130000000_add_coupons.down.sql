@@ -0,0 +1,13 @@
+DELETE FROM role_permissions WHERE permission = 'coupons:manage';
+
+DROP INDEX idx_reservations_coupon_id ON reservations;
+
+ALTER TABLE reservations DROP COLUMN discount;
+
+ALTER TABLE reservations DROP COLUMN coupon_code;
+
+ALTER TABLE reservations DROP COLUMN coupon_id;
+
+DROP TABLE coupon_redemptions;
+
+DROP TABLE coupons;
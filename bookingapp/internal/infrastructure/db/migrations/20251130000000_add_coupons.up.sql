@@ -0,0 +1,43 @@
+-- キャンペーンのクーポン。plan_ids はカンマ区切りで、空ならすべてのプラン
+CREATE TABLE coupons (
+  id bigint NOT NULL AUTO_INCREMENT,
+  code varchar(64) NOT NULL,
+  name varchar(255) NOT NULL,
+  discount varchar(10) NOT NULL,
+  percent bigint NOT NULL DEFAULT 0,
+  amount bigint NOT NULL DEFAULT 0,
+  currency char(3) NOT NULL DEFAULT 'JPY',
+  starts_at datetime(3) NULL,
+  ends_at datetime(3) NULL,
+  plan_ids varchar(255) NOT NULL DEFAULT '',
+  max_redemptions bigint NOT NULL DEFAULT 0,
+  per_user_limit bigint NOT NULL DEFAULT 0,
+  redemptions bigint NOT NULL DEFAULT 0,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  UNIQUE INDEX idx_coupons_code (code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- クーポンの利用（予約 1 件につき 1 行。キャンセルで削除する）
+CREATE TABLE coupon_redemptions (
+  reservation_id bigint NOT NULL,
+  coupon_id bigint NOT NULL,
+  user_id char(36) NOT NULL,
+  created_at datetime(3) NULL,
+  PRIMARY KEY (reservation_id),
+  INDEX idx_coupon_redemptions_coupon_user (coupon_id, user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 予約に使ったクーポンと割引額（total は割引後）
+ALTER TABLE reservations ADD COLUMN coupon_id bigint NULL;
+
+ALTER TABLE reservations ADD COLUMN coupon_code varchar(64) NOT NULL DEFAULT '';
+
+ALTER TABLE reservations ADD COLUMN discount bigint NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_reservations_coupon_id ON reservations (coupon_id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('staff', 'coupons:manage'),
+  ('admin', 'coupons:manage');
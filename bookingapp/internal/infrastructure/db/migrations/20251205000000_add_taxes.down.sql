@@ -0,0 +1,7 @@
+ALTER TABLE reservations DROP COLUMN accommodation_tax;
+
+ALTER TABLE reservations DROP COLUMN consumption_tax;
+
+ALTER TABLE reservations DROP COLUMN subtotal;
+
+ALTER TABLE plans DROP COLUMN jurisdiction;
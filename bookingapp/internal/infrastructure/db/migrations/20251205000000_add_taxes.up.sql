@@ -0,0 +1,11 @@
+-- プランの課税地域（空なら税のルールの既定の地域）
+ALTER TABLE plans ADD COLUMN jurisdiction varchar(32) NOT NULL DEFAULT '';
+
+-- 予約の税の内訳。total は税込みになる（既存の予約は税抜 = 税込みとして扱う）
+ALTER TABLE reservations ADD COLUMN subtotal bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE reservations ADD COLUMN consumption_tax bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE reservations ADD COLUMN accommodation_tax bigint NOT NULL DEFAULT 0;
+
+UPDATE reservations SET subtotal = total;
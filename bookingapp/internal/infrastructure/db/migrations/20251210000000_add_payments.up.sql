@@ -0,0 +1,16 @@
+-- 予約の決済（予約 1 件につき 1 行）。金額は currency の最小単位
+CREATE TABLE payments (
+  id bigint NOT NULL AUTO_INCREMENT,
+  reservation_id bigint NOT NULL,
+  amount bigint NOT NULL,
+  captured bigint NOT NULL,
+  refunded bigint NOT NULL,
+  currency char(3) NOT NULL DEFAULT 'JPY',
+  status varchar(20) NOT NULL,
+  gateway_ref varchar(255) NOT NULL,
+  failure_reason varchar(255) NOT NULL,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  UNIQUE INDEX idx_payments_reservation_id (reservation_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,11 @@
+ALTER TABLE reservations DROP COLUMN cancellation_fee;
+
+ALTER TABLE reservations DROP COLUMN cancellation_rules;
+
+ALTER TABLE reservations DROP COLUMN cancellation_policy_name;
+
+ALTER TABLE reservations DROP COLUMN cancellation_policy_id;
+
+ALTER TABLE plans DROP COLUMN cancellation_policy_id;
+
+DROP TABLE cancellation_policies;
@@ -0,0 +1,21 @@
+-- キャンセルポリシー。rules は "日前:料率" のカンマ区切り（例: "7:0,3:50,0:100"）
+CREATE TABLE cancellation_policies (
+  id bigint NOT NULL AUTO_INCREMENT,
+  name varchar(100) NOT NULL,
+  rules varchar(255) NOT NULL,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 0 ならキャンセル料なし
+ALTER TABLE plans ADD COLUMN cancellation_policy_id bigint NOT NULL DEFAULT 0;
+
+-- 予約時点のポリシーの写し（後でポリシーを変えても既存の予約には影響しない）と確定したキャンセル料
+ALTER TABLE reservations ADD COLUMN cancellation_policy_id bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE reservations ADD COLUMN cancellation_policy_name varchar(100) NOT NULL DEFAULT '';
+
+ALTER TABLE reservations ADD COLUMN cancellation_rules varchar(255) NOT NULL DEFAULT '';
+
+ALTER TABLE reservations ADD COLUMN cancellation_fee bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+-- Idempotency-Key 付きリクエストの記録。status_code が 0 の間は処理中で、expires_at を過ぎたら消す
+CREATE TABLE idempotency_keys (
+  id char(36) NOT NULL,
+  user_id char(36) NOT NULL,
+  idem_key varchar(255) NOT NULL,
+  request_hash char(64) NOT NULL,
+  status_code int NOT NULL,
+  content_type varchar(100) NOT NULL,
+  body mediumblob NULL,
+  created_at datetime(3) NOT NULL,
+  expires_at datetime(3) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_idempotency_keys_user_key (user_id, idem_key),
+  KEY idx_idempotency_keys_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN version;
+
+ALTER TABLE plans DROP COLUMN version;
+
+ALTER TABLE reservations DROP COLUMN version;
@@ -0,0 +1,6 @@
+-- 楽観的排他制御のバージョン。更新は読み込んだ時点のバージョンと一致する場合だけ行い、1 ずつ増やす
+ALTER TABLE reservations ADD COLUMN version int NOT NULL DEFAULT 1;
+
+ALTER TABLE plans ADD COLUMN version int NOT NULL DEFAULT 1;
+
+ALTER TABLE users ADD COLUMN version int NOT NULL DEFAULT 1;
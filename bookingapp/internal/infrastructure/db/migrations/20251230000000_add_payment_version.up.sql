@@ -0,0 +1,2 @@
+-- 決済の楽観的排他制御のバージョン。請求・取り消し・返金が同じ決済を同時に書き換えないよう、読み込んだ時点のバージョンと一致する場合だけ更新する
+ALTER TABLE payments ADD COLUMN version int NOT NULL DEFAULT 1;
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// キャンセルポリシー。rules は "日前:料率" のカンマ区切り（例: "7:0,3:50,0:100"）
+type CancellationPolicyModel struct {
+	ID        int    `gorm:"primaryKey;autoIncrement"`
+	Name      string `gorm:"size:100;not null"`
+	Rules     string `gorm:"size:255;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (CancellationPolicyModel) TableName() string { return "cancellation_policies" }
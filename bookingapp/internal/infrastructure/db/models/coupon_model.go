@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// キャンペーンのクーポン
+type CouponModel struct {
+	ID             int    `gorm:"primaryKey;autoIncrement"`
+	Code           string `gorm:"size:64;not null;uniqueIndex"`
+	Name           string `gorm:"size:255;not null"`
+	Discount       string `gorm:"size:10;not null"`
+	Percent        int    `gorm:"not null"`
+	Amount         int64  `gorm:"not null"`
+	Currency       string `gorm:"type:char(3);not null;default:'JPY'"`
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	PlanIDs        string `gorm:"size:255;not null"` // カンマ区切り。空ならすべてのプラン
+	MaxRedemptions int    `gorm:"not null"`
+	PerUserLimit   int    `gorm:"not null"`
+	Redemptions    int    `gorm:"not null"` // 利用中の数
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (CouponModel) TableName() string { return "coupons" }
+
+// クーポンの利用（予約 1 件につき 1 行。キャンセルで削除する）
+type CouponRedemptionModel struct {
+	ReservationID int    `gorm:"primaryKey;autoIncrement:false"`
+	CouponID      int    `gorm:"not null;index:idx_coupon_redemptions_coupon_user,priority:1"`
+	UserID        string `gorm:"type:char(36);not null;index:idx_coupon_redemptions_coupon_user,priority:2"`
+	CreatedAt     time.Time
+}
+
+func (CouponRedemptionModel) TableName() string { return "coupon_redemptions" }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Idempotency-Key 付きリクエストの記録。status_code が 0 の間は処理中
+type IdempotencyModel struct {
+	ID          string    `gorm:"primaryKey;type:char(36)"`
+	UserID      string    `gorm:"type:char(36);not null;uniqueIndex:idx_idempotency_keys_user_key,priority:1"`
+	IdemKey     string    `gorm:"column:idem_key;size:255;not null;uniqueIndex:idx_idempotency_keys_user_key,priority:2"`
+	RequestHash string    `gorm:"type:char(64);not null"`
+	StatusCode  int       `gorm:"not null"`
+	ContentType string    `gorm:"size:100;not null"`
+	Body        []byte    `gorm:"type:mediumblob"`
+	CreatedAt   time.Time `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+func (IdempotencyModel) TableName() string { return "idempotency_keys" }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// 予約の決済（予約 1 件につき 1 行）。金額は currency の最小単位
+type PaymentModel struct {
+	ID            int    `gorm:"primaryKey;autoIncrement"`
+	ReservationID int    `gorm:"not null;uniqueIndex"`
+	Amount        int64  `gorm:"not null"`
+	Captured      int64  `gorm:"not null"`
+	Refunded      int64  `gorm:"not null"`
+	Currency      string `gorm:"type:char(3);not null;default:'JPY'"`
+	Status        string `gorm:"size:20;not null"`
+	GatewayRef    string `gorm:"size:255;not null"`
+	FailureReason string `gorm:"size:255;not null"`
+	Version       int    `gorm:"not null;default:1"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (PaymentModel) TableName() string { return "payments" }
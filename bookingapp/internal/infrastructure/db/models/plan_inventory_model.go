@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// プラン×宿泊日ごとの在庫。行が無い日は PlanModel.Capacity を初期値として作成する
+type PlanInventoryModel struct {
+	PlanID    int       `gorm:"primaryKey;autoIncrement:false"`
+	Date      time.Time `gorm:"primaryKey;type:date"`
+	Capacity  int       `gorm:"not null"`
+	Reserved  int       `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (PlanInventoryModel) TableName() string { return "plan_inventories" }
@@ -6,9 +6,24 @@ type PlanModel struct {
 	ID        int    `gorm:"primaryKey;autoIncrement"`
 	Name      string `gorm:"size:255;not null"`
 	Keyword   string `gorm:"size:255;index"`
-	Price     int    `gorm:"not null"`
+	Price     int64  `gorm:"not null;index"`
+	Currency  string `gorm:"type:char(3);not null;default:'JPY'"`
+	Capacity  int    `gorm:"not null;default:10"`
+	MinGuests int    `gorm:"not null;default:1"`
+	MaxGuests int    `gorm:"not null;default:4"`
+	// 0%（無料）もあり得るので default タグは付けない（付けるとゼロ値が INSERT されない）
+	ChildRate  int `gorm:"not null"`
+	InfantRate int `gorm:"not null"`
+	// 課税地域。空なら既定の地域
+	Jurisdiction string `gorm:"size:32;not null"`
+	// キャンセルポリシー。0 ならキャンセル料なし
+	CancellationPolicyID int `gorm:"not null"`
+	// 楽観的排他制御のバージョン
+	Version   int `gorm:"not null;default:1"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// gorm.DeletedAt だと FindByID からも除外されるので、自前で条件を付ける
+	DeletedAt *time.Time `gorm:"index"`
 }
 
 func (PlanModel) TableName() string { return "plans" }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// プランの料金ルール
+type RateRuleModel struct {
+	ID         int        `gorm:"primaryKey;autoIncrement"`
+	PlanID     int        `gorm:"not null;index"`
+	Name       string     `gorm:"size:255;not null"`
+	StartDate  *time.Time `gorm:"type:date"`
+	EndDate    *time.Time `gorm:"type:date"`
+	Days       string     `gorm:"size:100;not null;default:''"` // カンマ区切り（例: "sat,holiday_eve"）。空なら毎日
+	Adjustment string     `gorm:"size:10;not null"`
+	Amount     int64      `gorm:"not null;default:0"`
+	Percent    int        `gorm:"not null;default:0"`
+	Priority   int        `gorm:"not null;default:0"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (RateRuleModel) TableName() string { return "plan_rate_rules" }
@@ -3,13 +3,33 @@ package models
 import "time"
 
 type ReservationModel struct {
-	ID        int       `gorm:"primaryKey;autoIncrement"`
-	UserID    string    `gorm:"type:char(36);not null;index"`
-	PlanID    int       `gorm:"not null;index"`
-	Number    int       `gorm:"not null"`
-	Checkin   time.Time `gorm:"type:date;not null"`
-	Checkout  time.Time `gorm:"type:date;not null"`
-	Total     int       `gorm:"not null"`
+	ID       int       `gorm:"primaryKey;autoIncrement"`
+	UserID   string    `gorm:"type:char(36);not null;index;index:idx_reservations_user_checkin,priority:1"`
+	PlanID   int       `gorm:"not null;index;index:idx_reservations_plan_checkin,priority:1"`
+	Number   int       `gorm:"not null"`
+	Adults   int       `gorm:"not null"`
+	Children int       `gorm:"not null"`
+	Infants  int       `gorm:"not null"`
+	Checkin  time.Time `gorm:"type:date;not null;index:idx_reservations_checkin;index:idx_reservations_user_checkin,priority:2;index:idx_reservations_plan_checkin,priority:2"`
+	Checkout time.Time `gorm:"type:date;not null"`
+	Total    int64     `gorm:"not null"` // 税込み
+	// 税の内訳（total = subtotal + consumption_tax + accommodation_tax）
+	Subtotal         int64  `gorm:"not null"`
+	ConsumptionTax   int64  `gorm:"not null"`
+	AccommodationTax int64  `gorm:"not null"`
+	Currency         string `gorm:"type:char(3);not null;default:'JPY'"`
+	Status           string `gorm:"size:20;not null;default:'confirmed';index"`
+	// 使ったクーポンと割引額（total は割引後）
+	CouponID   *int   `gorm:"index"`
+	CouponCode string `gorm:"size:64;not null"`
+	Discount   int64  `gorm:"not null"`
+	// 予約時点のキャンセルポリシーの写し（id が 0 なら無し。rules の形式は cancellation_policies と同じ）と確定したキャンセル料
+	CancellationPolicyID   int    `gorm:"not null"`
+	CancellationPolicyName string `gorm:"size:100;not null"`
+	CancellationRules      string `gorm:"size:255;not null"`
+	CancellationFee        int64  `gorm:"not null"`
+	// 楽観的排他制御のバージョン（更新は読み込んだ時点のバージョンと一致する場合だけ）
+	Version   int `gorm:"not null;default:1"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// 予約の泊ごとの料金の内訳
+type ReservationNightModel struct {
+	ReservationID int       `gorm:"primaryKey;autoIncrement:false"`
+	Date          time.Time `gorm:"primaryKey;type:date"`
+	Rate          int64     `gorm:"not null"` // 大人1人あたり
+	ChildRate     int64     `gorm:"not null"` // 小学生1人あたり
+	InfantRate    int64     `gorm:"not null"` // 幼児1人あたり
+	Amount        int64     `gorm:"not null"` // 区分ごとの価格 × 人数の合計
+	Currency      string    `gorm:"type:char(3);not null;default:'JPY'"`
+}
+
+func (ReservationNightModel) TableName() string { return "reservation_nights" }
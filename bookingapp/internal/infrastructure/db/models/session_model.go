@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type SessionModel struct {
+	ID               string    `gorm:"primaryKey;type:char(36)"`
+	UserID           string    `gorm:"type:char(36);not null;index"`
+	RefreshTokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt        time.Time `gorm:"not null"`
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+}
+
+func (SessionModel) TableName() string { return "sessions" }
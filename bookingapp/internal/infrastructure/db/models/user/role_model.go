@@ -0,0 +1,26 @@
+package user
+
+import "time"
+
+type RoleModel struct {
+	Name      string `gorm:"primaryKey;size:20"`
+	CreatedAt time.Time
+}
+
+func (RoleModel) TableName() string { return "roles" }
+
+type RolePermissionModel struct {
+	Role       string `gorm:"primaryKey;size:20"`
+	Permission string `gorm:"primaryKey;size:50"`
+}
+
+func (RolePermissionModel) TableName() string { return "role_permissions" }
+
+// ユーザーに付与されたロール
+type UserRoleModel struct {
+	UserID    string `gorm:"primaryKey;type:char(36)"`
+	Role      string `gorm:"primaryKey;size:20;index"`
+	CreatedAt time.Time
+}
+
+func (UserRoleModel) TableName() string { return "user_roles" }
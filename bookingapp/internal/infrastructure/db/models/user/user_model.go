@@ -11,6 +11,8 @@ type UserModel struct {
 	DateOfBirth  *time.Time `gorm:"type:date"`
 	RegisteredAt time.Time  `gorm:"not null"`
 	Status       string     `gorm:"size:50;not null"`
+	PasswordHash *string    `gorm:"size:255"`
+	Version      int        `gorm:"not null;default:1"` // 楽観的排他制御のバージョン（ロールを変えるたびに増える）
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
@@ -2,6 +2,8 @@ package db
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"time"
 
 	"gorm.io/driver/mysql"
@@ -16,12 +18,37 @@ type Config struct {
 	Name string // database name
 }
 
+// 環境変数から接続情報を読む（未設定ならローカル開発用のデフォルト）
+func ConfigFromEnv() Config {
+	c := Config{User: "root", Pass: "password", Host: "127.0.0.1", Port: 3306, Name: "booking"}
+	if v := os.Getenv("DB_HOST"); v != "" {
+		c.Host = v
+	}
+	if v := os.Getenv("DB_PORT"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.Port = i
+		}
+	}
+	if v := os.Getenv("DB_USER"); v != "" {
+		c.User = v
+	}
+	if v := os.Getenv("DB_PASS"); v != "" {
+		c.Pass = v
+	}
+	if v := os.Getenv("DB_NAME"); v != "" {
+		c.Name = v
+	}
+	return c
+}
+
 func Open(c Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=Local",
 		c.User, c.Pass, c.Host, c.Port, c.Name,
 	)
 	return gorm.Open(mysql.Open(dsn), &gorm.Config{
 		// ここで必要ならLoggerやNamingStrategyを設定
+		// 一意制約違反などをドライバ固有のエラーから gorm.ErrDuplicatedKey 等に変換する
+		TranslateError: true,
 	})
 }
 
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+type CancellationPolicyRepoMemory struct {
+	mu   sync.RWMutex
+	data map[int]*entity.CancellationPolicy
+}
+
+func NewCancellationPolicyRepoMemory() repository.CancellationPolicyRepository {
+	return &CancellationPolicyRepoMemory{data: map[int]*entity.CancellationPolicy{}}
+}
+
+func (m *CancellationPolicyRepoMemory) FindByID(ctx context.Context, id int) (*entity.CancellationPolicy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if p, ok := m.data[id]; ok {
+		return p.Snapshot(), nil
+	}
+	return nil, nil
+}
+
+func (m *CancellationPolicyRepoMemory) List(ctx context.Context) ([]*entity.CancellationPolicy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]*entity.CancellationPolicy, 0, len(m.data))
+	for _, p := range m.data {
+		out = append(out, p.Snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (m *CancellationPolicyRepoMemory) Save(ctx context.Context, policy *entity.CancellationPolicy) (*entity.CancellationPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := policy.Snapshot()
+	if cp.ID == 0 {
+		for id := range m.data {
+			cp.ID = max(cp.ID, id)
+		}
+		cp.ID++
+	} else if _, ok := m.data[cp.ID]; !ok {
+		return nil, errors.New("cancellation policy not found")
+	}
+	m.data[cp.ID] = cp
+	return cp.Snapshot(), nil
+}
+
+var _ repository.CancellationPolicyRepository = (*CancellationPolicyRepoMemory)(nil)
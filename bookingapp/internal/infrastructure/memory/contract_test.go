@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/domain/repository/repositorytest"
+	"testing"
+)
+
+func TestRepositoryContract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T, plans []*entity.Plan) repositorytest.Backend {
+		p := NewPlanRepoMemory(plans)
+		r := NewReservationRepoMemory()
+		u := NewUserRepoMemory()
+		c := NewCouponRepoMemory()
+		pay := NewPaymentRepoMemory()
+		return repositorytest.Backend{
+			Repositories:         repository.Repositories{Plans: p, Reservations: r, Users: u, Coupons: c, Payments: pay},
+			Roles:                NewRoleRepoMemory(),
+			Sessions:             NewSessionRepoMemory(),
+			CancellationPolicies: NewCancellationPolicyRepoMemory(),
+			Idempotency:          NewIdempotencyRepoMemory(),
+			Tx:                   NewUnitOfWork(p, r, u, c, pay),
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// クーポンの利用（予約 1 件につき 1 つ）
+type couponRedemption struct {
+	couponID int
+	userID   string
+}
+
+type CouponRepoMemory struct {
+	mu          sync.RWMutex
+	data        map[int]*entity.Coupon
+	redemptions map[int]couponRedemption // reservationID -> 利用
+}
+
+func NewCouponRepoMemory() repository.CouponRepository {
+	return &CouponRepoMemory{data: map[int]*entity.Coupon{}, redemptions: map[int]couponRedemption{}}
+}
+
+func (m *CouponRepoMemory) FindByCode(ctx context.Context, code string) (*entity.Coupon, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, c := range m.data {
+		if c.Code == code {
+			return cloneCoupon(c), nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *CouponRepoMemory) FindByID(ctx context.Context, id int) (*entity.Coupon, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if c, ok := m.data[id]; ok {
+		return cloneCoupon(c), nil
+	}
+	return nil, nil
+}
+
+func (m *CouponRepoMemory) List(ctx context.Context) ([]*entity.Coupon, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]*entity.Coupon, 0, len(m.data))
+	for _, c := range m.data {
+		out = append(out, cloneCoupon(c))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (m *CouponRepoMemory) Save(ctx context.Context, coupon *entity.Coupon) (*entity.Coupon, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, c := range m.data {
+		if c.Code == coupon.Code && id != coupon.ID {
+			return nil, repository.ErrDuplicateCouponCode
+		}
+	}
+	cp := cloneCoupon(coupon)
+	if cp.ID == 0 {
+		for id := range m.data {
+			cp.ID = max(cp.ID, id)
+		}
+		cp.ID++
+		cp.Redemptions = 0
+	} else if old, ok := m.data[cp.ID]; ok {
+		cp.Redemptions = old.Redemptions
+	} else {
+		return nil, errors.New("coupon not found")
+	}
+	prev, existed := m.data[cp.ID]
+	m.data[cp.ID] = cp
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		undoPut(m.data, cp.ID, prev, existed, cp)
+	})
+	return cloneCoupon(cp), nil
+}
+
+func (m *CouponRepoMemory) Redeem(ctx context.Context, couponID int, userID string, reservationID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.data[couponID]
+	if !ok {
+		return errors.New("coupon not found")
+	}
+	if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+		return repository.ErrCouponExhausted
+	}
+	if c.PerUserLimit > 0 {
+		used := 0
+		for _, r := range m.redemptions {
+			if r.couponID == couponID && r.userID == userID {
+				used++
+			}
+		}
+		if used >= c.PerUserLimit {
+			return repository.ErrCouponUserLimit
+		}
+	}
+	if _, ok := m.redemptions[reservationID]; ok {
+		return errors.New("reservation already has a coupon")
+	}
+	c.Redemptions++
+	m.redemptions[reservationID] = couponRedemption{couponID: couponID, userID: userID}
+	// 利用回数は他の予約の利用と足し引きできるので、ロールバックではこの利用の分だけを戻す
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.releaseLocked(reservationID)
+	})
+	return nil
+}
+
+func (m *CouponRepoMemory) Release(ctx context.Context, reservationID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.releaseLocked(reservationID)
+	if ok {
+		onRollback(ctx, func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if _, used := m.redemptions[reservationID]; used {
+				return
+			}
+			m.redemptions[reservationID] = r
+			if c, ok := m.data[r.couponID]; ok {
+				c.Redemptions++
+			}
+		})
+	}
+	return nil
+}
+
+// 予約の利用を取り消し、取り消した利用を返す（m.mu を取った状態で呼ぶ）
+func (m *CouponRepoMemory) releaseLocked(reservationID int) (couponRedemption, bool) {
+	r, ok := m.redemptions[reservationID]
+	if !ok {
+		return r, false
+	}
+	delete(m.redemptions, reservationID)
+	if c, ok := m.data[r.couponID]; ok {
+		c.Redemptions--
+	}
+	return r, true
+}
+
+// 呼び出し側に内部の状態を書き換えられないよう、スライスとポインタを複製する
+func cloneCoupon(c *entity.Coupon) *entity.Coupon {
+	cp := *c
+	cp.PlanIDs = slices.Clone(c.PlanIDs)
+	if c.StartsAt != nil {
+		t := *c.StartsAt
+		cp.StartsAt = &t
+	}
+	if c.EndsAt != nil {
+		t := *c.EndsAt
+		cp.EndsAt = &t
+	}
+	return &cp
+}
+
+var _ repository.CouponRepository = (*CouponRepoMemory)(nil)
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+type idempotencyKey struct {
+	userID string
+	key    string
+}
+
+type IdempotencyRepoMemory struct {
+	mu   sync.Mutex
+	data map[idempotencyKey]*entity.IdempotencyRecord
+}
+
+func NewIdempotencyRepoMemory() repository.IdempotencyRepository {
+	return &IdempotencyRepoMemory{data: map[idempotencyKey]*entity.IdempotencyRecord{}}
+}
+
+func (m *IdempotencyRepoMemory) Create(ctx context.Context, rec *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := idempotencyKey{rec.UserID, rec.Key}
+	if existing, ok := m.data[k]; ok {
+		return copyIdempotencyRecord(existing), nil
+	}
+	m.data[k] = copyIdempotencyRecord(rec)
+	return nil, nil
+}
+
+func (m *IdempotencyRepoMemory) Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rec := range m.data {
+		if rec.ID == id {
+			rec.StatusCode, rec.ContentType, rec.Body = statusCode, contentType, slices.Clone(body)
+			return nil
+		}
+	}
+	return errors.New("idempotency record not found")
+}
+
+func (m *IdempotencyRepoMemory) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, rec := range m.data {
+		if rec.ID == id {
+			delete(m.data, k)
+		}
+	}
+	return nil
+}
+
+func (m *IdempotencyRepoMemory) DeleteExpired(ctx context.Context, at time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for k, rec := range m.data {
+		if !rec.ExpiresAt.After(at) {
+			delete(m.data, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func copyIdempotencyRecord(rec *entity.IdempotencyRecord) *entity.IdempotencyRecord {
+	cp := *rec
+	cp.Body = slices.Clone(rec.Body)
+	return &cp
+}
+
+var _ repository.IdempotencyRepository = (*IdempotencyRepoMemory)(nil)
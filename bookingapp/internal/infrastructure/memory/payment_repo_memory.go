@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"sync"
+)
+
+type PaymentRepoMemory struct {
+	mu   sync.RWMutex
+	data map[int]entity.Payment // reservationID -> 決済
+	next int
+}
+
+func NewPaymentRepoMemory() repository.PaymentRepository {
+	return &PaymentRepoMemory{data: map[int]entity.Payment{}, next: 1}
+}
+
+func (m *PaymentRepoMemory) FindByReservation(ctx context.Context, reservationID int) (*entity.Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if p, ok := m.data[reservationID]; ok {
+		return &p, nil
+	}
+	return nil, nil
+}
+
+func (m *PaymentRepoMemory) Save(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := *payment
+	prev, existed := m.data[p.ReservationID]
+	if p.ID == 0 {
+		p.ID = m.next
+		m.next++
+		p.Version = 1
+	} else {
+		if !existed || prev.ID != p.ID || prev.Version != p.Version {
+			return nil, repository.ErrConcurrentModification
+		}
+		p.Version++
+	}
+	m.data[p.ReservationID] = p
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		undoPut(m.data, p.ReservationID, prev, existed, p)
+	})
+	return &p, nil
+}
+
+var _ repository.PaymentRepository = (*PaymentRepoMemory)(nil)
@@ -3,23 +3,39 @@ package memory
 import (
 	"bookingapp/internal/domain/entity"
 	"bookingapp/internal/domain/repository"
-	"strings"
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 )
 
 type PlanRepoMemory struct {
-	data map[int]*entity.Plan
+	mu       sync.RWMutex
+	data     map[int]*entity.Plan
+	reserved map[int]map[string]int  // planID -> 宿泊日(yyyy-mm-dd) -> 確保済み室数
+	rates    map[int]entity.RateRule // ruleID -> 料金ルール
 }
 
 func NewPlanRepoMemory(seed []*entity.Plan) repository.PlanRepository {
-	m := &PlanRepoMemory{data: map[int]*entity.Plan{}}
+	m := &PlanRepoMemory{
+		data:     map[int]*entity.Plan{},
+		reserved: map[int]map[string]int{},
+		rates:    map[int]entity.RateRule{},
+	}
 	for _, p := range seed {
 		cp := *p
+		defaultGuests(&cp)
+		cp.Version = max(cp.Version, 1)
 		m.data[p.ID] = &cp
 	}
 	return m
 }
 
-func (m *PlanRepoMemory) FindByID(id int) (*entity.Plan, error) {
+func (m *PlanRepoMemory) FindByID(ctx context.Context, id int) (*entity.Plan, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	if p, ok := m.data[id]; ok {
 		cp := *p
 		return &cp, nil
@@ -27,22 +43,298 @@ func (m *PlanRepoMemory) FindByID(id int) (*entity.Plan, error) {
 	return nil, nil
 }
 
-func (m *PlanRepoMemory) SearchByKeyword(keyword string) ([]*entity.Plan, error) {
-	if keyword == "" {
-		out := make([]*entity.Plan, 0, len(m.data))
-		for _, p := range m.data {
-			cp := *p
-			out = append(out, &cp)
-		}
-		return out, nil
+func (m *PlanRepoMemory) Search(ctx context.Context, spec entity.PlanSpec) ([]*entity.Plan, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var days []string
+	if spec.Checkin != nil && spec.Checkout != nil {
+		days = stayKeys(*spec.Checkin, *spec.Checkout)
 	}
-	kw := strings.ToLower(keyword)
-	var out []*entity.Plan
+	out := make([]*entity.Plan, 0, len(m.data))
 	for _, p := range m.data {
-		if strings.Contains(strings.ToLower(p.Name), kw) || strings.Contains(strings.ToLower(p.Keyword), kw) {
-			cp := *p
-			out = append(out, &cp)
+		if !spec.Matches(p) || !m.availableLocked(p, days) {
+			continue
+		}
+		cp := *p
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return spec.Less(out[i], out[j]) })
+	if spec.Offset > 0 {
+		out = out[min(spec.Offset, len(out)):]
+	}
+	if spec.Limit > 0 && len(out) > spec.Limit {
+		out = out[:spec.Limit]
+	}
+	return out, nil
+}
+
+// days の全泊に1室以上の空きがあるか
+func (m *PlanRepoMemory) availableLocked(p *entity.Plan, days []string) bool {
+	for _, d := range days {
+		if m.reserved[p.ID][d] >= p.Capacity {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *PlanRepoMemory) Save(ctx context.Context, plan *entity.Plan) (*entity.Plan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *plan
+	defaultGuests(&cp)
+	if cp.ID == 0 {
+		for id := range m.data {
+			cp.ID = max(cp.ID, id)
+		}
+		cp.ID++
+		cp.DeletedAt = nil
+		cp.Version = 1
+	} else {
+		cur, ok := m.data[cp.ID]
+		if !ok {
+			return nil, repository.ErrPlanNotFound
+		}
+		if cur.Version != cp.Version {
+			return nil, repository.ErrConcurrentModification
+		}
+		// MySQL 実装と同じく削除状態は Save では変えない
+		cp.DeletedAt = cur.DeletedAt
+		cp.Version++
+	}
+	m.putLocked(ctx, &cp)
+	out := cp
+	return &out, nil
+}
+
+// プランを差し替え、ロールバックでは差し替える前に戻す（m.mu を取った状態で呼ぶ）
+func (m *PlanRepoMemory) putLocked(ctx context.Context, p *entity.Plan) {
+	prev, existed := m.data[p.ID]
+	m.data[p.ID] = p
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		undoPut(m.data, p.ID, prev, existed, p)
+	})
+}
+
+func (m *PlanRepoMemory) Delete(ctx context.Context, id, version int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.data[id]; ok && !p.Deleted() {
+		if p.Version != version {
+			return repository.ErrConcurrentModification
+		}
+		cp := *p
+		cp.DeletedAt = &at
+		cp.Version++
+		m.putLocked(ctx, &cp)
+	}
+	return nil
+}
+
+// 全泊の空きを確認してから一括で確保する（ロック内なので途中で割り込まれない）
+func (m *PlanRepoMemory) ReserveNights(ctx context.Context, planID int, checkin, checkout time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	days := stayKeys(checkin, checkout)
+	if err := m.reserveLocked(planID, days); err != nil {
+		return err
+	}
+	// ロールバックでは確保した分だけを戻す（他の予約の確保・解放は残す）
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.releaseLocked(planID, days)
+	})
+	return nil
+}
+
+func (m *PlanRepoMemory) ReleaseNights(ctx context.Context, planID int, checkin, checkout time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	released := m.releaseLocked(planID, stayKeys(checkin, checkout))
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.restoreLocked(planID, released)
+	})
+	return nil
+}
+
+func (m *PlanRepoMemory) RebookNights(ctx context.Context, planID int, oldCheckin, oldCheckout, newCheckin, newCheckout time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	released := m.releaseLocked(planID, stayKeys(oldCheckin, oldCheckout))
+	days := stayKeys(newCheckin, newCheckout)
+	if err := m.reserveLocked(planID, days); err != nil {
+		// 新日程が取れなければ旧日程の確保状態に戻す
+		m.restoreLocked(planID, released)
+		return err
+	}
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.releaseLocked(planID, days)
+		m.restoreLocked(planID, released)
+	})
+	return nil
+}
+
+func (m *PlanRepoMemory) reserveLocked(planID int, days []string) error {
+	p, ok := m.data[planID]
+	if !ok {
+		return repository.ErrPlanNotFound
+	}
+	used := m.reserved[planID]
+	for _, d := range days {
+		if used[d] >= p.Capacity {
+			return repository.ErrSoldOut
+		}
+	}
+	if used == nil {
+		used = map[string]int{}
+		m.reserved[planID] = used
+	}
+	for _, d := range days {
+		used[d]++
+	}
+	return nil
+}
+
+// 実際に戻した日だけを返す
+func (m *PlanRepoMemory) releaseLocked(planID int, days []string) []string {
+	used := m.reserved[planID]
+	var released []string
+	for _, d := range days {
+		if used[d] > 0 {
+			used[d]--
+			released = append(released, d)
+		}
+	}
+	return released
+}
+
+// releaseLocked で戻した日を確保し直す
+func (m *PlanRepoMemory) restoreLocked(planID int, days []string) {
+	if len(days) == 0 {
+		return
+	}
+	used := m.reserved[planID]
+	if used == nil {
+		used = map[string]int{}
+		m.reserved[planID] = used
+	}
+	for _, d := range days {
+		used[d]++
+	}
+}
+
+func (m *PlanRepoMemory) Availability(ctx context.Context, planID int, from, to time.Time) ([]entity.NightAvailability, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.data[planID]
+	if !ok {
+		return nil, repository.ErrPlanNotFound
+	}
+	dates := entity.StayDates(from, to)
+	out := make([]entity.NightAvailability, 0, len(dates))
+	for _, d := range dates {
+		remaining := p.Capacity - m.reserved[planID][d.Format("2006-01-02")]
+		if remaining < 0 {
+			remaining = 0
+		}
+		out = append(out, entity.NightAvailability{Date: d, Remaining: remaining})
+	}
+	return out, nil
+}
+
+func (m *PlanRepoMemory) RateRules(ctx context.Context, planID int) ([]entity.RateRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := []entity.RateRule{}
+	for _, r := range m.rates {
+		if r.PlanID == planID {
+			out = append(out, cloneRateRule(r))
 		}
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
 	return out, nil
 }
+
+func (m *PlanRepoMemory) SaveRateRule(ctx context.Context, rule *entity.RateRule) (*entity.RateRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := cloneRateRule(*rule)
+	if cp.ID == 0 {
+		for id := range m.rates {
+			cp.ID = max(cp.ID, id)
+		}
+		cp.ID++
+	} else if _, ok := m.rates[cp.ID]; !ok {
+		return nil, errors.New("rate rule not found")
+	}
+	prev, existed := m.rates[cp.ID]
+	m.rates[cp.ID] = cp
+	// 料金ルールは比較できないので、ロールバックではこのルールだけを書き込む前に戻す
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if existed {
+			m.rates[cp.ID] = prev
+		} else {
+			delete(m.rates, cp.ID)
+		}
+	})
+	out := cloneRateRule(cp)
+	return &out, nil
+}
+
+func (m *PlanRepoMemory) DeleteRateRule(ctx context.Context, planID, ruleID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.rates[ruleID]; ok && r.PlanID == planID {
+		delete(m.rates, ruleID)
+		onRollback(ctx, func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if _, ok := m.rates[ruleID]; !ok {
+				m.rates[ruleID] = r
+			}
+		})
+	}
+	return nil
+}
+
+// 人数の範囲が未指定なら既定値にする（MySQL のカラムの既定値と同じ）
+func defaultGuests(p *entity.Plan) {
+	if p.MinGuests == 0 {
+		p.MinGuests = 1
+	}
+	if p.MaxGuests == 0 {
+		p.MaxGuests = entity.DefaultMaxGuests
+	}
+}
+
+// 呼び出し側に内部の状態を書き換えられないよう、スライスとポインタを複製する
+func cloneRateRule(r entity.RateRule) entity.RateRule {
+	r.Days = slices.Clone(r.Days)
+	if r.StartDate != nil {
+		d := entity.DateOf(*r.StartDate)
+		r.StartDate = &d
+	}
+	if r.EndDate != nil {
+		d := entity.DateOf(*r.EndDate)
+		r.EndDate = &d
+	}
+	return r
+}
+
+func stayKeys(checkin, checkout time.Time) []string {
+	dates := entity.StayDates(checkin, checkout)
+	out := make([]string, 0, len(dates))
+	for _, d := range dates {
+		out = append(out, d.Format("2006-01-02"))
+	}
+	return out
+}
@@ -3,7 +3,11 @@ package memory
 import (
 	"bookingapp/internal/domain/entity"
 	"bookingapp/internal/domain/repository"
+	"context"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 )
 
 type ReservationRepoMemory struct {
@@ -19,40 +23,109 @@ func NewReservationRepoMemory() repository.ReservationRepository {
 	}
 }
 
-func (r *ReservationRepoMemory) NextID() int {
+func (r *ReservationRepoMemory) Save(ctx context.Context, res *entity.Reservation) (*entity.Reservation, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	id := r.next
-	r.next++
-	return id
-}
-
-func (r *ReservationRepoMemory) Save(res *entity.Reservation) (*entity.Reservation, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	cp := *res
-	r.data[cp.ID] = &cp
-	out := cp
-	return &out, nil
+	// MySQL の AUTO_INCREMENT と同じく、ID が 0 なら採番する
+	if res.ID == 0 {
+		res.ID = r.next
+		r.next++
+		res.Version = 1
+	} else {
+		cur, ok := r.data[res.ID]
+		if !ok || cur.Version != res.Version {
+			return nil, repository.ErrConcurrentModification
+		}
+		res.Version++
+	}
+	cp := copyReservation(res)
+	prev, existed := r.data[cp.ID]
+	r.data[cp.ID] = cp
+	// 採番した ID は AUTO_INCREMENT と同じくロールバックしても戻さない
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		undoPut(r.data, cp.ID, prev, existed, cp)
+	})
+	return copyReservation(cp), nil
 }
 
-func (r *ReservationRepoMemory) FindByID(id int) (*entity.Reservation, error) {
+func (r *ReservationRepoMemory) FindByID(ctx context.Context, id int) (*entity.Reservation, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	if v, ok := r.data[id]; ok {
-		cp := *v
-		return &cp, nil
+		return copyReservation(v), nil
 	}
 	return nil, nil
 }
 
-func (r *ReservationRepoMemory) List() ([]*entity.Reservation, error) {
+// 内訳とキャンセルポリシーも複製する（呼び出し側の変更が保存内容に及ばないように）
+func copyReservation(v *entity.Reservation) *entity.Reservation {
+	cp := *v
+	cp.Breakdown = slices.Clone(v.Breakdown)
+	if v.CancellationPolicy != nil {
+		cp.CancellationPolicy = v.CancellationPolicy.Snapshot()
+	}
+	return &cp
+}
+
+// MySQL 実装と同じ条件・並び順で返す（全件を走査する）
+func (r *ReservationRepoMemory) List(ctx context.Context, q repository.ReservationQuery) ([]*entity.Reservation, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	less := reservationLess(q.Sort)
 	out := make([]*entity.Reservation, 0, len(r.data))
 	for _, v := range r.data {
-		cp := *v
-		out = append(out, &cp)
+		if !matchReservation(v, q) {
+			continue
+		}
+		// カーソル位置以前のものは除く
+		if q.After != nil && !less(q.After.ID, entity.DateOf(q.After.Checkin), v.ID, entity.DateOf(v.Checkin)) {
+			continue
+		}
+		out = append(out, copyReservation(v))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return less(out[i].ID, entity.DateOf(out[i].Checkin), out[j].ID, entity.DateOf(out[j].Checkin))
+	})
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
 	}
 	return out, nil
 }
+
+func matchReservation(v *entity.Reservation, q repository.ReservationQuery) bool {
+	switch {
+	case q.UserID != "" && v.UserID != q.UserID,
+		q.PlanID != 0 && v.PlanID != q.PlanID,
+		q.Status != "" && v.Status != q.Status,
+		q.CheckinFrom != nil && entity.DateOf(v.Checkin).Before(entity.DateOf(*q.CheckinFrom)),
+		q.CheckinTo != nil && entity.DateOf(v.Checkin).After(entity.DateOf(*q.CheckinTo)):
+		return false
+	}
+	return true
+}
+
+// (id, checkin) の a が b より前に並ぶか
+func reservationLess(s repository.ReservationSort) func(aID int, aIn time.Time, bID int, bIn time.Time) bool {
+	switch s {
+	case repository.SortByIDDesc:
+		return func(aID int, _ time.Time, bID int, _ time.Time) bool { return aID > bID }
+	case repository.SortByCheckinAsc:
+		return func(aID int, aIn time.Time, bID int, bIn time.Time) bool {
+			if !aIn.Equal(bIn) {
+				return aIn.Before(bIn)
+			}
+			return aID < bID
+		}
+	case repository.SortByCheckinDesc:
+		return func(aID int, aIn time.Time, bID int, bIn time.Time) bool {
+			if !aIn.Equal(bIn) {
+				return aIn.After(bIn)
+			}
+			return aID > bID
+		}
+	default:
+		return func(aID int, _ time.Time, bID int, _ time.Time) bool { return aID < bID }
+	}
+}
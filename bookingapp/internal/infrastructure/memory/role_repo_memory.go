@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"slices"
+)
+
+// ロール定義は entity.DefaultRolePermissions で固定（実行中には変わらない）
+type RoleRepoMemory struct {
+	perms map[entity.Role][]entity.Permission
+}
+
+func NewRoleRepoMemory() repository.RoleRepository {
+	return &RoleRepoMemory{perms: entity.DefaultRolePermissions}
+}
+
+func (r *RoleRepoMemory) Permissions(ctx context.Context, role entity.Role) ([]entity.Permission, error) {
+	perms, ok := r.perms[role]
+	if !ok {
+		return nil, repository.ErrRoleNotFound
+	}
+	out := slices.Clone(perms)
+	slices.Sort(out)
+	return out, nil
+}
+
+var _ repository.RoleRepository = (*RoleRepoMemory)(nil)
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type SessionRepoMemory struct {
+	mu   sync.RWMutex
+	data map[string]*entity.Session
+}
+
+func NewSessionRepoMemory() repository.SessionRepository {
+	return &SessionRepoMemory{data: map[string]*entity.Session{}}
+}
+
+func (r *SessionRepoMemory) Create(ctx context.Context, s *entity.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.data[s.ID]; ok {
+		return fmt.Errorf("session %s already exists", s.ID)
+	}
+	cp := *s
+	r.data[cp.ID] = &cp
+	return nil
+}
+
+func (r *SessionRepoMemory) Get(ctx context.Context, id string) (*entity.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if s, ok := r.data[id]; ok {
+		cp := *s
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *SessionRepoMemory) FindByRefreshTokenHash(ctx context.Context, hash string) (*entity.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.data {
+		if s.RefreshTokenHash == hash {
+			cp := *s
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *SessionRepoMemory) Revoke(ctx context.Context, id string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.data[id]
+	if !ok || s.RevokedAt != nil {
+		return false, nil
+	}
+	s.RevokedAt = &at
+	return true, nil
+}
+
+var _ repository.SessionRepository = (*SessionRepoMemory)(nil)
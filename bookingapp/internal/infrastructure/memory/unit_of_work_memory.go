@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/repository"
+	"context"
+	"sync"
+)
+
+// UnitOfWork 内で行った書き込みの取り消し方の記録。
+// 各リポジトリは書き込むたびに自分が変えたキーだけを戻す関数を積むので、
+// ロールバックしても同じ時期に UnitOfWork の外で行われた書き込みは消えない
+type undoLog struct {
+	mu    sync.Mutex
+	undos []func()
+}
+
+type undoLogKey struct{}
+
+// ctx が UnitOfWork.Do の中なら、ロールバックで呼ぶ undo を記録する（外なら何もしない）。
+// undo は書き込みと逆の順に、リポジトリのロックを取らずに呼ばれる
+func onRollback(ctx context.Context, undo func()) {
+	if l, ok := ctx.Value(undoLogKey{}).(*undoLog); ok {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.undos = append(l.undos, undo)
+	}
+}
+
+func (l *undoLog) rollback() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.undos) - 1; i >= 0; i-- {
+		l.undos[i]()
+	}
+	l.undos = nil
+}
+
+// data[key] が written のままなら書き込む前の状態（existed が false なら無し）に戻す。
+// その後に別の書き込みで変わっていればそちらを残す
+func undoPut[K, V comparable](data map[K]V, key K, prev V, existed bool, written V) {
+	if cur, ok := data[key]; !ok || cur != written {
+		return
+	}
+	if existed {
+		data[key] = prev
+	} else {
+		delete(data, key)
+	}
+}
+
+// インメモリ版の UnitOfWork。
+// Do を直列化し、fn がエラーを返したら fn 内で行った書き込みだけを取り消す。
+// 取り消しは fn に渡した ctx で記録するので、fn 内ではその ctx を使ってリポジトリを呼ぶ
+type UnitOfWorkMemory struct {
+	mu    sync.Mutex
+	repos repository.Repositories
+}
+
+func NewUnitOfWork(plans repository.PlanRepository, reservations repository.ReservationRepository, users repository.UserRepository, coupons repository.CouponRepository, payments repository.PaymentRepository) repository.UnitOfWork {
+	return &UnitOfWorkMemory{repos: repository.Repositories{
+		Plans:        plans,
+		Reservations: reservations,
+		Users:        users,
+		Coupons:      coupons,
+		Payments:     payments,
+	}}
+}
+
+func (u *UnitOfWorkMemory) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	undo := &undoLog{}
+	ctx = context.WithValue(ctx, undoLogKey{}, undo)
+	defer func() {
+		if p := recover(); p != nil {
+			undo.rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx, u.repos); err != nil {
+		undo.rollback()
+		return err
+	}
+	return nil
+}
+
+var _ repository.UnitOfWork = (*UnitOfWorkMemory)(nil)
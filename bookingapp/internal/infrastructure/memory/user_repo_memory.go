@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type UserRepoMemory struct {
+	mu      sync.RWMutex
+	data    map[string]*entity.User // id -> user
+	byEmail map[string]string       // email -> id（一意制約の代わり）
+}
+
+func NewUserRepoMemory() repository.UserRepository {
+	return &UserRepoMemory{
+		data:    map[string]*entity.User{},
+		byEmail: map[string]string{},
+	}
+}
+
+func (r *UserRepoMemory) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
+	if user == nil {
+		return nil, errors.New("user is nil")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email := strings.TrimSpace(user.Email)
+	if _, ok := r.byEmail[email]; ok {
+		return nil, repository.ErrDuplicateEmail
+	}
+	if user.ID == "" {
+		user.ID = uuid.NewString()
+	}
+	if len(user.Roles) == 0 {
+		user.Roles = []entity.Role{entity.RoleGuest}
+	}
+	if _, ok := r.data[user.ID]; ok {
+		return nil, fmt.Errorf("user %s already exists", user.ID)
+	}
+	user.Version = 1
+	cp := cloneUser(user)
+	slices.Sort(cp.Roles)
+	r.data[cp.ID] = cp
+	r.byEmail[email] = cp.ID
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		undoPut(r.data, cp.ID, nil, false, cp)
+		undoPut(r.byEmail, email, "", false, cp.ID)
+	})
+	return user, nil
+}
+
+func (r *UserRepoMemory) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	if strings.TrimSpace(email) == "" {
+		return nil, nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.byEmail[strings.TrimSpace(email)]
+	if !ok {
+		return nil, nil
+	}
+	return cloneUser(r.data[id]), nil
+}
+
+func (r *UserRepoMemory) Get(ctx context.Context, id string) (*entity.User, error) {
+	if strings.TrimSpace(id) == "" {
+		return nil, fmt.Errorf("id is empty")
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if u, ok := r.data[strings.TrimSpace(id)]; ok {
+		return cloneUser(u), nil
+	}
+	return nil, nil
+}
+
+func (r *UserRepoMemory) GrantRole(ctx context.Context, userID string, role entity.Role, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.updateLocked(ctx, userID, version, func(u *entity.User) {
+		if !slices.Contains(u.Roles, role) {
+			u.Roles = append(u.Roles, role)
+			slices.Sort(u.Roles)
+		}
+	})
+}
+
+func (r *UserRepoMemory) RevokeRole(ctx context.Context, userID string, role entity.Role, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.updateLocked(ctx, userID, version, func(u *entity.User) {
+		u.Roles = slices.DeleteFunc(u.Roles, func(x entity.Role) bool { return x == role })
+	})
+}
+
+// バージョンを確認し、update を適用してバージョンを上げた複製に差し替える（r.mu を取った状態で呼ぶ）
+func (r *UserRepoMemory) updateLocked(ctx context.Context, userID string, version int, update func(u *entity.User)) error {
+	prev, ok := r.data[userID]
+	if !ok {
+		return fmt.Errorf("user %s not found", userID)
+	}
+	if version != 0 && prev.Version != version {
+		return repository.ErrConcurrentModification
+	}
+	u := cloneUser(prev)
+	update(u)
+	u.Version++
+	r.data[userID] = u
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		undoPut(r.data, userID, prev, true, u)
+	})
+	return nil
+}
+
+func cloneUser(u *entity.User) *entity.User {
+	cp := *u
+	cp.Roles = slices.Clone(u.Roles)
+	return &cp
+}
+
+var _ repository.UserRepository = (*UserRepoMemory)(nil)
@@ -0,0 +1,155 @@
+package payment
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/gateway"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// この支払いトークンで与信すると必ず断られる（Fail の設定によらない）
+const DeclineToken = "tok_decline"
+
+// 決済代行を使わずに動かすための設定
+type FakeConfig struct {
+	// 各操作の前に待つ時間（タイムアウトの確認用）
+	Delay time.Duration
+	// true の操作は失敗させる。与信は ErrPaymentDeclined、それ以外は ErrFakeFailure
+	FailAuthorize bool
+	FailCapture   bool
+	FailRefund    bool
+	FailVoid      bool
+}
+
+// FakeConfig で失敗させた操作のエラー
+var ErrFakeFailure = errors.New("fake payment gateway failure")
+
+type fakeTransaction struct {
+	authorized entity.Money
+	captured   entity.Money
+	refunded   entity.Money
+	voided     bool
+}
+
+// プロセス内で与信・請求・返金を記録するだけの決済代行。
+// 金額や状態の整合（与信額を超える請求など）は本物と同じく検証する
+type FakeGateway struct {
+	cfg FakeConfig
+
+	mu   sync.Mutex
+	txs  map[string]*fakeTransaction
+	next int
+}
+
+func NewFakeGateway(cfg FakeConfig) gateway.PaymentGateway {
+	return &FakeGateway{cfg: cfg, txs: map[string]*fakeTransaction{}}
+}
+
+func (g *FakeGateway) Authorize(ctx context.Context, req gateway.AuthorizeRequest) (string, error) {
+	if err := g.wait(ctx); err != nil {
+		return "", err
+	}
+	if g.cfg.FailAuthorize || req.Token == DeclineToken {
+		return "", fmt.Errorf("%w: card declined by fake gateway", gateway.ErrPaymentDeclined)
+	}
+	if !req.Amount.IsPositive() {
+		return "", fmt.Errorf("%w: amount must be > 0", gateway.ErrInvalidPaymentOperation)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	ref := fmt.Sprintf("fake_%d_%d", req.ReservationID, g.next)
+	zero := entity.NewMoney(0, req.Amount.Currency)
+	g.txs[ref] = &fakeTransaction{authorized: req.Amount, captured: zero, refunded: zero}
+	return ref, nil
+}
+
+func (g *FakeGateway) Capture(ctx context.Context, ref string, amount entity.Money) error {
+	if err := g.wait(ctx); err != nil {
+		return err
+	}
+	if g.cfg.FailCapture {
+		return ErrFakeFailure
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tx, err := g.find(ref, amount)
+	if err != nil {
+		return err
+	}
+	if tx.voided || tx.captured.IsPositive() || amount.Amount > tx.authorized.Amount || amount.Amount < 0 {
+		return fmt.Errorf("%w: cannot capture %s of %s", gateway.ErrInvalidPaymentOperation, amount, tx.authorized)
+	}
+	tx.captured = amount
+	return nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, ref string, amount entity.Money) error {
+	if err := g.wait(ctx); err != nil {
+		return err
+	}
+	if g.cfg.FailRefund {
+		return ErrFakeFailure
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tx, err := g.find(ref, amount)
+	if err != nil {
+		return err
+	}
+	if amount.Amount < 0 || tx.refunded.Amount+amount.Amount > tx.captured.Amount {
+		return fmt.Errorf("%w: cannot refund %s of %s", gateway.ErrInvalidPaymentOperation, amount, tx.captured)
+	}
+	tx.refunded.Amount += amount.Amount
+	return nil
+}
+
+func (g *FakeGateway) Void(ctx context.Context, ref string) error {
+	if err := g.wait(ctx); err != nil {
+		return err
+	}
+	if g.cfg.FailVoid {
+		return ErrFakeFailure
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tx, ok := g.txs[ref]
+	if !ok {
+		return fmt.Errorf("%w: unknown transaction %q", gateway.ErrInvalidPaymentOperation, ref)
+	}
+	if tx.captured.IsPositive() {
+		return fmt.Errorf("%w: already captured", gateway.ErrInvalidPaymentOperation)
+	}
+	tx.voided = true
+	return nil
+}
+
+func (g *FakeGateway) find(ref string, amount entity.Money) (*fakeTransaction, error) {
+	tx, ok := g.txs[ref]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown transaction %q", gateway.ErrInvalidPaymentOperation, ref)
+	}
+	if amount.Currency != tx.authorized.Currency {
+		return nil, fmt.Errorf("%w: %s for a %s transaction", entity.ErrCurrencyMismatch, amount.Currency, tx.authorized.Currency)
+	}
+	return tx, nil
+}
+
+func (g *FakeGateway) wait(ctx context.Context) error {
+	if g.cfg.Delay <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(g.cfg.Delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var _ gateway.PaymentGateway = (*FakeGateway)(nil)
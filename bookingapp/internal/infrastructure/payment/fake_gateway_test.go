@@ -0,0 +1,71 @@
+package payment
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/gateway"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeGatewayLifecycle(t *testing.T) {
+	ctx := context.Background()
+	g := NewFakeGateway(FakeConfig{})
+
+	ref, err := g.Authorize(ctx, gateway.AuthorizeRequest{ReservationID: 1, Amount: entity.Yen(10000), Token: "tok_visa"})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if err := g.Capture(ctx, ref, entity.Yen(12000)); !errors.Is(err, gateway.ErrInvalidPaymentOperation) {
+		t.Fatalf("Capture(over authorized) = %v, want ErrInvalidPaymentOperation", err)
+	}
+	if err := g.Capture(ctx, ref, entity.Yen(10000)); err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if err := g.Void(ctx, ref); !errors.Is(err, gateway.ErrInvalidPaymentOperation) {
+		t.Fatalf("Void(captured) = %v, want ErrInvalidPaymentOperation", err)
+	}
+	if err := g.Refund(ctx, ref, entity.Yen(4000)); err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if err := g.Refund(ctx, ref, entity.Yen(7000)); !errors.Is(err, gateway.ErrInvalidPaymentOperation) {
+		t.Fatalf("Refund(over captured) = %v, want ErrInvalidPaymentOperation", err)
+	}
+	if err := g.Refund(ctx, ref, entity.Yen(6000)); err != nil {
+		t.Fatalf("Refund(rest): %v", err)
+	}
+}
+
+func TestFakeGatewayFailures(t *testing.T) {
+	ctx := context.Background()
+	req := gateway.AuthorizeRequest{ReservationID: 1, Amount: entity.Yen(10000)}
+
+	declined := req
+	declined.Token = DeclineToken
+	if _, err := NewFakeGateway(FakeConfig{}).Authorize(ctx, declined); !errors.Is(err, gateway.ErrPaymentDeclined) {
+		t.Fatalf("Authorize(%s) = %v, want ErrPaymentDeclined", DeclineToken, err)
+	}
+	if _, err := NewFakeGateway(FakeConfig{FailAuthorize: true}).Authorize(ctx, req); !errors.Is(err, gateway.ErrPaymentDeclined) {
+		t.Fatalf("Authorize(FailAuthorize) = %v, want ErrPaymentDeclined", err)
+	}
+
+	g := NewFakeGateway(FakeConfig{FailVoid: true})
+	ref, err := g.Authorize(ctx, req)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if err := g.Void(ctx, ref); !errors.Is(err, ErrFakeFailure) {
+		t.Fatalf("Void(FailVoid) = %v, want ErrFakeFailure", err)
+	}
+}
+
+func TestFakeGatewayDelayHonorsContext(t *testing.T) {
+	g := NewFakeGateway(FakeConfig{Delay: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := g.Authorize(ctx, gateway.AuthorizeRequest{ReservationID: 1, Amount: entity.Yen(10000)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Authorize = %v, want context.DeadlineExceeded", err)
+	}
+}
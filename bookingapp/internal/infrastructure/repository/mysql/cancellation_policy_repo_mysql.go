@@ -0,0 +1,98 @@
+package mysqlrepo
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/infrastructure/db/models"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type CancellationPolicyRepo struct {
+	db *gorm.DB
+}
+
+func NewCancellationPolicyRepo(db *gorm.DB) repository.CancellationPolicyRepository {
+	return &CancellationPolicyRepo{db: db}
+}
+
+func (r *CancellationPolicyRepo) FindByID(ctx context.Context, id int) (*entity.CancellationPolicy, error) {
+	var m models.CancellationPolicyModel
+	if err := r.db.WithContext(ctx).First(&m, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cancellationPolicyToEntity(&m), nil
+}
+
+func (r *CancellationPolicyRepo) List(ctx context.Context) ([]*entity.CancellationPolicy, error) {
+	var list []models.CancellationPolicyModel
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*entity.CancellationPolicy, 0, len(list))
+	for i := range list {
+		out = append(out, cancellationPolicyToEntity(&list[i]))
+	}
+	return out, nil
+}
+
+func (r *CancellationPolicyRepo) Save(ctx context.Context, policy *entity.CancellationPolicy) (*entity.CancellationPolicy, error) {
+	m := models.CancellationPolicyModel{ID: policy.ID, Name: policy.Name, Rules: formatCancellationRules(policy.Rules)}
+	db := r.db.WithContext(ctx)
+	var err error
+	if m.ID == 0 {
+		err = db.Create(&m).Error
+	} else {
+		err = db.Model(&models.CancellationPolicyModel{ID: m.ID}).Select("name", "rules").Updates(&m).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	saved, err := r.FindByID(ctx, m.ID)
+	if err == nil && saved == nil {
+		return nil, errors.New("cancellation policy not found")
+	}
+	return saved, err
+}
+
+func cancellationPolicyToEntity(m *models.CancellationPolicyModel) *entity.CancellationPolicy {
+	return &entity.CancellationPolicy{ID: m.ID, Name: m.Name, Rules: parseCancellationRules(m.Rules)}
+}
+
+// "日前:料率" のカンマ区切り（cancellation_policies.rules と reservations.cancellation_rules で共通）
+func formatCancellationRules(rules []entity.CancellationRule) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		parts = append(parts, fmt.Sprintf("%d:%d", r.DaysBefore, r.FeePercent))
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseCancellationRules(s string) []entity.CancellationRule {
+	var out []entity.CancellationRule
+	if s == "" {
+		return out
+	}
+	for _, part := range strings.Split(s, ",") {
+		days, percent, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		d, err1 := strconv.Atoi(days)
+		p, err2 := strconv.Atoi(percent)
+		if err1 == nil && err2 == nil {
+			out = append(out, entity.CancellationRule{DaysBefore: d, FeePercent: p})
+		}
+	}
+	return out
+}
+
+var _ repository.CancellationPolicyRepository = (*CancellationPolicyRepo)(nil)
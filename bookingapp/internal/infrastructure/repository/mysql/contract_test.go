@@ -0,0 +1,75 @@
+package mysqlrepo
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/domain/repository/repositorytest"
+	"bookingapp/internal/infrastructure/db"
+	"bookingapp/internal/infrastructure/db/models"
+	userrepo "bookingapp/internal/infrastructure/repository/mysql/user"
+	"context"
+	"os"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// テーブルを空にして使うので、専用のデータベースを DB_* で指定したうえで TEST_MYSQL=1 を付けて実行する
+//
+//	TEST_MYSQL=1 DB_NAME=booking_test go test ./internal/infrastructure/repository/mysql/
+func TestRepositoryContract(t *testing.T) {
+	if os.Getenv("TEST_MYSQL") != "1" {
+		t.Skip("set TEST_MYSQL=1 to run against MySQL")
+	}
+	gdb, err := db.Open(db.ConfigFromEnv())
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	gdb.Logger = logger.Discard
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	m, err := db.NewMigrator(sqlDB)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	repositorytest.Run(t, func(t *testing.T, plans []*entity.Plan) repositorytest.Backend {
+		resetTables(t, gdb, plans)
+		return repositorytest.Backend{
+			Repositories: repository.Repositories{
+				Plans:        NewPlanRepo(gdb),
+				Reservations: NewReservationRepo(gdb),
+				Users:        userrepo.NewUserRepo(gdb),
+				Coupons:      NewCouponRepo(gdb),
+				Payments:     NewPaymentRepo(gdb),
+			},
+			Roles:                userrepo.NewRoleRepo(gdb),
+			Sessions:             NewSessionRepo(gdb),
+			CancellationPolicies: NewCancellationPolicyRepo(gdb),
+			Idempotency:          NewIdempotencyRepo(gdb),
+			Tx:                   NewUnitOfWork(gdb),
+		}
+	})
+}
+
+func resetTables(t *testing.T, gdb *gorm.DB, plans []*entity.Plan) {
+	t.Helper()
+	for _, table := range []string{"idempotency_keys", "cancellation_policies", "payments", "coupon_redemptions", "coupons", "reservation_nights", "reservations", "plan_inventories", "plan_rate_rules", "plans", "sessions", "user_roles", "users"} {
+		if err := gdb.Exec("DELETE FROM " + table).Error; err != nil {
+			t.Fatalf("reset %s: %v", table, err)
+		}
+	}
+	for _, p := range plans {
+		m := models.PlanModel{ID: p.ID, Name: p.Name, Keyword: p.Keyword, Price: p.Price.Amount, Currency: string(p.Price.Currency), Capacity: p.Capacity,
+			MinGuests: p.MinGuests, MaxGuests: p.MaxGuests, ChildRate: p.ChildRate, InfantRate: p.InfantRate}
+		if err := gdb.Create(&m).Error; err != nil {
+			t.Fatalf("seed plan %d: %v", p.ID, err)
+		}
+	}
+}
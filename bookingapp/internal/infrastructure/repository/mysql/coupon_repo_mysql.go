@@ -0,0 +1,181 @@
+package mysqlrepo
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/infrastructure/db/models"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepo struct {
+	db *gorm.DB
+	// UnitOfWork のトランザクション内で使う場合 true（入れ子のトランザクションを張らない）
+	inTx bool
+}
+
+func NewCouponRepo(db *gorm.DB) repository.CouponRepository {
+	return &CouponRepo{db: db}
+}
+
+func (r *CouponRepo) FindByCode(ctx context.Context, code string) (*entity.Coupon, error) {
+	return r.find(ctx, "code = ?", code)
+}
+
+func (r *CouponRepo) FindByID(ctx context.Context, id int) (*entity.Coupon, error) {
+	return r.find(ctx, "id = ?", id)
+}
+
+func (r *CouponRepo) find(ctx context.Context, query string, arg any) (*entity.Coupon, error) {
+	var m models.CouponModel
+	if err := r.db.WithContext(ctx).Where(query, arg).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return couponToEntity(&m), nil
+}
+
+func (r *CouponRepo) List(ctx context.Context) ([]*entity.Coupon, error) {
+	var list []models.CouponModel
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*entity.Coupon, 0, len(list))
+	for i := range list {
+		out = append(out, couponToEntity(&list[i]))
+	}
+	return out, nil
+}
+
+func (r *CouponRepo) Save(ctx context.Context, coupon *entity.Coupon) (*entity.Coupon, error) {
+	ids := make([]string, 0, len(coupon.PlanIDs))
+	for _, id := range coupon.PlanIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	m := models.CouponModel{
+		ID:             coupon.ID,
+		Code:           coupon.Code,
+		Name:           coupon.Name,
+		Discount:       string(coupon.Discount),
+		Percent:        coupon.Percent,
+		Amount:         coupon.Amount.Amount,
+		Currency:       string(coupon.Amount.Currency),
+		StartsAt:       coupon.StartsAt,
+		EndsAt:         coupon.EndsAt,
+		PlanIDs:        strings.Join(ids, ","),
+		MaxRedemptions: coupon.MaxRedemptions,
+		PerUserLimit:   coupon.PerUserLimit,
+	}
+	if m.Currency == "" {
+		m.Currency = string(entity.DefaultCurrency)
+	}
+	db := r.db.WithContext(ctx)
+	var err error
+	if m.ID == 0 {
+		err = db.Create(&m).Error
+	} else {
+		// 利用数は Redeem / Release だけが変える
+		err = db.Model(&models.CouponModel{ID: m.ID}).
+			Select("code", "name", "discount", "percent", "amount", "currency", "starts_at", "ends_at",
+				"plan_ids", "max_redemptions", "per_user_limit").
+			Updates(&m).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, repository.ErrDuplicateCouponCode
+		}
+		return nil, err
+	}
+	saved, err := r.FindByID(ctx, m.ID)
+	if err == nil && saved == nil {
+		return nil, errors.New("coupon not found")
+	}
+	return saved, err
+}
+
+func (r *CouponRepo) Redeem(ctx context.Context, couponID int, userID string, reservationID int) error {
+	return r.transaction(ctx, func(tx *gorm.DB) error {
+		// クーポンの行をロックして、同じクーポンの利用を直列にする
+		var c models.CouponModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, couponID).Error; err != nil {
+			return err
+		}
+		if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+			return repository.ErrCouponExhausted
+		}
+		if c.PerUserLimit > 0 {
+			var used int64
+			if err := tx.Model(&models.CouponRedemptionModel{}).
+				Where("coupon_id = ? AND user_id = ?", couponID, userID).
+				Count(&used).Error; err != nil {
+				return err
+			}
+			if used >= int64(c.PerUserLimit) {
+				return repository.ErrCouponUserLimit
+			}
+		}
+		if err := tx.Create(&models.CouponRedemptionModel{ReservationID: reservationID, CouponID: couponID, UserID: userID}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.CouponModel{ID: couponID}).
+			UpdateColumn("redemptions", gorm.Expr("redemptions + 1")).Error
+	})
+}
+
+func (r *CouponRepo) Release(ctx context.Context, reservationID int) error {
+	return r.transaction(ctx, func(tx *gorm.DB) error {
+		var red models.CouponRedemptionModel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&red, "reservation_id = ?", reservationID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.CouponRedemptionModel{}, "reservation_id = ?", reservationID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.CouponModel{ID: red.CouponID}).
+			UpdateColumn("redemptions", gorm.Expr("redemptions - 1")).Error
+	})
+}
+
+func (r *CouponRepo) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if r.inTx {
+		return fn(r.db.WithContext(ctx))
+	}
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+func couponToEntity(m *models.CouponModel) *entity.Coupon {
+	c := &entity.Coupon{
+		ID:             m.ID,
+		Code:           m.Code,
+		Name:           m.Name,
+		Discount:       entity.CouponDiscount(m.Discount),
+		Percent:        m.Percent,
+		Amount:         entity.NewMoney(m.Amount, entity.Currency(m.Currency)),
+		StartsAt:       m.StartsAt,
+		EndsAt:         m.EndsAt,
+		MaxRedemptions: m.MaxRedemptions,
+		PerUserLimit:   m.PerUserLimit,
+		Redemptions:    m.Redemptions,
+	}
+	if m.PlanIDs != "" {
+		for _, s := range strings.Split(m.PlanIDs, ",") {
+			if id, err := strconv.Atoi(s); err == nil {
+				c.PlanIDs = append(c.PlanIDs, id)
+			}
+		}
+	}
+	return c
+}
+
+var _ repository.CouponRepository = (*CouponRepo)(nil)
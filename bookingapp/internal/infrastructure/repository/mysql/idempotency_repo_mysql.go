@@ -0,0 +1,70 @@
+package mysqlrepo
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/infrastructure/db/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepo struct{ db *gorm.DB }
+
+func NewIdempotencyRepo(db *gorm.DB) repository.IdempotencyRepository {
+	return &IdempotencyRepo{db: db}
+}
+
+// (user_id, idem_key) の一意制約で、同時に作られても 1 つだけが残る
+func (r *IdempotencyRepo) Create(ctx context.Context, rec *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	m := models.IdempotencyModel{
+		ID: rec.ID, UserID: rec.UserID, IdemKey: rec.Key, RequestHash: rec.RequestHash,
+		StatusCode: rec.StatusCode, ContentType: rec.ContentType, Body: rec.Body,
+		CreatedAt: rec.CreatedAt, ExpiresAt: rec.ExpiresAt,
+	}
+	err := r.db.WithContext(ctx).Create(&m).Error
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, err
+	}
+	var existing models.IdempotencyModel
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND idem_key = ?", rec.UserID, rec.Key).First(&existing).Error; err != nil {
+		// 重複した記録が直後に消された
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return r.Create(ctx, rec)
+		}
+		return nil, err
+	}
+	return &entity.IdempotencyRecord{
+		ID: existing.ID, UserID: existing.UserID, Key: existing.IdemKey, RequestHash: existing.RequestHash,
+		StatusCode: existing.StatusCode, ContentType: existing.ContentType, Body: existing.Body,
+		CreatedAt: existing.CreatedAt, ExpiresAt: existing.ExpiresAt,
+	}, nil
+}
+
+func (r *IdempotencyRepo) Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error {
+	res := r.db.WithContext(ctx).Model(&models.IdempotencyModel{ID: id}).
+		Updates(map[string]any{"status_code": statusCode, "content_type": contentType, "body": body})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("idempotency record not found")
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&models.IdempotencyModel{}, "id = ?", id).Error
+}
+
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context, at time.Time) (int, error) {
+	res := r.db.WithContext(ctx).Delete(&models.IdempotencyModel{}, "expires_at <= ?", at)
+	return int(res.RowsAffected), res.Error
+}
+
+var _ repository.IdempotencyRepository = (*IdempotencyRepo)(nil)
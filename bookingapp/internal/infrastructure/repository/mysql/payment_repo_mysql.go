@@ -0,0 +1,76 @@
+package mysqlrepo
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/infrastructure/db/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type PaymentRepo struct{ db *gorm.DB }
+
+func NewPaymentRepo(db *gorm.DB) repository.PaymentRepository {
+	return &PaymentRepo{db: db}
+}
+
+func (r *PaymentRepo) FindByReservation(ctx context.Context, reservationID int) (*entity.Payment, error) {
+	var m models.PaymentModel
+	if err := r.db.WithContext(ctx).Where("reservation_id = ?", reservationID).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return paymentToEntity(&m), nil
+}
+
+func (r *PaymentRepo) Save(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+	m := models.PaymentModel{
+		ID:            payment.ID,
+		ReservationID: payment.ReservationID,
+		Amount:        payment.Amount.Amount,
+		Captured:      payment.Captured.Amount,
+		Refunded:      payment.Refunded.Amount,
+		// 請求額・返金額の通貨は与信額と同じ
+		Currency:      string(payment.Amount.Currency),
+		Status:        string(payment.Status),
+		GatewayRef:    payment.GatewayRef,
+		FailureReason: payment.FailureReason,
+	}
+	db := r.db.WithContext(ctx)
+	if m.ID == 0 {
+		m.Version = 1
+		db = db.Create(&m)
+	} else {
+		// 読み込んだ時点のバージョンのときだけ全カラムを上書きする（ゼロ値の created_at で潰さないよう除外）
+		m.Version = payment.Version + 1
+		db = db.Model(&m).Where("version = ?", payment.Version).Select("*").Omit("id", "created_at").Updates(&m)
+	}
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	if db.RowsAffected == 0 {
+		return nil, repository.ErrConcurrentModification
+	}
+	return paymentToEntity(&m), nil
+}
+
+func paymentToEntity(m *models.PaymentModel) *entity.Payment {
+	c := entity.Currency(m.Currency)
+	return &entity.Payment{
+		ID:            m.ID,
+		ReservationID: m.ReservationID,
+		Amount:        entity.NewMoney(m.Amount, c),
+		Captured:      entity.NewMoney(m.Captured, c),
+		Refunded:      entity.NewMoney(m.Refunded, c),
+		Status:        entity.PaymentStatus(m.Status),
+		GatewayRef:    m.GatewayRef,
+		FailureReason: m.FailureReason,
+		Version:       m.Version,
+	}
+}
+
+var _ repository.PaymentRepository = (*PaymentRepo)(nil)
@@ -5,43 +5,315 @@ import (
 	"bookingapp/internal/domain/repository"
 	"bookingapp/internal/infrastructure/db/models"
 	"context"
+	"errors"
+	"math"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type PlanRepo struct{ db *gorm.DB }
+type PlanRepo struct {
+	db       *gorm.DB
+	lockRead bool // true なら FindByID で共有ロックを取る（UnitOfWork 内で使用）。在庫操作も入れ子のトランザクションを張らない
+}
 
 func NewPlanRepo(db *gorm.DB) repository.PlanRepository { return &PlanRepo{db: db} }
 
-func (r *PlanRepo) FindByID(id int) (*entity.Plan, error) {
+func (r *PlanRepo) FindByID(ctx context.Context, id int) (*entity.Plan, error) {
 	var m models.PlanModel
-	if err := r.db.WithContext(context.Background()).
-		First(&m, id).Error; err != nil {
+	q := r.db.WithContext(ctx)
+	if r.lockRead {
+		q = q.Clauses(clause.Locking{Strength: "SHARE"})
+	}
+	if err := q.First(&m, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &entity.Plan{ID: m.ID, Name: m.Name, Keyword: m.Keyword, Price: m.Price}, nil
+	return planToEntity(&m), nil
 }
 
-func (r *PlanRepo) SearchByKeyword(keyword string) ([]*entity.Plan, error) {
-	ctx := context.Background()
-	var list []models.PlanModel
+// entity.PlanSpec の Matches / Less と同じ条件・並び順を SQL で表す。
+// キーワードは strings.Contains と同じく文字どおりに比べるため、列の照合順序（アクセント・かなを区別しない）ではなく utf8mb4_bin で照合する
+func (r *PlanRepo) Search(ctx context.Context, spec entity.PlanSpec) ([]*entity.Plan, error) {
+	q := r.db.WithContext(ctx).Model(&models.PlanModel{}).Where("deleted_at IS NULL")
+
+	kw := spec.NormalizedKeyword()
+	like := "%" + escapeLike(kw) + "%"
+	if kw != "" {
+		q = q.Where("(LOWER(name) COLLATE utf8mb4_bin LIKE ? OR LOWER(keyword) COLLATE utf8mb4_bin LIKE ?)", like, like)
+	}
+	if spec.IDs != nil {
+		// 空の IN () は構文エラーになるので、空なら何も返さない条件にする
+		if len(spec.IDs) == 0 {
+			q = q.Where("1 = 0")
+		} else {
+			q = q.Where("id IN ?", spec.IDs)
+		}
+	}
+	if c := spec.PriceCurrency(); c != "" {
+		q = q.Where("currency = ?", string(c))
+	}
+	if spec.MinPrice > 0 {
+		q = q.Where("price >= ?", spec.MinPrice)
+	}
+	if spec.MaxPrice > 0 {
+		q = q.Where("price <= ?", spec.MaxPrice)
+	}
+	if spec.Guests > 0 {
+		q = q.Where("max_guests >= ? AND min_guests <= ?", spec.Guests, spec.Guests)
+	}
+	if spec.Checkin != nil && spec.Checkout != nil {
+		// 在庫行が無い日は満室になり得ない（販売室数は 1 以上）ので、満室の行が無ければ空きあり
+		q = q.Where(`NOT EXISTS (
+  SELECT 1 FROM plan_inventories i
+  WHERE i.plan_id = plans.id AND i.date >= ? AND i.date < ? AND i.reserved >= i.capacity)`,
+			entity.DateOf(*spec.Checkin).Format("2006-01-02"), entity.DateOf(*spec.Checkout).Format("2006-01-02"))
+	}
 
-	q := r.db.WithContext(ctx).Model(&models.PlanModel{})
-	if strings.TrimSpace(keyword) != "" {
-		kw := "%" + strings.TrimSpace(keyword) + "%"
-		q = q.Where("LOWER(name) LIKE LOWER(?) OR LOWER(keyword) LIKE LOWER(?)", kw, kw)
+	switch spec.Sort {
+	case entity.PlanSortPrice:
+		q = q.Order("currency ASC").Order("price ASC")
+	case entity.PlanSortName:
+		// Go の文字列比較と同じ文字コード順にする
+		q = q.Order("BINARY name ASC")
+	default:
+		if kw != "" {
+			q = q.Select("plans.*, (CASE WHEN LOWER(name) COLLATE utf8mb4_bin LIKE ? THEN 2 ELSE 0 END + CASE WHEN LOWER(keyword) COLLATE utf8mb4_bin LIKE ? THEN 1 ELSE 0 END) AS relevance", like, like).
+				Order("relevance DESC")
+		}
+	}
+	q = q.Order("id ASC")
+	if spec.Limit > 0 {
+		q = q.Limit(spec.Limit)
+	} else if spec.Offset > 0 {
+		// MySQL は LIMIT なしの OFFSET を受け付けない
+		q = q.Limit(math.MaxInt32)
+	}
+	if spec.Offset > 0 {
+		q = q.Offset(spec.Offset)
 	}
-	if err := q.Order("id ASC").Find(&list).Error; err != nil {
+
+	var list []models.PlanModel
+	if err := q.Find(&list).Error; err != nil {
 		return nil, err
 	}
 	out := make([]*entity.Plan, 0, len(list))
-	for _, m := range list {
-		copy := m
-		out = append(out, &entity.Plan{ID: copy.ID, Name: copy.Name, Keyword: copy.Keyword, Price: copy.Price})
+	for i := range list {
+		out = append(out, planToEntity(&list[i]))
 	}
 	return out, nil
 }
+
+// LIKE の特殊文字をエスケープして、キーワードを文字どおりに照合する
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *PlanRepo) Save(ctx context.Context, plan *entity.Plan) (*entity.Plan, error) {
+	m := models.PlanModel{
+		ID: plan.ID, Name: plan.Name, Keyword: plan.Keyword,
+		Price: plan.Price.Amount, Currency: string(plan.Price.Currency),
+		Capacity: plan.Capacity, MinGuests: plan.MinGuests, MaxGuests: plan.MaxGuests,
+		ChildRate: plan.ChildRate, InfantRate: plan.InfantRate, Jurisdiction: plan.Jurisdiction,
+		CancellationPolicyID: plan.CancellationPolicyID,
+	}
+	if m.MinGuests == 0 {
+		m.MinGuests = 1
+	}
+	if m.MaxGuests == 0 {
+		m.MaxGuests = entity.DefaultMaxGuests
+	}
+	if plan.ID == 0 {
+		m.Version = 1
+		if err := r.db.WithContext(ctx).Create(&m).Error; err != nil {
+			return nil, err
+		}
+		out := *plan
+		out.ID, out.Version = m.ID, m.Version
+		out.MinGuests, out.MaxGuests = m.MinGuests, m.MaxGuests
+		return &out, nil
+	}
+	m.Version = plan.Version + 1
+	err := r.transaction(ctx, func(tx *gorm.DB) error {
+		res := tx.Model(&models.PlanModel{ID: plan.ID}).
+			Where("version = ?", plan.Version).
+			Select("name", "keyword", "price", "currency", "capacity", "min_guests", "max_guests", "child_rate", "infant_rate", "jurisdiction",
+				"cancellation_policy_id", "version").
+			Updates(&m)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			var n int64
+			if err := tx.Model(&models.PlanModel{}).Where("id = ?", plan.ID).Count(&n).Error; err != nil {
+				return err
+			}
+			if n == 0 {
+				return repository.ErrPlanNotFound
+			}
+			return repository.ErrConcurrentModification
+		}
+		// 在庫行は作成時の販売室数を持っているので合わせる（確保済みが上回る日は残室 0 になる）
+		return tx.Model(&models.PlanInventoryModel{}).
+			Where("plan_id = ?", plan.ID).
+			UpdateColumn("capacity", plan.Capacity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := *plan
+	out.Version = m.Version
+	out.MinGuests, out.MaxGuests = m.MinGuests, m.MaxGuests
+	return &out, nil
+}
+
+// 読み込んだ時点のバージョンのときだけ削除する
+func (r *PlanRepo) Delete(ctx context.Context, id, version int, at time.Time) error {
+	tx := r.db.WithContext(ctx).
+		Model(&models.PlanModel{}).
+		Where("id = ? AND deleted_at IS NULL AND version = ?", id, version).
+		UpdateColumns(map[string]any{"deleted_at": at, "version": gorm.Expr("version + 1")})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected > 0 {
+		return nil
+	}
+	// 削除済み・存在しないなら何もしない。削除されていなければバージョンが違う
+	var n int64
+	if err := r.db.WithContext(ctx).Model(&models.PlanModel{}).Where("id = ? AND deleted_at IS NULL", id).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return repository.ErrConcurrentModification
+	}
+	return nil
+}
+
+// 各泊について「reserved < capacity」の条件付き UPDATE で在庫を確保する。
+// 条件付き UPDATE は行ロックを取るので、同時リクエストでも売り越さない
+func (r *PlanRepo) ReserveNights(ctx context.Context, planID int, checkin, checkout time.Time) error {
+	return r.transaction(ctx, func(tx *gorm.DB) error {
+		return reserveNights(tx, planID, entity.StayDates(checkin, checkout))
+	})
+}
+
+func (r *PlanRepo) ReleaseNights(ctx context.Context, planID int, checkin, checkout time.Time) error {
+	return releaseNights(r.db.WithContext(ctx), planID, entity.StayDates(checkin, checkout))
+}
+
+// 旧日程の在庫を戻して新日程を確保する。新日程が満室ならトランザクションごと戻す
+func (r *PlanRepo) RebookNights(ctx context.Context, planID int, oldCheckin, oldCheckout, newCheckin, newCheckout time.Time) error {
+	return r.transaction(ctx, func(tx *gorm.DB) error {
+		if err := releaseNights(tx, planID, entity.StayDates(oldCheckin, oldCheckout)); err != nil {
+			return err
+		}
+		return reserveNights(tx, planID, entity.StayDates(newCheckin, newCheckout))
+	})
+}
+
+func (r *PlanRepo) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if r.lockRead {
+		return fn(r.db.WithContext(ctx))
+	}
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+func reserveNights(tx *gorm.DB, planID int, dates []time.Time) error {
+	var plan models.PlanModel
+	if err := tx.Select("id", "capacity").First(&plan, planID).Error; err != nil {
+		return planNotFound(err)
+	}
+	for _, d := range dates {
+		// 在庫行が無ければプランの販売室数で作る（既にあれば何もしない）
+		row := models.PlanInventoryModel{PlanID: planID, Date: localDate(d), Capacity: plan.Capacity}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&models.PlanInventoryModel{}).
+			Where("plan_id = ? AND date = ? AND reserved < capacity", planID, d.Format("2006-01-02")).
+			UpdateColumn("reserved", gorm.Expr("reserved + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return repository.ErrSoldOut
+		}
+	}
+	return nil
+}
+
+func releaseNights(tx *gorm.DB, planID int, dates []time.Time) error {
+	if len(dates) == 0 {
+		return nil
+	}
+	days := make([]string, 0, len(dates))
+	for _, d := range dates {
+		days = append(days, d.Format("2006-01-02"))
+	}
+	return tx.Model(&models.PlanInventoryModel{}).
+		Where("plan_id = ? AND date IN ? AND reserved > 0", planID, days).
+		UpdateColumn("reserved", gorm.Expr("reserved - 1")).Error
+}
+
+func (r *PlanRepo) Availability(ctx context.Context, planID int, from, to time.Time) ([]entity.NightAvailability, error) {
+	var plan models.PlanModel
+	if err := r.db.WithContext(ctx).Select("id", "capacity").First(&plan, planID).Error; err != nil {
+		return nil, planNotFound(err)
+	}
+	dates := entity.StayDates(from, to)
+	if len(dates) == 0 {
+		return []entity.NightAvailability{}, nil
+	}
+	var rows []models.PlanInventoryModel
+	if err := r.db.WithContext(ctx).
+		Where("plan_id = ? AND date >= ? AND date < ?", planID,
+			dates[0].Format("2006-01-02"), entity.DateOf(to).Format("2006-01-02")).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]models.PlanInventoryModel, len(rows))
+	for _, row := range rows {
+		byDate[row.Date.Format("2006-01-02")] = row
+	}
+	out := make([]entity.NightAvailability, 0, len(dates))
+	for _, d := range dates {
+		remaining := plan.Capacity
+		if row, ok := byDate[d.Format("2006-01-02")]; ok {
+			remaining = row.Capacity - row.Reserved
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+		out = append(out, entity.NightAvailability{Date: d, Remaining: remaining})
+	}
+	return out, nil
+}
+
+func planNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrPlanNotFound
+	}
+	return err
+}
+
+// DSN が loc=Local なので、日付カラムへはローカルの 0 時として渡す
+func localDate(d time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func planToEntity(m *models.PlanModel) *entity.Plan {
+	return &entity.Plan{
+		ID: m.ID, Name: m.Name, Keyword: m.Keyword,
+		Price:    entity.NewMoney(m.Price, entity.Currency(m.Currency)),
+		Capacity: m.Capacity, MinGuests: m.MinGuests, MaxGuests: m.MaxGuests,
+		ChildRate: m.ChildRate, InfantRate: m.InfantRate, Jurisdiction: m.Jurisdiction, DeletedAt: m.DeletedAt,
+		CancellationPolicyID: m.CancellationPolicyID, Version: m.Version,
+	}
+}
+
+var _ repository.PlanRepository = (*PlanRepo)(nil)
@@ -0,0 +1,111 @@
+package mysqlrepo
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/infrastructure/db/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// 料金ルールはプランの一部として PlanRepo で扱う（UnitOfWork のトランザクションにも乗る）
+
+func (r *PlanRepo) RateRules(ctx context.Context, planID int) ([]entity.RateRule, error) {
+	var list []models.RateRuleModel
+	if err := r.db.WithContext(ctx).Where("plan_id = ?", planID).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	out := make([]entity.RateRule, 0, len(list))
+	for i := range list {
+		out = append(out, rateRuleToEntity(&list[i]))
+	}
+	return out, nil
+}
+
+func (r *PlanRepo) SaveRateRule(ctx context.Context, rule *entity.RateRule) (*entity.RateRule, error) {
+	m := models.RateRuleModel{
+		ID:         rule.ID,
+		PlanID:     rule.PlanID,
+		Name:       rule.Name,
+		StartDate:  localDatePtr(rule.StartDate),
+		EndDate:    localDatePtr(rule.EndDate),
+		Days:       joinRateDays(rule.Days),
+		Adjustment: string(rule.Adjustment),
+		Amount:     rule.Amount,
+		Percent:    rule.Percent,
+		Priority:   rule.Priority,
+	}
+	db := r.db.WithContext(ctx)
+	if m.ID == 0 {
+		if err := db.Create(&m).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		res := db.Model(&models.RateRuleModel{ID: m.ID}).
+			Select("plan_id", "name", "start_date", "end_date", "days", "adjustment", "amount", "percent", "priority").
+			Updates(&m)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			var n int64
+			if err := db.Model(&models.RateRuleModel{}).Where("id = ?", m.ID).Count(&n).Error; err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return nil, errors.New("rate rule not found")
+			}
+		}
+	}
+	out := rateRuleToEntity(&m)
+	return &out, nil
+}
+
+func (r *PlanRepo) DeleteRateRule(ctx context.Context, planID, ruleID int) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND plan_id = ?", ruleID, planID).
+		Delete(&models.RateRuleModel{}).Error
+}
+
+func rateRuleToEntity(m *models.RateRuleModel) entity.RateRule {
+	rule := entity.RateRule{
+		ID:         m.ID,
+		PlanID:     m.PlanID,
+		Name:       m.Name,
+		Adjustment: entity.RateAdjustment(m.Adjustment),
+		Amount:     m.Amount,
+		Percent:    m.Percent,
+		Priority:   m.Priority,
+	}
+	if m.StartDate != nil {
+		d := entity.DateOf(*m.StartDate)
+		rule.StartDate = &d
+	}
+	if m.EndDate != nil {
+		d := entity.DateOf(*m.EndDate)
+		rule.EndDate = &d
+	}
+	if m.Days != "" {
+		for _, d := range strings.Split(m.Days, ",") {
+			rule.Days = append(rule.Days, entity.RateDay(d))
+		}
+	}
+	return rule
+}
+
+func joinRateDays(days []entity.RateDay) string {
+	s := make([]string, 0, len(days))
+	for _, d := range days {
+		s = append(s, string(d))
+	}
+	return strings.Join(s, ",")
+}
+
+func localDatePtr(d *time.Time) *time.Time {
+	if d == nil {
+		return nil
+	}
+	ld := localDate(*d)
+	return &ld
+}
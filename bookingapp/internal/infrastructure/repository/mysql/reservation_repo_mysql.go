@@ -5,78 +5,255 @@ import (
 	"bookingapp/internal/domain/repository"
 	"bookingapp/internal/infrastructure/db/models"
 	"context"
+	"slices"
 
 	"gorm.io/gorm"
 )
 
-type ReservationRepo struct{ db *gorm.DB }
+type ReservationRepo struct {
+	db *gorm.DB
+	// UnitOfWork のトランザクション内で使う場合 true（入れ子のトランザクションを張らない）
+	inTx bool
+}
 
 func NewReservationRepo(db *gorm.DB) repository.ReservationRepository {
 	return &ReservationRepo{db: db}
 }
 
-// DBのauto-incrementに委譲するのでNextIDは使わないが、interface満たすために実装
-func (r *ReservationRepo) NextID() int { return 0 }
-
-func (r *ReservationRepo) Save(res *entity.Reservation) (*entity.Reservation, error) {
-	ctx := context.Background()
+func (r *ReservationRepo) Save(ctx context.Context, res *entity.Reservation) (*entity.Reservation, error) {
 	m := models.ReservationModel{
 		ID:       res.ID, // 0ならAUTO_INCREMENT
 		UserID:   res.UserID,
 		PlanID:   res.PlanID,
 		Number:   res.Number,
+		Adults:   res.Guests.Adults,
+		Children: res.Guests.Children,
+		Infants:  res.Guests.Infants,
 		Checkin:  res.Checkin,
 		Checkout: res.Checkout,
-		Total:    res.Total,
+		Total:    res.Total.Amount,
+		Currency: string(res.Total.Currency),
+		Status:   string(res.Status),
+		// 税・割引額の通貨は合計金額と同じ
+		Subtotal:         res.Subtotal.Amount,
+		ConsumptionTax:   res.ConsumptionTax.Amount,
+		AccommodationTax: res.AccommodationTax.Amount,
+		CouponCode:       res.CouponCode,
+		Discount:         res.Discount.Amount,
+		CancellationFee:  res.CancellationFee.Amount,
+	}
+	if res.CouponID != 0 {
+		m.CouponID = &res.CouponID
 	}
-	if err := r.db.WithContext(ctx).Save(&m).Error; err != nil {
+	if p := res.CancellationPolicy; p != nil {
+		m.CancellationPolicyID, m.CancellationPolicyName, m.CancellationRules = p.ID, p.Name, formatCancellationRules(p.Rules)
+	}
+	err := r.transaction(ctx, func(tx *gorm.DB) error {
+		if m.ID == 0 {
+			m.Version = 1
+			tx = tx.Create(&m)
+		} else {
+			// 読み込んだ時点のバージョンのときだけ全カラムを上書きする（ゼロ値の created_at で潰さないよう除外）
+			m.Version = res.Version + 1
+			tx = tx.Model(&m).Where("version = ?", res.Version).Select("*").Omit("id", "created_at").Updates(&m)
+		}
+		if err := tx.Error; err != nil {
+			return err
+		}
+		if tx.RowsAffected == 0 {
+			return repository.ErrConcurrentModification
+		}
+		return saveNights(tx.Session(&gorm.Session{NewDB: true}), m.ID, res.Breakdown)
+	})
+	if err != nil {
 		return nil, err
 	}
-	// 生成されたIDを反映
-	res.ID = m.ID
+	// 生成されたIDと保存後のバージョンを反映
+	res.ID, res.Version = m.ID, m.Version
 	return res, nil
 }
 
-func (r *ReservationRepo) FindByID(id int) (*entity.Reservation, error) {
+// 内訳が保存済みのものと変わったときだけ丸ごと置き換える（状態だけの更新で行を書き直さない）
+func saveNights(tx *gorm.DB, reservationID int, nights []entity.NightCharge) error {
+	rows := make([]models.ReservationNightModel, 0, len(nights))
+	for _, n := range nights {
+		rows = append(rows, models.ReservationNightModel{
+			ReservationID: reservationID,
+			Date:          localDate(n.Date),
+			Rate:          n.Rate.Amount,
+			ChildRate:     n.ChildRate.Amount,
+			InfantRate:    n.InfantRate.Amount,
+			Amount:        n.Amount.Amount,
+			Currency:      string(n.Amount.Currency),
+		})
+	}
+	saved, err := loadNights(tx, []int{reservationID})
+	if err != nil {
+		return err
+	}
+	if slices.EqualFunc(saved[reservationID], nights, sameNight) {
+		return nil
+	}
+	if err := tx.Where("reservation_id = ?", reservationID).Delete(&models.ReservationNightModel{}).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Create(&rows).Error
+}
+
+func sameNight(a, b entity.NightCharge) bool {
+	return entity.DateOf(a.Date).Equal(entity.DateOf(b.Date)) &&
+		a.Rate == b.Rate && a.ChildRate == b.ChildRate && a.InfantRate == b.InfantRate && a.Amount == b.Amount
+}
+
+// 予約ごとの内訳を日付順に読む
+func loadNights(tx *gorm.DB, reservationIDs []int) (map[int][]entity.NightCharge, error) {
+	out := map[int][]entity.NightCharge{}
+	if len(reservationIDs) == 0 {
+		return out, nil
+	}
+	var rows []models.ReservationNightModel
+	if err := tx.Where("reservation_id IN ?", reservationIDs).Order("reservation_id ASC").Order("date ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, n := range rows {
+		c := entity.Currency(n.Currency)
+		out[n.ReservationID] = append(out[n.ReservationID], entity.NightCharge{
+			Date:       entity.DateOf(n.Date),
+			Rate:       entity.NewMoney(n.Rate, c),
+			ChildRate:  entity.NewMoney(n.ChildRate, c),
+			InfantRate: entity.NewMoney(n.InfantRate, c),
+			Amount:     entity.NewMoney(n.Amount, c),
+		})
+	}
+	return out, nil
+}
+
+func (r *ReservationRepo) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if r.inTx {
+		return fn(r.db.WithContext(ctx))
+	}
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+func (r *ReservationRepo) FindByID(ctx context.Context, id int) (*entity.Reservation, error) {
 	var m models.ReservationModel
-	if err := r.db.WithContext(context.Background()).
+	if err := r.db.WithContext(ctx).
 		First(&m, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &entity.Reservation{
+	nights, err := loadNights(r.db.WithContext(ctx), []int{id})
+	if err != nil {
+		return nil, err
+	}
+	res := reservationToEntity(&m)
+	res.Breakdown = nights[id]
+	return res, nil
+}
+
+// 絞り込みと並び順は (user_id, checkin, id) / (checkin, id) などのインデックスで引ける形にする。
+// ページングは OFFSET ではなく直前の位置を条件にする（キーセット方式）
+func (r *ReservationRepo) List(ctx context.Context, q repository.ReservationQuery) ([]*entity.Reservation, error) {
+	tx := r.db.WithContext(ctx).Model(&models.ReservationModel{})
+	if q.UserID != "" {
+		tx = tx.Where("user_id = ?", q.UserID)
+	}
+	if q.PlanID != 0 {
+		tx = tx.Where("plan_id = ?", q.PlanID)
+	}
+	if q.Status != "" {
+		tx = tx.Where("status = ?", string(q.Status))
+	}
+	if q.CheckinFrom != nil {
+		tx = tx.Where("checkin >= ?", q.CheckinFrom.Format("2006-01-02"))
+	}
+	if q.CheckinTo != nil {
+		tx = tx.Where("checkin <= ?", q.CheckinTo.Format("2006-01-02"))
+	}
+
+	switch q.Sort {
+	case repository.SortByIDDesc:
+		if q.After != nil {
+			tx = tx.Where("id < ?", q.After.ID)
+		}
+		tx = tx.Order("id DESC")
+	case repository.SortByCheckinAsc:
+		if q.After != nil {
+			c := q.After.Checkin.Format("2006-01-02")
+			tx = tx.Where("(checkin > ? OR (checkin = ? AND id > ?))", c, c, q.After.ID)
+		}
+		tx = tx.Order("checkin ASC").Order("id ASC")
+	case repository.SortByCheckinDesc:
+		if q.After != nil {
+			c := q.After.Checkin.Format("2006-01-02")
+			tx = tx.Where("(checkin < ? OR (checkin = ? AND id < ?))", c, c, q.After.ID)
+		}
+		tx = tx.Order("checkin DESC").Order("id DESC")
+	default:
+		if q.After != nil {
+			tx = tx.Where("id > ?", q.After.ID)
+		}
+		tx = tx.Order("id ASC")
+	}
+	if q.Limit > 0 {
+		tx = tx.Limit(q.Limit)
+	}
+
+	var list []models.ReservationModel
+	if err := tx.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(list))
+	for _, m := range list {
+		ids = append(ids, m.ID)
+	}
+	nights, err := loadNights(r.db.WithContext(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*entity.Reservation, 0, len(list))
+	for i := range list {
+		res := reservationToEntity(&list[i])
+		res.Breakdown = nights[res.ID]
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+func reservationToEntity(m *models.ReservationModel) *entity.Reservation {
+	res := &entity.Reservation{
 		ID:       m.ID,
 		UserID:   m.UserID,
 		PlanID:   m.PlanID,
 		Number:   m.Number,
+		Guests:   entity.Guests{Adults: m.Adults, Children: m.Children, Infants: m.Infants},
 		Checkin:  m.Checkin,
 		Checkout: m.Checkout,
-		Total:    m.Total,
-	}, nil
-}
+		Total:    entity.NewMoney(m.Total, entity.Currency(m.Currency)),
+		Status:   entity.ReservationStatus(m.Status),
+		Version:  m.Version,
 
-func (r *ReservationRepo) List() ([]*entity.Reservation, error) {
-	var list []models.ReservationModel
-	if err := r.db.WithContext(context.Background()).
-		Order("id ASC").Find(&list).Error; err != nil {
-		return nil, err
+		Subtotal:         entity.NewMoney(m.Subtotal, entity.Currency(m.Currency)),
+		ConsumptionTax:   entity.NewMoney(m.ConsumptionTax, entity.Currency(m.Currency)),
+		AccommodationTax: entity.NewMoney(m.AccommodationTax, entity.Currency(m.Currency)),
+		CancellationFee:  entity.NewMoney(m.CancellationFee, entity.Currency(m.Currency)),
 	}
-	out := make([]*entity.Reservation, 0, len(list))
-	for _, m := range list {
-		copy := m
-		out = append(out, &entity.Reservation{
-			ID:       copy.ID,
-			UserID:   copy.UserID,
-			PlanID:   copy.PlanID,
-			Number:   copy.Number,
-			Checkin:  copy.Checkin,
-			Checkout: copy.Checkout,
-			Total:    copy.Total,
-		})
+	if m.CancellationPolicyID != 0 {
+		res.CancellationPolicy = &entity.CancellationPolicy{
+			ID: m.CancellationPolicyID, Name: m.CancellationPolicyName, Rules: parseCancellationRules(m.CancellationRules),
+		}
 	}
-	return out, nil
+	if m.CouponID != nil {
+		res.CouponID = *m.CouponID
+		res.CouponCode = m.CouponCode
+		res.Discount = entity.NewMoney(m.Discount, entity.Currency(m.Currency))
+	}
+	return res
 }
 
 var _ repository.ReservationRepository = (*ReservationRepo)(nil)
@@ -0,0 +1,67 @@
+package mysqlrepo
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/infrastructure/db/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepo struct{ db *gorm.DB }
+
+func NewSessionRepo(db *gorm.DB) repository.SessionRepository { return &SessionRepo{db: db} }
+
+func (r *SessionRepo) Create(ctx context.Context, s *entity.Session) error {
+	m := models.SessionModel{
+		ID:               s.ID,
+		UserID:           s.UserID,
+		RefreshTokenHash: s.RefreshTokenHash,
+		ExpiresAt:        s.ExpiresAt,
+		RevokedAt:        s.RevokedAt,
+		CreatedAt:        s.CreatedAt,
+	}
+	return r.db.WithContext(ctx).Create(&m).Error
+}
+
+func (r *SessionRepo) Get(ctx context.Context, id string) (*entity.Session, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *SessionRepo) FindByRefreshTokenHash(ctx context.Context, hash string) (*entity.Session, error) {
+	return r.first(ctx, "refresh_token_hash = ?", hash)
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, id string, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.SessionModel{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *SessionRepo) first(ctx context.Context, query string, arg any) (*entity.Session, error) {
+	var m models.SessionModel
+	err := r.db.WithContext(ctx).Where(query, arg).First(&m).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return &entity.Session{
+		ID:               m.ID,
+		UserID:           m.UserID,
+		RefreshTokenHash: m.RefreshTokenHash,
+		ExpiresAt:        m.ExpiresAt,
+		RevokedAt:        m.RevokedAt,
+		CreatedAt:        m.CreatedAt,
+	}, nil
+}
+
+var _ repository.SessionRepository = (*SessionRepo)(nil)
@@ -0,0 +1,30 @@
+package mysqlrepo
+
+import (
+	"bookingapp/internal/domain/repository"
+	userrepo "bookingapp/internal/infrastructure/repository/mysql/user"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type UnitOfWork struct{ db *gorm.DB }
+
+func NewUnitOfWork(db *gorm.DB) repository.UnitOfWork { return &UnitOfWork{db: db} }
+
+// gorm のトランザクションに束縛したリポジトリを fn に渡す。
+// fn がエラーを返すか panic した場合はロールバックされる
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(ctx, repository.Repositories{
+			// トランザクション中に読んだプラン（単価など）はコミットまで変更させない
+			Plans:        &PlanRepo{db: tx, lockRead: true},
+			Reservations: &ReservationRepo{db: tx, inTx: true},
+			Users:        userrepo.NewUserRepo(tx),
+			Coupons:      &CouponRepo{db: tx, inTx: true},
+			Payments:     NewPaymentRepo(tx),
+		})
+	})
+}
+
+var _ repository.UnitOfWork = (*UnitOfWork)(nil)
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	usermodel "bookingapp/internal/infrastructure/db/models/user"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// roles / role_permissions を読む。定義はマイグレーションで投入する
+type RoleRepo struct {
+	db *gorm.DB
+}
+
+func NewRoleRepo(db *gorm.DB) repository.RoleRepository {
+	return &RoleRepo{db: db}
+}
+
+func (r *RoleRepo) Permissions(ctx context.Context, role entity.Role) ([]entity.Permission, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&usermodel.RoleModel{}).Where("name = ?", string(role)).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, repository.ErrRoleNotFound
+	}
+	var perms []string
+	err := r.db.WithContext(ctx).
+		Model(&usermodel.RolePermissionModel{}).
+		Where("role = ?", string(role)).
+		Order("permission").
+		Pluck("permission", &perms).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.Permission, 0, len(perms))
+	for _, p := range perms {
+		out = append(out, entity.Permission(p))
+	}
+	return out, nil
+}
+
+var _ repository.RoleRepository = (*RoleRepo)(nil)
@@ -12,12 +12,12 @@ import (
 )
 
 // ---- ユーザー情報取得 ----
-func (r *UserRepo) Get(id string) (*entity.User, error) {
+func (r *UserRepo) Get(ctx context.Context, id string) (*entity.User, error) {
 	if strings.TrimSpace(id) == "" {
 		return nil, fmt.Errorf("id is empty")
 	}
 	var model usermodel.UserModel
-	err := r.db.WithContext(context.Background()).
+	err := r.db.WithContext(ctx).
 		Where("id = ?", strings.TrimSpace(id)).
 		First(&model).Error
 
@@ -28,5 +28,5 @@ func (r *UserRepo) Get(id string) (*entity.User, error) {
 		return nil, err
 	}
 
-	return modelToEntity(&model), nil
+	return r.withRoles(ctx, modelToEntity(&model))
 }
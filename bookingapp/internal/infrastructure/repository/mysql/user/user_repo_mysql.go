@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // データベース操作を行うための実装
@@ -23,7 +24,7 @@ func NewUserRepo(db *gorm.DB) repository.UserRepository {
 	return &UserRepo{db: db}
 }
 
-func (r *UserRepo) Create(user *entity.User) (*entity.User, error) {
+func (r *UserRepo) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
 	if user == nil {
 		return nil, errors.New("user is nil")
 	}
@@ -31,6 +32,9 @@ func (r *UserRepo) Create(user *entity.User) (*entity.User, error) {
 	if user.ID == "" {
 		user.ID = uuid.NewString()
 	}
+	if len(user.Roles) == 0 {
+		user.Roles = []entity.Role{entity.RoleGuest}
+	}
 
 	var dob *time.Time
 	if !user.DateOfBirth.IsZero() {
@@ -38,6 +42,12 @@ func (r *UserRepo) Create(user *entity.User) (*entity.User, error) {
 		dob = &d
 	}
 
+	var hash *string
+	if user.PasswordHash != "" {
+		h := user.PasswordHash
+		hash = &h
+	}
+
 	model := usermodel.UserModel{
 		ID:           user.ID,
 		Name:         user.Name,
@@ -47,22 +57,39 @@ func (r *UserRepo) Create(user *entity.User) (*entity.User, error) {
 		DateOfBirth:  dob,
 		RegisteredAt: user.RegisteredAt,
 		Status:       user.Status,
+		PasswordHash: hash,
+		Version:      1,
+	}
+	roles := make([]usermodel.UserRoleModel, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, usermodel.UserRoleModel{UserID: user.ID, Role: string(role)})
 	}
 
-	if err := r.db.WithContext(context.Background()).Create(&model).Error; err != nil {
+	// ユーザーとロールはまとめて作成する
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&model).Error; err != nil {
+			return err
+		}
+		return tx.Create(&roles).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, repository.ErrDuplicateEmail
+		}
 		return nil, err
 	}
 
+	user.Version = model.Version
 	return user, nil
 }
 
-func (r *UserRepo) FindByEmail(email string) (*entity.User, error) {
+func (r *UserRepo) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
 	if strings.TrimSpace(email) == "" {
 		return nil, nil
 	}
 
 	var model usermodel.UserModel
-	err := r.db.WithContext(context.Background()).
+	err := r.db.WithContext(ctx).
 		Where("email = ?", strings.TrimSpace(email)).
 		First(&model).Error
 
@@ -73,7 +100,61 @@ func (r *UserRepo) FindByEmail(email string) (*entity.User, error) {
 		return nil, err
 	}
 
-	return modelToEntity(&model), nil
+	return r.withRoles(ctx, modelToEntity(&model))
+}
+
+func (r *UserRepo) GrantRole(ctx context.Context, userID string, role entity.Role, version int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, userID, version); err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&usermodel.UserRoleModel{UserID: userID, Role: string(role)}).Error
+	})
+}
+
+func (r *UserRepo) RevokeRole(ctx context.Context, userID string, role entity.Role, version int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, userID, version); err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND role = ?", userID, string(role)).
+			Delete(&usermodel.UserRoleModel{}).Error
+	})
+}
+
+// ユーザーのバージョンを上げる。version が 0 でなければ一致する場合だけ（違えば ErrConcurrentModification）
+func bumpVersion(tx *gorm.DB, userID string, version int) error {
+	q := tx.Model(&usermodel.UserModel{}).Where("id = ?", userID)
+	if version != 0 {
+		q = q.Where("version = ?", version)
+	}
+	res := q.UpdateColumn("version", gorm.Expr("version + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if version != 0 && res.RowsAffected == 0 {
+		return repository.ErrConcurrentModification
+	}
+	return nil
+}
+
+// user_roles から付与済みのロールを読み込む（名前順）
+func (r *UserRepo) withRoles(ctx context.Context, user *entity.User) (*entity.User, error) {
+	var roles []string
+	err := r.db.WithContext(ctx).
+		Model(&usermodel.UserRoleModel{}).
+		Where("user_id = ?", user.ID).
+		Order("role").
+		Pluck("role", &roles).Error
+	if err != nil {
+		return nil, err
+	}
+	user.Roles = make([]entity.Role, 0, len(roles))
+	for _, role := range roles {
+		user.Roles = append(user.Roles, entity.Role(role))
+	}
+	return user, nil
 }
 
 var _ repository.UserRepository = (*UserRepo)(nil)
@@ -88,6 +169,11 @@ func modelToEntity(model *usermodel.UserModel) *entity.User {
 		dob = *model.DateOfBirth
 	}
 
+	var hash string
+	if model.PasswordHash != nil {
+		hash = *model.PasswordHash
+	}
+
 	return &entity.User{
 		ID:           model.ID,
 		Name:         model.Name,
@@ -97,5 +183,7 @@ func modelToEntity(model *usermodel.UserModel) *entity.User {
 		DateOfBirth:  dob,
 		RegisteredAt: model.RegisteredAt,
 		Status:       model.Status,
+		PasswordHash: hash,
+		Version:      model.Version,
 	}
 }
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"slices"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 表記ゆれの多い異体字を代表字にそろえる（旧字体・人名用の異体字など）
+var variants = map[rune]rune{
+	'冨': '富', '髙': '高', '﨑': '崎', '嵜': '崎', '濱': '浜', '濵': '浜',
+	'邊': '辺', '邉': '辺', '澤': '沢', '齋': '斎', '齊': '斎', '櫻': '桜',
+	'國': '国', '廣': '広', '嶋': '島', '嶌': '島', '瀨': '瀬', '龍': '竜',
+	'藏': '蔵', '驛': '駅', '舘': '館', '會': '会', '縣': '県', '溫': '温',
+}
+
+// 正規化した文字列。runes[i] は元の文字列の [start[i], end[i]) 文字目（rune 単位）から来ている
+type normalized struct {
+	runes []rune
+	start []int
+	end   []int
+}
+
+// 検索用に正規化する。
+// NFKC で全角英数・半角カナをそろえ、カタカナはひらがなに、英字は小文字に、異体字は代表字にする
+func normalize(s string) normalized {
+	var n normalized
+	for i, r := range []rune(s) {
+		for _, c := range norm.NFKC.String(string(r)) {
+			// 半角カナの濁点・半濁点は NFKC で結合文字になるので、直前の文字と合成する
+			if (c == '゙' || c == '゚') && len(n.runes) > 0 {
+				last := len(n.runes) - 1
+				if composed := []rune(norm.NFC.String(string(n.runes[last]) + string(c))); len(composed) == 1 {
+					n.runes[last] = composed[0]
+					n.end[last] = i + 1
+					continue
+				}
+			}
+			n.runes = append(n.runes, fold(c))
+			n.start = append(n.start, i)
+			n.end = append(n.end, i+1)
+		}
+	}
+	return n
+}
+
+func fold(c rune) rune {
+	switch {
+	case c >= 'ァ' && c <= 'ヶ':
+		c -= 'ァ' - 'ぁ'
+	case c == 'ヽ' || c == 'ヾ':
+		c -= 'ヽ' - 'ゝ'
+	}
+	if v, ok := variants[c]; ok {
+		return v
+	}
+	return unicode.ToLower(c)
+}
+
+// 空白で区切った語（正規化済み）
+func terms(query string) [][]rune {
+	var out [][]rune
+	var cur []rune
+	for _, c := range normalize(query).runes {
+		if unicode.IsSpace(c) {
+			if len(cur) > 0 {
+				out = append(out, cur)
+				cur = nil
+			}
+			continue
+		}
+		cur = append(cur, c)
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// 文字 n-gram（1-gram と 2-gram）。空白をまたぐ 2-gram は作らない
+func grams(rs []rune) []string {
+	var out []string
+	for i, c := range rs {
+		if unicode.IsSpace(c) {
+			continue
+		}
+		out = append(out, string(c))
+		if i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) {
+			out = append(out, string(rs[i:i+2]))
+		}
+	}
+	return out
+}
+
+// 検索語から引く n-gram。1 文字なら 1-gram、2 文字以上なら 2-gram だけ
+func queryGrams(term []rune) []string {
+	if len(term) == 1 {
+		return []string{string(term)}
+	}
+	out := make([]string, 0, len(term)-1)
+	for i := 0; i+1 < len(term); i++ {
+		out = append(out, string(term[i:i+2]))
+	}
+	return out
+}
+
+// text の中で term が現れる位置（rune 単位、重ならないもの）
+func occurrences(text, term []rune) []int {
+	var out []int
+	for i := 0; i+len(term) <= len(text); {
+		if slices.Equal(text[i:i+len(term)], term) {
+			out = append(out, i)
+			i += len(term)
+			continue
+		}
+		i++
+	}
+	return out
+}
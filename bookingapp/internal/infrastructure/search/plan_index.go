@@ -0,0 +1,241 @@
+// Package search はプランの全文検索インデックスのプロセス内実装。
+// 日本語は単語の区切りが無いので、正規化した文字の 1-gram / 2-gram で転置索引を作る
+package search
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/usecase"
+	"context"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// フィールドごとの重み。名前に一致したほうを上位にする
+var fieldWeights = []struct {
+	name   string
+	weight float64
+}{
+	{"name", 2},
+	{"keyword", 1},
+}
+
+// 抜粋の長さ（rune 単位）。これより長いフィールドは最初の一致箇所の前後だけを返す
+const (
+	snippetBefore = 15
+	snippetAfter  = 45
+)
+
+type indexedField struct {
+	text string
+	norm normalized
+}
+
+type PlanIndex struct {
+	mu     sync.RWMutex
+	fields map[int][]indexedField // plan ID → fieldWeights と同じ順のフィールド
+	grams  map[string]map[int]struct{}
+}
+
+// 空のインデックスを作る。起動時と定期的に PlanUsecase.Reindex で全プランを登録し直す
+func NewPlanIndex() usecase.PlanSearchIndex {
+	return newPlanIndex()
+}
+
+func newPlanIndex() *PlanIndex {
+	return &PlanIndex{fields: map[int][]indexedField{}, grams: map[string]map[int]struct{}{}}
+}
+
+func (x *PlanIndex) Index(ctx context.Context, plan *entity.Plan) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.indexLocked(plan)
+	return nil
+}
+
+// 別に作った索引と差し替えるので、作り直している間も検索できる
+func (x *PlanIndex) Replace(ctx context.Context, plans []*entity.Plan) error {
+	next := newPlanIndex()
+	for _, p := range plans {
+		next.indexLocked(p)
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.fields, x.grams = next.fields, next.grams
+	return nil
+}
+
+func (x *PlanIndex) indexLocked(plan *entity.Plan) {
+	x.removeLocked(plan.ID)
+	if plan.Deleted() {
+		return
+	}
+	fs := []indexedField{
+		{text: plan.Name, norm: normalize(plan.Name)},
+		{text: plan.Keyword, norm: normalize(plan.Keyword)},
+	}
+	x.fields[plan.ID] = fs
+	for _, f := range fs {
+		for _, g := range grams(f.norm.runes) {
+			ids, ok := x.grams[g]
+			if !ok {
+				ids = map[int]struct{}{}
+				x.grams[g] = ids
+			}
+			ids[plan.ID] = struct{}{}
+		}
+	}
+}
+
+func (x *PlanIndex) Remove(ctx context.Context, planID int) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(planID)
+	return nil
+}
+
+func (x *PlanIndex) removeLocked(planID int) {
+	fs, ok := x.fields[planID]
+	if !ok {
+		return
+	}
+	for _, f := range fs {
+		for _, g := range grams(f.norm.runes) {
+			delete(x.grams[g], planID)
+			if len(x.grams[g]) == 0 {
+				delete(x.grams, g)
+			}
+		}
+	}
+	delete(x.fields, planID)
+}
+
+// 語はすべて含むもの（AND）だけを返す。
+// スコアは語ごとに「フィールドの重み × 出現回数 × idf」の和で、ヒットの少ない語ほど効く
+func (x *PlanIndex) Search(ctx context.Context, query string) ([]usecase.PlanSearchHit, error) {
+	ts := terms(query)
+	if len(ts) == 0 {
+		return nil, nil
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	scores := map[int]float64{}
+	spans := map[int][][][2]int{} // plan ID → フィールドごとの一致範囲（正規化後の rune 位置）
+	for i, t := range ts {
+		// n-gram で候補を絞り、正規化した本文に語がそのまま含まれるかで確かめる
+		matched := map[int][][]int{}
+		for id := range x.candidates(t) {
+			if i > 0 {
+				if _, ok := scores[id]; !ok {
+					continue
+				}
+			}
+			var pos [][]int
+			found := false
+			for _, f := range x.fields[id] {
+				occ := occurrences(f.norm.runes, t)
+				pos = append(pos, occ)
+				found = found || len(occ) > 0
+			}
+			if found {
+				matched[id] = pos
+			}
+		}
+		idf := 1 + math.Log(float64(len(x.fields))/float64(max(len(matched), 1)))
+		next := make(map[int]float64, len(matched))
+		for id, pos := range matched {
+			score := scores[id]
+			if spans[id] == nil {
+				spans[id] = make([][][2]int, len(fieldWeights))
+			}
+			for fi, occ := range pos {
+				score += fieldWeights[fi].weight * float64(len(occ)) * idf
+				for _, p := range occ {
+					spans[id][fi] = append(spans[id][fi], [2]int{p, p + len(t)})
+				}
+			}
+			next[id] = score
+		}
+		scores = next
+	}
+
+	out := make([]usecase.PlanSearchHit, 0, len(scores))
+	for id, score := range scores {
+		hit := usecase.PlanSearchHit{PlanID: id, Score: math.Round(score*1000) / 1000, Highlights: map[string]string{}}
+		for fi, sp := range spans[id] {
+			if len(sp) > 0 {
+				hit.Highlights[fieldWeights[fi].name] = highlight(x.fields[id][fi], sp)
+			}
+		}
+		out = append(out, hit)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].PlanID < out[j].PlanID
+	})
+	return out, nil
+}
+
+// 語の n-gram をすべて含むプラン
+func (x *PlanIndex) candidates(term []rune) map[int]struct{} {
+	var out map[int]struct{}
+	for _, g := range queryGrams(term) {
+		ids := x.grams[g]
+		if out == nil {
+			out = make(map[int]struct{}, len(ids))
+			for id := range ids {
+				out[id] = struct{}{}
+			}
+			continue
+		}
+		for id := range out {
+			if _, ok := ids[id]; !ok {
+				delete(out, id)
+			}
+		}
+	}
+	return out
+}
+
+// 一致範囲を元の文字列の位置に戻し、<em> で囲んだ抜粋を作る（本文は HTML エスケープする）
+func highlight(f indexedField, spans [][2]int) string {
+	orig := []rune(f.text)
+	marks := make([]bool, len(orig))
+	first := len(orig)
+	for _, sp := range spans {
+		from, to := f.norm.start[sp[0]], f.norm.end[sp[1]-1]
+		first = min(first, from)
+		for i := from; i < to; i++ {
+			marks[i] = true
+		}
+	}
+	from, to := 0, len(orig)
+	if len(orig) > snippetBefore+snippetAfter {
+		from = max(first-snippetBefore, 0)
+		to = min(from+snippetBefore+snippetAfter, len(orig))
+	}
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	for i := from; i < to; i++ {
+		if marks[i] && (i == from || !marks[i-1]) {
+			b.WriteString("<em>")
+		}
+		b.WriteString(html.EscapeString(string(orig[i])))
+		if marks[i] && (i+1 == to || !marks[i+1]) {
+			b.WriteString("</em>")
+		}
+	}
+	if to < len(orig) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+var _ usecase.PlanSearchIndex = (*PlanIndex)(nil)
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"bookingapp/internal/domain/entity"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPlanIndex(t *testing.T) {
+	ctx := context.Background()
+	idx := NewPlanIndex()
+	for _, p := range []*entity.Plan{
+		{ID: 100, Name: "富士プレミアム", Keyword: "富士 山 静岡"},
+		{ID: 175, Name: "サウスベーシック", Keyword: "サウス 南"},
+		{ID: 200, Name: "北の宿", Keyword: "北海道 北 温泉"},
+		{ID: 300, Name: "ＯＮＳＥＮ 露天風呂 <特別>", Keyword: "冨士 おんせん"},
+	} {
+		if err := idx.Index(ctx, p); err != nil {
+			t.Fatalf("Index(%d): %v", p.ID, err)
+		}
+	}
+
+	cases := []struct {
+		query string
+		want  []int
+	}{
+		{"富士", []int{100, 300}}, // 冨 は 富 の異体字。名前に一致した 100 が上位
+		{"ぷれみあむ", []int{100}},   // ひらがなでカタカナに一致
+		{"ﾍﾞｰｼｯｸ", []int{175}},  // 半角カナ
+		{"onsen", []int{300}},   // 全角英字・大文字小文字
+		{"温泉", []int{200}},
+		{"北 温泉", []int{200}}, // 複数語は AND
+		{"北 富士", []int{}},
+		{"山", []int{100}},
+		{"　", []int{}},
+	}
+	for _, c := range cases {
+		hits, err := idx.Search(ctx, c.query)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", c.query, err)
+		}
+		got := make([]int, 0, len(hits))
+		for _, h := range hits {
+			got = append(got, h.PlanID)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("Search(%q) = %v, want %v", c.query, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("Search(%q) = %v, want %v", c.query, got, c.want)
+				break
+			}
+		}
+	}
+
+	hits, _ := idx.Search(ctx, "ﾍﾞｰｼｯｸ")
+	if h := hits[0].Highlights["name"]; h != "サウス<em>ベーシック</em>" {
+		t.Errorf("highlight(name) = %q", h)
+	}
+	hits, _ = idx.Search(ctx, "onsen 特別")
+	if h := hits[0].Highlights["name"]; h != "<em>ＯＮＳＥＮ</em> 露天風呂 &lt;<em>特別</em>&gt;" {
+		t.Errorf("highlight(name) = %q", h)
+	}
+	if _, ok := hits[0].Highlights["keyword"]; ok {
+		t.Errorf("highlights = %v, want name only", hits[0].Highlights)
+	}
+	hits, _ = idx.Search(ctx, "オンセン")
+	if h := hits[0].Highlights["keyword"]; h != "冨士 <em>おんせん</em>" {
+		t.Errorf("highlight(keyword) = %q", h)
+	}
+
+	// 更新・削除は索引に反映される
+	if err := idx.Index(ctx, &entity.Plan{ID: 200, Name: "南の宿", Keyword: "沖縄"}); err != nil {
+		t.Fatalf("Index(update): %v", err)
+	}
+	if hits, _ := idx.Search(ctx, "北海道"); len(hits) != 0 {
+		t.Errorf("Search after update = %+v, want none", hits)
+	}
+	now := time.Now()
+	if err := idx.Index(ctx, &entity.Plan{ID: 175, Name: "サウスベーシック", DeletedAt: &now}); err != nil {
+		t.Fatalf("Index(deleted): %v", err)
+	}
+	if err := idx.Remove(ctx, 100); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	hits, _ = idx.Search(ctx, "南")
+	if len(hits) != 1 || hits[0].PlanID != 200 {
+		t.Errorf("Search(南) after delete = %+v, want [200]", hits)
+	}
+	if hits, _ := idx.Search(ctx, "富士"); len(hits) != 1 || hits[0].PlanID != 300 {
+		t.Errorf("Search(富士) after remove = %+v, want [300]", hits)
+	}
+}
+
+func TestPlanIndexReplace(t *testing.T) {
+	ctx := context.Background()
+	idx := NewPlanIndex()
+	if err := idx.Index(ctx, &entity.Plan{ID: 1, Name: "富士プレミアム"}); err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+	// 渡したプランだけが残る（他で削除されたプランも索引から消える）
+	deleted := time.Now()
+	if err := idx.Replace(ctx, []*entity.Plan{
+		{ID: 2, Name: "北の宿"},
+		{ID: 3, Name: "北の離れ", DeletedAt: &deleted},
+	}); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	for query, want := range map[string]int{"富士": 0, "北": 1} {
+		if hits, _ := idx.Search(ctx, query); len(hits) != want {
+			t.Errorf("Search(%q) = %d hits, want %d", query, len(hits), want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package taxconfig
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/usecase"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TAX_RULES_FILE が無い場合に使う既定のルール
+//
+//go:embed default_tax_rules.json
+var defaultRules []byte
+
+// 設定ファイルの形式。金額は currency（省略時は entity.DefaultCurrency）の最小単位
+type fileRules struct {
+	Default       string                      `json:"default"`
+	Jurisdictions map[string]fileJurisdiction `json:"jurisdictions"`
+}
+
+type fileJurisdiction struct {
+	Name                  string `json:"name"`
+	ConsumptionTaxPercent int    `json:"consumption_tax_percent"`
+	Currency              string `json:"currency"`
+	AccommodationTax      []struct {
+		MinRate int64 `json:"min_rate"`
+		Tax     int64 `json:"tax"`
+	} `json:"accommodation_tax"`
+}
+
+// JSON の設定を読み込んで検証する。地域コードは小文字にそろえる
+func Parse(b []byte) (*entity.TaxRules, error) {
+	var f fileRules
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrInvalidTaxRules, err)
+	}
+	rules := &entity.TaxRules{
+		Default:       normalizeCode(f.Default),
+		Jurisdictions: make(map[string]entity.Jurisdiction, len(f.Jurisdictions)),
+	}
+	for code, fj := range f.Jurisdictions {
+		currency, err := entity.ParseCurrency(fj.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", entity.ErrInvalidTaxRules, code, err)
+		}
+		j := entity.Jurisdiction{Name: fj.Name, ConsumptionTaxPercent: fj.ConsumptionTaxPercent}
+		for _, b := range fj.AccommodationTax {
+			j.AccommodationTax = append(j.AccommodationTax, entity.AccommodationTaxBracket{
+				MinRate: entity.NewMoney(b.MinRate, currency),
+				Tax:     entity.NewMoney(b.Tax, currency),
+			})
+		}
+		rules.Jurisdictions[normalizeCode(code)] = j
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func normalizeCode(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+type staticSource struct {
+	rules *entity.TaxRules
+}
+
+// 埋め込みの既定のルール（日本の消費税 10% と主な都市の宿泊税）
+func Default() usecase.TaxRuleSource {
+	rules, err := Parse(defaultRules)
+	if err != nil {
+		panic(err)
+	}
+	return staticSource{rules: rules}
+}
+
+func (s staticSource) TaxRules() (*entity.TaxRules, error) {
+	return s.rules, nil
+}
+
+// 設定ファイルのルール。更新日時かサイズが変わっていれば読み直すので、再起動せずにルールを変えられる
+type FileSource struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	rules   *entity.TaxRules
+}
+
+// 起動時に一度読み込み、不正な設定ならエラーにする
+func NewFileSource(path string) (usecase.TaxRuleSource, error) {
+	s := &FileSource{path: path}
+	if _, err := s.TaxRules(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// 読み直しに失敗した場合（編集途中など）は直前のルールを使い続ける
+func (s *FileSource) TaxRules() (*entity.TaxRules, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return s.fallback(err)
+	}
+	if s.rules != nil && fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return s.rules, nil
+	}
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return s.fallback(err)
+	}
+	rules, err := Parse(b)
+	if err != nil {
+		return s.fallback(err)
+	}
+	if s.rules != nil {
+		log.Printf("reloaded tax rules from %s", s.path)
+	}
+	s.rules, s.modTime, s.size = rules, fi.ModTime(), fi.Size()
+	return rules, nil
+}
+
+func (s *FileSource) fallback(err error) (*entity.TaxRules, error) {
+	if s.rules == nil {
+		return nil, fmt.Errorf("load tax rules %s: %w", s.path, err)
+	}
+	log.Printf("tax rules %s: %v (keeping the previous rules)", s.path, err)
+	// 同じ失敗を毎回読み直さないよう、次に変更されるまでは前のルールを返す
+	if fi, statErr := os.Stat(s.path); statErr == nil {
+		s.modTime, s.size = fi.ModTime(), fi.Size()
+	}
+	return s.rules, nil
+}
+
+var _ usecase.TaxRuleSource = (*FileSource)(nil)
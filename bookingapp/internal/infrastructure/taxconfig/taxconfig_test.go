@@ -0,0 +1,70 @@
+package taxconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaultRulesAreValid(t *testing.T) {
+	rules, err := Default().TaxRules()
+	if err != nil {
+		t.Fatalf("Default: %v", err)
+	}
+	for _, code := range []string{"", "tokyo", "osaka", "kyoto"} {
+		if _, err := rules.Jurisdiction(code); err != nil {
+			t.Errorf("Jurisdiction(%q): %v", code, err)
+		}
+	}
+}
+
+func TestFileSourceReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tax.json")
+	write := func(body string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := time.Now().Add(-time.Hour)
+	write(`{"default": "JP", "jurisdictions": {"JP": {"consumption_tax_percent": 8}}}`, base)
+
+	src, err := NewFileSource(path)
+	if err != nil {
+		t.Fatalf("NewFileSource: %v", err)
+	}
+	percent := func() int {
+		t.Helper()
+		rules, err := src.TaxRules()
+		if err != nil {
+			t.Fatalf("TaxRules: %v", err)
+		}
+		j, err := rules.Jurisdiction("")
+		if err != nil {
+			t.Fatalf("Jurisdiction: %v", err)
+		}
+		return j.ConsumptionTaxPercent
+	}
+	if got := percent(); got != 8 {
+		t.Fatalf("consumption tax = %d, want 8", got)
+	}
+
+	write(`{"default": "jp", "jurisdictions": {"jp": {"consumption_tax_percent": 10}}}`, base.Add(time.Minute))
+	if got := percent(); got != 10 {
+		t.Fatalf("consumption tax after edit = %d, want 10", got)
+	}
+
+	// 不正な設定に書き換えても直前のルールを使い続ける
+	write(`{"default": "missing", "jurisdictions": {}}`, base.Add(2*time.Minute))
+	if got := percent(); got != 10 {
+		t.Fatalf("consumption tax after invalid edit = %d, want 10", got)
+	}
+
+	if _, err := NewFileSource(path); err == nil {
+		t.Error("NewFileSource(invalid file) = nil error")
+	}
+}
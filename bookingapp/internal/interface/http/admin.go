@@ -0,0 +1,61 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/usecase"
+	"errors"
+	"net/http"
+)
+
+// 管理者向け API
+type AdminHandler struct {
+	Roles *usecase.RoleUsecase
+}
+
+// PUT /admin/users/{id}/roles/{role}。If-Match にユーザーの ETag が必要
+func (h *AdminHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.Roles.Grant(r.Context(), PrincipalFrom(r.Context()), r.PathValue("id"), entity.Role(r.PathValue("role")), version)
+	if err != nil {
+		writeRoleError(w, r, err)
+		return
+	}
+	setETag(w, user.Version)
+	writeJSON(w, http.StatusOK, toUserView(user))
+}
+
+// DELETE /admin/users/{id}/roles/{role}。If-Match にユーザーの ETag が必要
+func (h *AdminHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.Roles.Revoke(r.Context(), PrincipalFrom(r.Context()), r.PathValue("id"), entity.Role(r.PathValue("role")), version)
+	if err != nil {
+		writeRoleError(w, r, err)
+		return
+	}
+	setETag(w, user.Version)
+	writeJSON(w, http.StatusOK, toUserView(user))
+}
+
+func writeRoleError(w http.ResponseWriter, r *http.Request, err error) {
+	if writeAuthError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrUserNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, usecase.ErrCannotRevokeOwnAdmin):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usecase.ErrConcurrentModification):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
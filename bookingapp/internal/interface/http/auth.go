@@ -0,0 +1,143 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/usecase"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type AuthHandler struct {
+	UC *usecase.AuthUsecase
+}
+
+type loginReq struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type refreshReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokenResp struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // アクセストークンの残り秒数
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var in loginReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	pair, err := h.UC.Login(r.Context(), in.Email, in.Password)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCredentials) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTokenResp(pair))
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var in refreshReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	pair, err := h.UC.Refresh(r.Context(), in.RefreshToken)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUnauthenticated) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTokenResp(pair))
+}
+
+// Require の内側で使う
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	p := PrincipalFrom(r.Context())
+	if p == nil {
+		http.Error(w, usecase.ErrUnauthenticated.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err := h.UC.Logout(r.Context(), p.SessionID); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toTokenResp(p *usecase.TokenPair) tokenResp {
+	return tokenResp{
+		AccessToken:  p.AccessToken,
+		RefreshToken: p.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(p.ExpiresAt).Seconds()),
+	}
+}
+
+type principalKey struct{}
+
+// 認証済みのリクエスト主体。Require を通っていなければ nil
+func PrincipalFrom(ctx context.Context) *usecase.Principal {
+	p, _ := ctx.Value(principalKey{}).(*usecase.Principal)
+	return p
+}
+
+// Authorization: Bearer <token> を検証し、リクエスト主体を context に載せる。無効なら 401。
+// perms を指定した場合はそのすべてを持っていなければ 403
+func (h *AuthHandler) Require(next http.HandlerFunc, perms ...entity.Permission) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer`)
+			http.Error(w, usecase.ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+		p, err := h.UC.Authenticate(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, usecase.ErrUnauthenticated) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		for _, perm := range perms {
+			if !p.Can(perm) {
+				http.Error(w, usecase.ErrForbidden.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+// 認証・認可のエラーを 401 / 403 で返す。該当しなければ何も書かずに false
+func writeAuthError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, usecase.ErrUnauthenticated):
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, usecase.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type CancellationPolicyHandler struct {
+	UC *usecase.CancellationPolicyUsecase
+}
+
+// キャンセルポリシーの登録・更新。rules は順不同
+type cancellationPolicyReq struct {
+	Name  string                 `json:"name"`
+	Rules []cancellationRuleView `json:"rules"`
+}
+
+type cancellationRuleView struct {
+	DaysBefore int `json:"days_before"` // チェックインの何日前以降か（0 は当日・不泊）
+	FeePercent int `json:"fee_percent"`
+}
+
+type cancellationPolicyView struct {
+	ID    int                    `json:"id"`
+	Name  string                 `json:"name"`
+	Rules []cancellationRuleView `json:"rules"`
+}
+
+func (h *CancellationPolicyHandler) List(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.UC.List(r.Context(), PrincipalFrom(r.Context()))
+	if err != nil {
+		writeCancellationPolicyError(w, r, err)
+		return
+	}
+	out := make([]cancellationPolicyView, 0, len(policies))
+	for _, p := range policies {
+		out = append(out, toCancellationPolicyView(p))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *CancellationPolicyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	p, err := h.UC.Get(r.Context(), PrincipalFrom(r.Context()), id)
+	if err != nil {
+		writeCancellationPolicyError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toCancellationPolicyView(p))
+}
+
+func (h *CancellationPolicyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeCancellationPolicyReq(w, r)
+	if !ok {
+		return
+	}
+	p, err := h.UC.Create(r.Context(), PrincipalFrom(r.Context()), in)
+	if err != nil {
+		writeCancellationPolicyError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toCancellationPolicyView(p))
+}
+
+func (h *CancellationPolicyHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	in, ok := decodeCancellationPolicyReq(w, r)
+	if !ok {
+		return
+	}
+	p, err := h.UC.Update(r.Context(), PrincipalFrom(r.Context()), id, in)
+	if err != nil {
+		writeCancellationPolicyError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toCancellationPolicyView(p))
+}
+
+func decodeCancellationPolicyReq(w http.ResponseWriter, r *http.Request) (usecase.CancellationPolicyInput, bool) {
+	var in cancellationPolicyReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return usecase.CancellationPolicyInput{}, false
+	}
+	out := usecase.CancellationPolicyInput{Name: in.Name}
+	for _, rule := range in.Rules {
+		out.Rules = append(out.Rules, entity.CancellationRule{DaysBefore: rule.DaysBefore, FeePercent: rule.FeePercent})
+	}
+	return out, true
+}
+
+func writeCancellationPolicyError(w http.ResponseWriter, r *http.Request, err error) {
+	if writeAuthError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, entity.ErrInvalidCancellationPolicy):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrCancellationPolicyNotFound):
+		http.NotFound(w, r)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func toCancellationPolicyView(p *entity.CancellationPolicy) cancellationPolicyView {
+	v := cancellationPolicyView{ID: p.ID, Name: p.Name, Rules: make([]cancellationRuleView, 0, len(p.Rules))}
+	for _, rule := range p.Rules {
+		v.Rules = append(v.Rules, cancellationRuleView{DaysBefore: rule.DaysBefore, FeePercent: rule.FeePercent})
+	}
+	return v
+}
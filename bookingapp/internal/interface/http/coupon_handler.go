@@ -0,0 +1,180 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type CouponHandler struct {
+	UC *usecase.CouponUsecase
+}
+
+// クーポンの発行・更新。日時は RFC3339（省略時は期間の制限なし）
+type couponReq struct {
+	Code           string   `json:"code"`
+	Name           string   `json:"name"`
+	Discount       string   `json:"discount"` // percent / amount
+	Percent        int      `json:"percent"`
+	Amount         moneyReq `json:"amount"` // 数値だけなら円
+	StartsAt       *string  `json:"starts_at"`
+	EndsAt         *string  `json:"ends_at"`
+	PlanIDs        []int    `json:"plan_ids"` // 省略時はすべてのプラン
+	MaxRedemptions int      `json:"max_redemptions"`
+	PerUserLimit   int      `json:"per_user_limit"`
+}
+
+func (in couponReq) input() (usecase.CouponInput, error) {
+	out := usecase.CouponInput{
+		Code: in.Code, Name: in.Name, Discount: entity.CouponDiscount(in.Discount),
+		Percent: in.Percent, Amount: entity.Money(in.Amount), PlanIDs: in.PlanIDs,
+		MaxRedemptions: in.MaxRedemptions, PerUserLimit: in.PerUserLimit,
+	}
+	var err error
+	if out.StartsAt, err = parseOptionalTime(in.StartsAt); err != nil {
+		return out, errors.New("invalid starts_at (RFC3339)")
+	}
+	if out.EndsAt, err = parseOptionalTime(in.EndsAt); err != nil {
+		return out, errors.New("invalid ends_at (RFC3339)")
+	}
+	return out, nil
+}
+
+type couponView struct {
+	ID             int        `json:"id"`
+	Code           string     `json:"code"`
+	Name           string     `json:"name"`
+	Discount       string     `json:"discount"`
+	Percent        int        `json:"percent,omitempty"`
+	Amount         *moneyView `json:"amount,omitempty"`
+	StartsAt       *string    `json:"starts_at"`
+	EndsAt         *string    `json:"ends_at"`
+	PlanIDs        []int      `json:"plan_ids"`
+	MaxRedemptions int        `json:"max_redemptions"`
+	PerUserLimit   int        `json:"per_user_limit"`
+	Redemptions    int        `json:"redemptions"`
+}
+
+func (h *CouponHandler) List(w http.ResponseWriter, r *http.Request) {
+	coupons, err := h.UC.List(r.Context(), PrincipalFrom(r.Context()))
+	if err != nil {
+		writeCouponError(w, r, err)
+		return
+	}
+	out := make([]couponView, 0, len(coupons))
+	for _, c := range coupons {
+		out = append(out, toCouponView(c))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *CouponHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	c, err := h.UC.Get(r.Context(), PrincipalFrom(r.Context()), id)
+	if err != nil {
+		writeCouponError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toCouponView(c))
+}
+
+func (h *CouponHandler) Create(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeCouponReq(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.UC.Create(r.Context(), PrincipalFrom(r.Context()), in)
+	if err != nil {
+		writeCouponError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toCouponView(c))
+}
+
+func (h *CouponHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	in, ok := decodeCouponReq(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.UC.Update(r.Context(), PrincipalFrom(r.Context()), id, in)
+	if err != nil {
+		writeCouponError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toCouponView(c))
+}
+
+func decodeCouponReq(w http.ResponseWriter, r *http.Request) (usecase.CouponInput, bool) {
+	var in couponReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return usecase.CouponInput{}, false
+	}
+	out, err := in.input()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return usecase.CouponInput{}, false
+	}
+	return out, true
+}
+
+func writeCouponError(w http.ResponseWriter, r *http.Request, err error) {
+	if writeAuthError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, entity.ErrInvalidCoupon), errors.Is(err, entity.ErrInvalidCurrency):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrCouponNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, usecase.ErrDuplicateCoupon):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func toCouponView(c *entity.Coupon) couponView {
+	v := couponView{
+		ID: c.ID, Code: c.Code, Name: c.Name, Discount: string(c.Discount), Percent: c.Percent,
+		PlanIDs: append([]int{}, c.PlanIDs...), MaxRedemptions: c.MaxRedemptions,
+		PerUserLimit: c.PerUserLimit, Redemptions: c.Redemptions,
+	}
+	if c.Discount == entity.CouponAmount {
+		m := toMoneyView(c.Amount)
+		v.Amount = &m
+	}
+	if c.StartsAt != nil {
+		s := c.StartsAt.Format(time.RFC3339)
+		v.StartsAt = &s
+	}
+	if c.EndsAt != nil {
+		s := c.EndsAt.Format(time.RFC3339)
+		v.EndsAt = &s
+	}
+	return v
+}
+
+func parseOptionalTime(s *string) (*time.Time, error) {
+	if s == nil {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
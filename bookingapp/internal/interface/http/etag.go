@@ -0,0 +1,46 @@
+package httpi
+
+import (
+	"bookingapp/internal/usecase"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// リソースのバージョンを ETag として返す
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// If-Match で指定されたバージョン。* の場合は 0（確認しない）。
+// 比較は強い比較なので、弱い ETag（W/"..."）や形式の違う値はどのバージョンとも一致しない
+func ifMatchVersion(r *http.Request) (version int, ok bool) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, true
+	}
+	s, quoted := strings.CutPrefix(v, `"`)
+	s, closed := strings.CutSuffix(s, `"`)
+	if !quoted || !closed {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// 更新に必要な If-Match のバージョンを読む。無ければ 428、どのバージョンとも一致しない値なら 412 を書いて false
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	if strings.TrimSpace(r.Header.Get("If-Match")) == "" {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	version, ok := ifMatchVersion(r)
+	if !ok {
+		http.Error(w, usecase.ErrConcurrentModification.Error(), http.StatusPreconditionFailed)
+		return 0, false
+	}
+	return version, true
+}
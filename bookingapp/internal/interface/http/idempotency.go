@@ -0,0 +1,126 @@
+package httpi
+
+import (
+	"bookingapp/internal/usecase"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Idempotency-Key 付きリクエストの本文の上限
+const maxIdempotentBody = 1 << 20
+
+type IdempotencyHandler struct {
+	UC *usecase.IdempotencyUsecase
+}
+
+// Idempotency-Key ヘッダー付きのリクエストを一度だけ処理する（ヘッダーが無ければそのまま next）。
+// 同じキー・同じ内容の再送には保存したレスポンスを Idempotent-Replayed: true 付きで返し、
+// 同じキーで内容が違えば 422、前のリクエストが処理中なら 409。
+// 処理は UC.ProcessingTimeout で打ち切る。5xx は書き込みをコミットする前の失敗なら保存せず、再送すると改めて処理する。
+// Require の内側で使う
+func (h *IdempotencyHandler) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		p := PrincipalFrom(r.Context())
+		if p == nil {
+			http.Error(w, usecase.ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+		if err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxIdempotentBody {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		rec, err := h.UC.Begin(r.Context(), p.User.ID, key, requestHash(r, body))
+		switch {
+		case errors.Is(err, usecase.ErrInvalidIdempotencyKey):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, usecase.ErrIdempotencyKeyReused):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, usecase.ErrIdempotencyInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if rec.Completed() {
+			if rec.ContentType != "" {
+				w.Header().Set("Content-Type", rec.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(rec.StatusCode)
+			_, _ = w.Write(rec.Body)
+			return
+		}
+
+		// クライアントが切断・タイムアウトしても結果は保存する
+		ctx := context.WithoutCancel(r.Context())
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			// panic した場合も処理中の記録を残さない
+			if !completed {
+				_ = h.UC.Abandon(ctx, rec)
+			}
+		}()
+		// 処理中の記録が放棄されたとみなされる前に打ち切る（再送と同時に処理しないように）
+		nextCtx, cancel := context.WithTimeout(r.Context(), h.UC.ProcessingTimeout())
+		defer cancel()
+		nextCtx, committed := usecase.TrackCommits(nextCtx)
+		next(rw, r.WithContext(nextCtx))
+		// 予約を保存した後の失敗（決済の失敗など）は、再送で予約し直さないよう 5xx でも保存する
+		if rw.status < 500 || committed() {
+			completed = h.UC.Complete(ctx, rec, rw.status, rw.Header().Get("Content-Type"), rw.body.Bytes()) == nil
+		}
+	}
+}
+
+// メソッド・パス・本文の SHA-256。同じキーで別の操作をした場合も内容違いとみなす
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, s := range []string{r.Method, r.URL.Path} {
+		_, _ = io.WriteString(h, strconv.Itoa(len(s))+":"+s)
+	}
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// クライアントに書きつつ、保存用にステータスと本文を控える
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
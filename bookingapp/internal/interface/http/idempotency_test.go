@@ -0,0 +1,68 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/infrastructure/memory"
+	"bookingapp/internal/infrastructure/payment"
+	"bookingapp/internal/usecase"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newIdempotencyHandler(lockTimeout time.Duration) *IdempotencyHandler {
+	return &IdempotencyHandler{UC: &usecase.IdempotencyUsecase{Records: memory.NewIdempotencyRepoMemory(), LockTimeout: lockTimeout}}
+}
+
+func TestIdempotencyStoresFailuresAfterCommit(t *testing.T) {
+	h, owner, _, _ := newReservationServer(t)
+	// 与信が処理の期限（LockTimeout の半分）に間に合わない
+	h.UC.Gateway = payment.NewFakeGateway(payment.FakeConfig{Delay: time.Second})
+	idem := newIdempotencyHandler(200 * time.Millisecond)
+	create := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/reservations",
+			strings.NewReader(`{"plan_id":1,"adults":1,"checkin":"2030-01-01","checkout":"2030-01-02","payment_token":"tok_visa"}`))
+		req.Header.Set("Idempotency-Key", "k1")
+		return serve(idem.Wrap(h.Create), "POST /reservations", owner, req)
+	}
+
+	start := time.Now()
+	if rec := create(); rec.Code != http.StatusBadGateway {
+		t.Fatalf("POST /reservations = %d, want 502", rec.Code)
+	}
+	if d := time.Since(start); d >= time.Second {
+		t.Errorf("request took %s, want it cut off by the processing timeout", d)
+	}
+	// 予約は保存してからキャンセルしたので、再送には同じ 502 を返して予約し直さない
+	rec := create()
+	if rec.Code != http.StatusBadGateway || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry = %d, replayed %q; want the stored 502", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	if list, _ := h.UC.Resv.List(context.Background(), repository.ReservationQuery{}); len(list) != 1 {
+		t.Fatalf("reservations = %d, want 1", len(list))
+	}
+}
+
+func TestIdempotencyRetriesFailuresBeforeCommit(t *testing.T) {
+	_, owner, _, _ := newReservationServer(t)
+	idem := newIdempotencyHandler(time.Minute)
+	calls := 0
+	failing := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/reservations", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "k1")
+		if rec := serve(idem.Wrap(failing), "POST /reservations", owner, req); rec.Code != http.StatusInternalServerError || rec.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatalf("POST = %d, replayed %q; want a fresh 500", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+		}
+	}
+	// 何もコミットしていない 5xx は記録しないので、再送で改めて処理する
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+}
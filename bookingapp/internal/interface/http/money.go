@@ -0,0 +1,40 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// 金額は最小単位の整数と通貨コードで返す（例: {"amount": 12000, "currency": "JPY"}）
+type moneyView struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func toMoneyView(m entity.Money) moneyView {
+	return moneyView{Amount: m.Amount, Currency: string(m.Currency)}
+}
+
+// 金額の入力。{"amount": 9000, "currency": "USD"} のほか、数値だけ（既定の通貨）も受け付ける
+type moneyReq entity.Money
+
+func (m *moneyReq) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] != '{' {
+		var amount int64
+		if err := json.Unmarshal(b, &amount); err != nil {
+			return errors.New("amount must be an integer in minor units")
+		}
+		*m = moneyReq{Amount: amount}
+		return nil
+	}
+	var v moneyView
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	*m = moneyReq{Amount: v.Amount, Currency: entity.Currency(v.Currency)}
+	return nil
+}
@@ -0,0 +1,247 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// プラン検索（誰でも）とプラン管理 API（staff / admin）
+type PlanHandler struct {
+	UC *usecase.PlanUsecase
+}
+
+// 登録・更新とも全項目を指定する
+type planReq struct {
+	Name      string   `json:"name"`
+	Keyword   string   `json:"keyword"`
+	Price     moneyReq `json:"price"` // 数値だけなら円
+	Capacity  int      `json:"capacity"`
+	MinGuests int      `json:"min_guests"` // 省略時は 1
+	MaxGuests int      `json:"max_guests"` // 省略時は 4
+	// 大人の料金に対する %。省略時は小学生 70、幼児 50
+	ChildRate  *int `json:"child_rate"`
+	InfantRate *int `json:"infant_rate"`
+	// 課税地域（税のルールの地域コード）。省略時は既定の地域
+	Jurisdiction string `json:"jurisdiction"`
+	// キャンセルポリシーの ID。省略時はキャンセル料なし
+	CancellationPolicyID int `json:"cancellation_policy_id"`
+}
+
+func (in planReq) input() usecase.PlanInput {
+	return usecase.PlanInput{
+		Name: in.Name, Keyword: in.Keyword, Price: entity.Money(in.Price),
+		Capacity: in.Capacity, MinGuests: in.MinGuests, MaxGuests: in.MaxGuests,
+		ChildRate: in.ChildRate, InfantRate: in.InfantRate, Jurisdiction: in.Jurisdiction,
+		CancellationPolicyID: in.CancellationPolicyID,
+	}
+}
+
+type planView struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Keyword    string    `json:"keyword"`
+	Price      moneyView `json:"price"`
+	Capacity   int       `json:"capacity"`
+	MinGuests  int       `json:"min_guests"`
+	MaxGuests  int       `json:"max_guests"`
+	ChildRate  int       `json:"child_rate"`
+	InfantRate int       `json:"infant_rate"`
+	// 空なら既定の地域
+	Jurisdiction string `json:"jurisdiction"`
+	// 0 ならキャンセル料なし
+	CancellationPolicyID int `json:"cancellation_policy_id"`
+}
+
+// 検索結果の 1 件。score・highlights はキーワードで全文検索した場合だけ
+type planHitView struct {
+	planView
+	Score      float64           `json:"score,omitempty"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type planSearchResp struct {
+	Items      []planHitView `json:"items"`
+	NextOffset int           `json:"next_offset,omitempty"` // 次のページが無ければ省略
+}
+
+// GET /plans?keyword=&currency=&min_price=&max_price=&guests=&checkin=&checkout=&sort=&limit=&offset=
+func (h *PlanHandler) Search(w http.ResponseWriter, r *http.Request) {
+	in, err := parsePlanSearchQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := h.UC.Search(r.Context(), in)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidSearch), errors.Is(err, usecase.ErrInvalidDates),
+			errors.Is(err, usecase.ErrDateRangeTooLong):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	out := make([]planHitView, 0, len(page.Items))
+	for _, it := range page.Items {
+		out = append(out, planHitView{planView: toPlanView(it.Plan), Score: it.Score, Highlights: it.Highlights})
+	}
+	writeJSON(w, http.StatusOK, planSearchResp{Items: out, NextOffset: page.NextOffset})
+}
+
+// GET /plans/{id}（削除済みなら 404）。ETag はプランのバージョン
+func (h *PlanHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	plan, err := h.UC.Get(r.Context(), id)
+	if err != nil {
+		writePlanError(w, r, err)
+		return
+	}
+	setETag(w, plan.Version)
+	writeJSON(w, http.StatusOK, toPlanView(plan))
+}
+
+func (h *PlanHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in planReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	plan, err := h.UC.Create(r.Context(), PrincipalFrom(r.Context()), in.input())
+	if err != nil {
+		writePlanError(w, r, err)
+		return
+	}
+	setETag(w, plan.Version)
+	writeJSON(w, http.StatusCreated, toPlanView(plan))
+}
+
+// If-Match にプランの ETag が必要（他の変更と競合した場合は 412）
+func (h *PlanHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	var in planReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	plan, err := h.UC.Update(r.Context(), PrincipalFrom(r.Context()), id, version, in.input())
+	if err != nil {
+		writePlanError(w, r, err)
+		return
+	}
+	setETag(w, plan.Version)
+	writeJSON(w, http.StatusOK, toPlanView(plan))
+}
+
+// If-Match にプランの ETag が必要（他の変更と競合した場合は 412）
+func (h *PlanHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	if err := h.UC.Delete(r.Context(), PrincipalFrom(r.Context()), id, version); err != nil {
+		writePlanError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePlanError(w http.ResponseWriter, r *http.Request, err error) {
+	if writeAuthError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidPlanName), errors.Is(err, usecase.ErrInvalidPrice),
+		errors.Is(err, usecase.ErrInvalidCapacity), errors.Is(err, usecase.ErrInvalidGuests),
+		errors.Is(err, usecase.ErrInvalidChildRate),
+		errors.Is(err, entity.ErrInvalidCurrency), errors.Is(err, entity.ErrInvalidRateRule),
+		errors.Is(err, entity.ErrUnknownJurisdiction), errors.Is(err, entity.ErrCurrencyMismatch),
+		errors.Is(err, usecase.ErrCancellationPolicyNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrPlanNotFound), errors.Is(err, usecase.ErrRateRuleNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, usecase.ErrConcurrentModification):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func toPlanView(p *entity.Plan) planView {
+	return planView{
+		ID: p.ID, Name: p.Name, Keyword: p.Keyword, Price: toMoneyView(p.Price), Capacity: p.Capacity,
+		MinGuests: p.MinGuests, MaxGuests: p.MaxGuests, ChildRate: p.ChildRate, InfantRate: p.InfantRate,
+		Jurisdiction: p.Jurisdiction, CancellationPolicyID: p.CancellationPolicyID,
+	}
+}
+
+func parsePlanSearchQuery(q url.Values) (usecase.SearchPlansInput, error) {
+	in := usecase.SearchPlansInput{Keyword: q.Get("keyword"), Currency: q.Get("currency"), Sort: q.Get("sort")}
+	prices := []struct {
+		key string
+		dst *int64
+	}{
+		{"min_price", &in.MinPrice},
+		{"max_price", &in.MaxPrice},
+	}
+	for _, p := range prices {
+		if v := q.Get(p.key); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return in, errors.New("invalid " + p.key)
+			}
+			*p.dst = n
+		}
+	}
+	ints := []struct {
+		key string
+		dst *int
+	}{
+		{"guests", &in.Guests},
+		{"limit", &in.Limit},
+		{"offset", &in.Offset},
+	}
+	for _, p := range ints {
+		if v := q.Get(p.key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return in, errors.New("invalid " + p.key)
+			}
+			*p.dst = n
+		}
+	}
+	var err error
+	if v := q.Get("checkin"); v != "" {
+		if in.Checkin, err = parseOptionalDate(&v); err != nil {
+			return in, errors.New("invalid checkin (yyyy-mm-dd)")
+		}
+	}
+	if v := q.Get("checkout"); v != "" {
+		if in.Checkout, err = parseOptionalDate(&v); err != nil {
+			return in, errors.New("invalid checkout (yyyy-mm-dd)")
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,161 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/infrastructure/memory"
+	"bookingapp/internal/infrastructure/taxconfig"
+	"bookingapp/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// インメモリのリポジトリで組んだプランのハンドラーと、プランを管理できるスタッフを返す
+func newPlanServer(t *testing.T) (*PlanHandler, *usecase.Principal) {
+	t.Helper()
+	plans := memory.NewPlanRepoMemory([]*entity.Plan{
+		{ID: 1, Name: "富士プレミアム", Keyword: "富士", Price: entity.Yen(10000), Capacity: 5},
+	})
+	staff := &usecase.Principal{User: &entity.User{ID: "s1"}, Permissions: entity.DefaultRolePermissions[entity.RoleStaff]}
+	return &PlanHandler{UC: &usecase.PlanUsecase{Plans: plans}}, staff
+}
+
+func TestPlanIfMatch(t *testing.T) {
+	h, staff := newPlanServer(t)
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/plans/1", strings.NewReader(`{"name":"富士プレミアム（改）","price":11000,"capacity":5}`))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return serve(h.Update, "PUT /plans/{id}", staff, req)
+	}
+	del := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/plans/1", nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return serve(h.Delete, "DELETE /plans/{id}", staff, req)
+	}
+
+	rec := serve(h.Get, "GET /plans/{id}", nil, httptest.NewRequest(http.MethodGet, "/plans/1", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET = %d, ETag %q; want 200, \"1\"", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := update(""); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("PUT without If-Match = %d, want 428", rec.Code)
+	}
+	if rec := del(""); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("DELETE without If-Match = %d, want 428", rec.Code)
+	}
+	rec = update(`"1"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT = %d, ETag %q; want 200, \"2\"", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := update(`"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with stale ETag = %d, want 412", rec.Code)
+	}
+	if rec := del(`"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with stale ETag = %d, want 412", rec.Code)
+	}
+	if rec := del(`"2"`); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE = %d, want 204", rec.Code)
+	}
+}
+
+func TestPlanManagement(t *testing.T) {
+	h, staff := newPlanServer(t)
+	guest := &usecase.Principal{User: &entity.User{ID: "g1"}, Permissions: entity.DefaultRolePermissions[entity.RoleGuest]}
+	create := func(p *usecase.Principal, body string) *httptest.ResponseRecorder {
+		return serve(h.Create, "POST /plans", p, httptest.NewRequest(http.MethodPost, "/plans", strings.NewReader(body)))
+	}
+
+	rec := create(staff, `{"name":" 湖畔の宿 ","keyword":"湖","price":9000,"capacity":2}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("POST /plans = %d, ETag %q; want 201, \"1\"", rec.Code, rec.Header().Get("ETag"))
+	}
+	var created planView
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// 省略した項目は既定値
+	if created.ID <= 1 || created.Name != "湖畔の宿" || created.Price.Amount != 9000 || created.Price.Currency != "JPY" ||
+		created.MinGuests != 1 || created.MaxGuests != entity.DefaultMaxGuests || created.ChildRate != entity.DefaultChildRate {
+		t.Fatalf("created = %+v", created)
+	}
+
+	invalid := []string{
+		`{"name":" ","price":9000,"capacity":2}`,
+		`{"name":"x","price":0,"capacity":2}`,
+		`{"name":"x","price":9000,"capacity":0}`,
+		`{"name":"x","price":9000,"capacity":1,"min_guests":3,"max_guests":2}`,
+		`{"name":"x","price":9000,"capacity":1,"child_rate":101}`,
+		`{"name":"x","price":{"amount":9000,"currency":"GBP"},"capacity":1}`,
+		`{"name":`,
+	}
+	for _, body := range invalid {
+		if rec := create(staff, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST /plans %s = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := create(guest, `{"name":"x","price":9000,"capacity":1}`); rec.Code != http.StatusForbidden {
+		t.Errorf("POST /plans as guest = %d, want 403", rec.Code)
+	}
+
+	path := "/plans/" + strconv.Itoa(created.ID)
+	req := httptest.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Match", `"1"`)
+	if rec := serve(h.Delete, "DELETE /plans/{id}", staff, req); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d, want 204", rec.Code)
+	}
+	// 削除済みのプランは取得・更新・検索の対象外
+	if rec := serve(h.Get, "GET /plans/{id}", nil, httptest.NewRequest(http.MethodGet, path, nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("GET deleted plan = %d, want 404", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"name":"x","price":9000,"capacity":1}`))
+	req.Header.Set("If-Match", "*")
+	if rec := serve(h.Update, "PUT /plans/{id}", staff, req); rec.Code != http.StatusNotFound {
+		t.Errorf("PUT deleted plan = %d, want 404", rec.Code)
+	}
+	rec = serve(h.Search, "GET /plans", nil, httptest.NewRequest(http.MethodGet, "/plans?keyword=湖", nil))
+	var page planSearchResp
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil || len(page.Items) != 0 {
+		t.Errorf("search after delete = %+v, %v; want no items", page, err)
+	}
+	if rec := serve(h.Get, "GET /plans/{id}", nil, httptest.NewRequest(http.MethodGet, "/plans/abc", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /plans/abc = %d, want 400", rec.Code)
+	}
+}
+
+func TestPlanJurisdiction(t *testing.T) {
+	h, staff := newPlanServer(t)
+	h.UC.Taxes = taxconfig.Default()
+	create := func(body string) int {
+		return serve(h.Create, "POST /plans", staff, httptest.NewRequest(http.MethodPost, "/plans", strings.NewReader(body))).Code
+	}
+
+	cases := []struct {
+		body string
+		want int
+	}{
+		{`{"name":"x","price":9000,"capacity":1,"jurisdiction":"tokyo"}`, http.StatusCreated},
+		// 宿泊税の無い地域はどの通貨でもよい
+		{`{"name":"x","price":{"amount":100,"currency":"USD"},"capacity":1}`, http.StatusCreated},
+		{`{"name":"x","price":9000,"capacity":1,"jurisdiction":"mars"}`, http.StatusBadRequest},
+		// 円建ての宿泊税の地域にドル建てのプランは置けない
+		{`{"name":"x","price":{"amount":100,"currency":"USD"},"capacity":1,"jurisdiction":"tokyo"}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		if got := create(c.body); got != c.want {
+			t.Errorf("POST /plans %s = %d, want %d", c.body, got, c.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/plans/1", strings.NewReader(`{"name":"x","price":{"amount":100,"currency":"USD"},"capacity":1,"jurisdiction":"kyoto"}`))
+	req.Header.Set("If-Match", `"1"`)
+	if rec := serve(h.Update, "PUT /plans/{id}", staff, req); rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT USD plan in kyoto = %d, want 400", rec.Code)
+	}
+}
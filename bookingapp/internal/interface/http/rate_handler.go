@@ -0,0 +1,157 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// プランの料金ルール。日付は yyyy-mm-dd（省略時は期間の制限なし）
+type rateReq struct {
+	Name       string   `json:"name"`
+	StartDate  *string  `json:"start_date"`
+	EndDate    *string  `json:"end_date"`
+	Days       []string `json:"days"` // sun〜sat / holiday / holiday_eve。省略時は毎日
+	Adjustment string   `json:"adjustment"`
+	Amount     int64    `json:"amount"`
+	Percent    int      `json:"percent"`
+	Priority   int      `json:"priority"`
+}
+
+func (in rateReq) rule() (entity.RateRule, error) {
+	rule := entity.RateRule{
+		Name:       in.Name,
+		Adjustment: entity.RateAdjustment(in.Adjustment),
+		Amount:     in.Amount,
+		Percent:    in.Percent,
+		Priority:   in.Priority,
+	}
+	var err error
+	if rule.StartDate, err = parseOptionalDate(in.StartDate); err != nil {
+		return rule, errors.New("invalid start_date (yyyy-mm-dd)")
+	}
+	if rule.EndDate, err = parseOptionalDate(in.EndDate); err != nil {
+		return rule, errors.New("invalid end_date (yyyy-mm-dd)")
+	}
+	for _, d := range in.Days {
+		rule.Days = append(rule.Days, entity.RateDay(d))
+	}
+	return rule, nil
+}
+
+type rateView struct {
+	ID         int      `json:"id"`
+	PlanID     int      `json:"plan_id"`
+	Name       string   `json:"name"`
+	StartDate  *string  `json:"start_date"`
+	EndDate    *string  `json:"end_date"`
+	Days       []string `json:"days"`
+	Adjustment string   `json:"adjustment"`
+	Amount     int64    `json:"amount"`
+	Percent    int      `json:"percent"`
+	Priority   int      `json:"priority"`
+}
+
+func (h *PlanHandler) ListRates(w http.ResponseWriter, r *http.Request) {
+	planID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	rules, err := h.UC.ListRates(r.Context(), PrincipalFrom(r.Context()), planID)
+	if err != nil {
+		writePlanError(w, r, err)
+		return
+	}
+	out := make([]rateView, 0, len(rules))
+	for i := range rules {
+		out = append(out, toRateView(&rules[i]))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *PlanHandler) CreateRate(w http.ResponseWriter, r *http.Request) {
+	planID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	rule, ok := decodeRateReq(w, r)
+	if !ok {
+		return
+	}
+	saved, err := h.UC.CreateRate(r.Context(), PrincipalFrom(r.Context()), planID, rule)
+	if err != nil {
+		writePlanError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toRateView(saved))
+}
+
+func (h *PlanHandler) UpdateRate(w http.ResponseWriter, r *http.Request) {
+	planID, err1 := strconv.Atoi(r.PathValue("id"))
+	ruleID, err2 := strconv.Atoi(r.PathValue("rateID"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	rule, ok := decodeRateReq(w, r)
+	if !ok {
+		return
+	}
+	saved, err := h.UC.UpdateRate(r.Context(), PrincipalFrom(r.Context()), planID, ruleID, rule)
+	if err != nil {
+		writePlanError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toRateView(saved))
+}
+
+func (h *PlanHandler) DeleteRate(w http.ResponseWriter, r *http.Request) {
+	planID, err1 := strconv.Atoi(r.PathValue("id"))
+	ruleID, err2 := strconv.Atoi(r.PathValue("rateID"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := h.UC.DeleteRate(r.Context(), PrincipalFrom(r.Context()), planID, ruleID); err != nil {
+		writePlanError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeRateReq(w http.ResponseWriter, r *http.Request) (entity.RateRule, bool) {
+	var in rateReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return entity.RateRule{}, false
+	}
+	rule, err := in.rule()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return entity.RateRule{}, false
+	}
+	return rule, true
+}
+
+func toRateView(r *entity.RateRule) rateView {
+	v := rateView{
+		ID: r.ID, PlanID: r.PlanID, Name: r.Name, Days: make([]string, 0, len(r.Days)),
+		Adjustment: string(r.Adjustment), Amount: r.Amount, Percent: r.Percent, Priority: r.Priority,
+	}
+	if r.StartDate != nil {
+		s := r.StartDate.Format("2006-01-02")
+		v.StartDate = &s
+	}
+	if r.EndDate != nil {
+		s := r.EndDate.Format("2006-01-02")
+		v.EndDate = &s
+	}
+	for _, d := range r.Days {
+		v.Days = append(v.Days, string(d))
+	}
+	return v
+}
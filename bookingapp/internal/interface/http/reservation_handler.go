@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -15,12 +16,34 @@ type ReservationHandler struct {
 	UC *usecase.ReservationUsecase
 }
 
+// 予約者はアクセストークンのユーザー（body では指定しない）
 type createReq struct {
-	UserID   string `json:"user_id"`
 	PlanID   int    `json:"plan_id"`
-	Number   int    `json:"number"`
+	Adults   *int   `json:"adults"`
+	Children []int  `json:"children"` // 子ども（12歳以下）の年齢
+	Number   int    `json:"number"`   // 旧形式。adults が無ければ大人の人数として扱う
 	Checkin  string `json:"checkin"`  // "2025-10-12"
 	Checkout string `json:"checkout"` // "2025-10-13"
+	// 任意。大文字・小文字は区別しない
+	CouponCode string `json:"coupon_code"`
+	// 決済代行に渡す支払い手段（カードのトークンなど）
+	PaymentToken string `json:"payment_token"`
+}
+
+// 指定された項目だけを変更する
+type modifyReq struct {
+	Checkin  *string `json:"checkin"`
+	Checkout *string `json:"checkout"`
+	Adults   *int    `json:"adults"`
+	Children *[]int  `json:"children"`
+	Number   *int    `json:"number"` // 旧形式。大人だけの人数に置き換える
+	// 合計金額が与信額を超える場合に与信を取り直す支払い手段
+	PaymentToken string `json:"payment_token"`
+}
+
+type reservationListResp struct {
+	Items      []reservationView `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"` // 次のページが無ければ省略
 }
 
 type createResp struct {
@@ -28,17 +51,71 @@ type createResp struct {
 }
 
 type reservationView struct {
-	ID       int    `json:"id"`
-	UserID   string `json:"user_id"`
-	PlanID   int    `json:"plan_id"`
-	Number   int    `json:"number"`
-	Checkin  string `json:"checkin"`
-	Checkout string `json:"checkout"`
-	Total    int    `json:"total"`
-	Nights   int    `json:"nights"`
+	ID       int        `json:"id"`
+	UserID   string     `json:"user_id"`
+	PlanID   int        `json:"plan_id"`
+	Number   int        `json:"number"` // 合計人数
+	Guests   guestsView `json:"guests"`
+	Checkin  string     `json:"checkin"`
+	Checkout string     `json:"checkout"`
+	// 税抜の小計（割引後）、消費税、宿泊税と、それらの合計
+	Subtotal         moneyView `json:"subtotal"`
+	ConsumptionTax   moneyView `json:"consumption_tax"`
+	AccommodationTax moneyView `json:"accommodation_tax"`
+	Total            moneyView `json:"total"`
+	Nights           int       `json:"nights"`
+	Status           string    `json:"status"`
+	// クーポンを使った予約だけ
+	CouponCode string     `json:"coupon_code,omitempty"`
+	Discount   *moneyView `json:"discount,omitempty"`
+	// 予約時点のキャンセルポリシー（無ければ省略）と、キャンセル済みの予約のキャンセル料
+	CancellationPolicy *cancellationPolicyView `json:"cancellation_policy,omitempty"`
+	CancellationFee    *moneyView              `json:"cancellation_fee,omitempty"`
+	// 泊ごとの料金（一覧にも含める）と決済（一覧では省略）
+	Breakdown []nightChargeView `json:"breakdown,omitempty"`
+	Payment   *paymentView      `json:"payment,omitempty"`
+}
+
+type paymentView struct {
+	Status        string    `json:"status"`
+	Amount        moneyView `json:"amount"` // 与信額
+	Captured      moneyView `json:"captured"`
+	Refunded      moneyView `json:"refunded"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+}
+
+// 今キャンセルした場合の料金
+type cancellationQuoteView struct {
+	ReservationID int `json:"reservation_id"`
+	// 予約時点のキャンセルポリシー（無ければ null。キャンセル料はかからない）
+	Policy     *cancellationPolicyView `json:"policy"`
+	DaysBefore int                     `json:"days_before_checkin"` // 当日は 0、過ぎていれば負
+	FeePercent int                     `json:"fee_percent"`
+	Total      moneyView               `json:"total"`
+	Fee        moneyView               `json:"fee"`
+	Refund     moneyView               `json:"refund"` // 与信の取り消し・返金の対象になる額
+}
+
+type guestsView struct {
+	Adults   int `json:"adults"`
+	Children int `json:"children"` // 小学生
+	Infants  int `json:"infants"`  // 幼児
+}
+
+type nightChargeView struct {
+	Date       string    `json:"date"`
+	Rate       moneyView `json:"rate"` // 大人1人あたり
+	ChildRate  moneyView `json:"child_rate"`
+	InfantRate moneyView `json:"infant_rate"`
+	Amount     moneyView `json:"amount"` // 全員分
 }
 
 func (h *ReservationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	p := PrincipalFrom(r.Context())
+	if p == nil {
+		http.Error(w, usecase.ErrUnauthenticated.Error(), http.StatusUnauthorized)
+		return
+	}
 	var in createReq
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
@@ -50,19 +127,43 @@ func (h *ReservationHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid date format (yyyy-mm-dd)", http.StatusBadRequest)
 		return
 	}
-	res, err := h.UC.Create(in.UserID, in.PlanID, in.Number, ci, co)
+	adults := in.Number
+	if in.Adults != nil {
+		adults = *in.Adults
+	}
+	guests, err := entity.GuestsOf(adults, in.Children)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := h.UC.Create(r.Context(), p.User.ID, usecase.CreateReservationInput{
+		PlanID: in.PlanID, Guests: guests, Checkin: ci, Checkout: co, CouponCode: in.CouponCode,
+		PaymentToken: in.PaymentToken,
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, usecase.ErrInvalidUserID):
 			http.Error(w, err.Error(), http.StatusBadRequest)
 		case errors.Is(err, usecase.ErrUserNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, usecase.ErrInvalidDates):
+		case errors.Is(err, usecase.ErrInvalidDates), errors.Is(err, usecase.ErrStayTooLong):
 			http.Error(w, err.Error(), http.StatusBadRequest)
-		case errors.Is(err, usecase.ErrInvalidNumber):
+		case errors.Is(err, usecase.ErrInvalidNumber), errors.Is(err, usecase.ErrTooManyGuests),
+			errors.Is(err, usecase.ErrTooFewGuests), errors.Is(err, entity.ErrMoneyOverflow):
 			http.Error(w, err.Error(), http.StatusBadRequest)
 		case errors.Is(err, usecase.ErrPlanNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, usecase.ErrCouponNotFound), errors.Is(err, entity.ErrCouponNotApplicable):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		// 与信を待つ間にキャンセルされた予約は ErrInvalidStatusTransition
+		case errors.Is(err, usecase.ErrSoldOut), errors.Is(err, usecase.ErrCouponExhausted),
+			errors.Is(err, usecase.ErrCouponUserLimit), errors.Is(err, entity.ErrInvalidStatusTransition):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, usecase.ErrPaymentDeclined), errors.Is(err, usecase.ErrPaymentFailed):
+			writePaymentError(w, err)
+		// 設定変更などでプランの課税地域・通貨が今の税のルールと合わなくなった
+		case errors.Is(err, entity.ErrUnknownJurisdiction), errors.Is(err, entity.ErrCurrencyMismatch):
+			http.Error(w, err.Error(), http.StatusConflict)
 		default:
 			http.Error(w, "internal error", http.StatusInternalServerError)
 		}
@@ -78,51 +179,364 @@ func (h *ReservationHandler) Get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid id", http.StatusBadRequest)
 		return
 	}
-	res, _ := h.UC.Get(id)
+	res, err := h.UC.Get(r.Context(), PrincipalFrom(r.Context()), id)
+	if err != nil {
+		if !writeAuthError(w, err) {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
 	if res == nil {
 		http.NotFound(w, r)
 		return
 	}
-	writeJSON(w, http.StatusOK, toView(res))
+	h.writeDetail(w, r, res)
 }
 
+// If-Match に予約の ETag が必要（他の変更と競合した場合は 412）
+func (h *ReservationHandler) Modify(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	var in modifyReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	ci, err1 := parseOptionalDate(in.Checkin)
+	co, err2 := parseOptionalDate(in.Checkout)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid date format (yyyy-mm-dd)", http.StatusBadRequest)
+		return
+	}
+	mod := usecase.ModifyReservationInput{
+		Checkin: ci, Checkout: co, Adults: in.Adults, ChildAges: in.Children, PaymentToken: in.PaymentToken,
+	}
+	if in.Number != nil && in.Adults == nil && in.Children == nil {
+		mod.Adults, mod.ChildAges = in.Number, &[]int{}
+	}
+	res, err := h.UC.Modify(r.Context(), PrincipalFrom(r.Context()), id, version, mod)
+	if err != nil {
+		if writeAuthError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, usecase.ErrReservationNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, usecase.ErrInvalidDates), errors.Is(err, usecase.ErrStayTooLong), errors.Is(err, usecase.ErrInvalidNumber),
+			errors.Is(err, usecase.ErrTooManyGuests), errors.Is(err, usecase.ErrTooFewGuests),
+			errors.Is(err, entity.ErrInvalidAge), errors.Is(err, entity.ErrMoneyOverflow),
+			errors.Is(err, entity.ErrCouponNotApplicable):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, usecase.ErrReservationNotModifiable), errors.Is(err, usecase.ErrSoldOut),
+			errors.Is(err, usecase.ErrPlanDeleted):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, usecase.ErrPlanNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, usecase.ErrConcurrentModification):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, usecase.ErrPaymentInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, usecase.ErrPaymentDeclined), errors.Is(err, usecase.ErrPaymentFailed):
+			writePaymentError(w, err)
+		// 設定変更などでプランの課税地域・通貨が今の税のルールと合わなくなった
+		case errors.Is(err, entity.ErrUnknownJurisdiction), errors.Is(err, entity.ErrCurrencyMismatch):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.writeDetail(w, r, res)
+}
+
+// If-Match に予約の ETag が必要（他の変更と競合した場合は 412）
+func (h *ReservationHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.UC.Cancel(r.Context(), PrincipalFrom(r.Context()), id, version)
+	if err != nil {
+		if writeAuthError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, usecase.ErrReservationNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, entity.ErrInvalidStatusTransition):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, usecase.ErrConcurrentModification):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, usecase.ErrPaymentInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, usecase.ErrPaymentDeclined), errors.Is(err, usecase.ErrPaymentFailed):
+			writePaymentError(w, err)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.writeDetail(w, r, res)
+}
+
+// 今キャンセルした場合のキャンセル料と返金額
+func (h *ReservationHandler) CancellationQuote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	res, q, err := h.UC.CancellationQuote(r.Context(), PrincipalFrom(r.Context()), id)
+	if err != nil {
+		if writeAuthError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, usecase.ErrReservationNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, entity.ErrInvalidStatusTransition):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	v := cancellationQuoteView{
+		ReservationID: res.ID, DaysBefore: q.DaysBefore, FeePercent: q.FeePercent,
+		Total: toMoneyView(res.Total), Fee: toMoneyView(q.Fee), Refund: toMoneyView(q.Refund),
+	}
+	if res.CancellationPolicy != nil {
+		p := toCancellationPolicyView(res.CancellationPolicy)
+		v.Policy = &p
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// 与信済みの決済を請求する（staff / admin）。If-Match に予約の ETag が必要
+func (h *ReservationHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	pay, err := h.UC.CapturePayment(r.Context(), PrincipalFrom(r.Context()), id, version)
+	if err != nil {
+		if writeAuthError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, usecase.ErrReservationNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, usecase.ErrPaymentNotCapturable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, usecase.ErrConcurrentModification):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, usecase.ErrPaymentInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, usecase.ErrPaymentFailed):
+			writePaymentError(w, err)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, toPaymentView(pay))
+}
+
+// 予約の詳細（内訳と決済を含む）。ETag は予約のバージョン
+func (h *ReservationHandler) writeDetail(w http.ResponseWriter, r *http.Request, res *entity.Reservation) {
+	v := toView(res)
+	pay, err := h.UC.Payment(r.Context(), PrincipalFrom(r.Context()), res.ID)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if pay != nil {
+		pv := toPaymentView(pay)
+		v.Payment = &pv
+	}
+	setETag(w, res.Version)
+	writeJSON(w, http.StatusOK, v)
+}
+
+// 断られた場合は 402、決済代行の障害は 502
+func writePaymentError(w http.ResponseWriter, err error) {
+	if errors.Is(err, usecase.ErrPaymentDeclined) {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
+
+func toPaymentView(p *entity.Payment) paymentView {
+	return paymentView{
+		Status: string(p.Status), Amount: toMoneyView(p.Amount),
+		Captured: toMoneyView(p.Captured), Refunded: toMoneyView(p.Refunded),
+		FailureReason: p.FailureReason,
+	}
+}
+
+// GET /reservations?user_id=&plan_id=&status=&checkin_from=&checkin_to=&sort=&limit=&cursor=
 func (h *ReservationHandler) List(w http.ResponseWriter, r *http.Request) {
-	list, _ := h.UC.List()
-	views := make([]reservationView, 0, len(list))
-	for _, v := range list {
+	in, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := h.UC.List(r.Context(), PrincipalFrom(r.Context()), in)
+	if err != nil {
+		if writeAuthError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, usecase.ErrInvalidListQuery), errors.Is(err, usecase.ErrInvalidCursor):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	views := make([]reservationView, 0, len(page.Items))
+	for _, v := range page.Items {
 		views = append(views, toView(v))
 	}
-	writeJSON(w, http.StatusOK, views)
+	writeJSON(w, http.StatusOK, reservationListResp{Items: views, NextCursor: page.NextCursor})
 }
 
-func (h *ReservationHandler) SearchPlans(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query().Get("keyword")
-	plans, _ := h.UC.SearchPlans(q)
-	type planView struct {
-		ID      int    `json:"id"`
-		Name    string `json:"name"`
-		Keyword string `json:"keyword"`
-		Price   int    `json:"price"`
+func (h *ReservationHandler) PlanAvailability(w http.ResponseWriter, r *http.Request) {
+	planID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	from, err1 := time.Parse("2006-01-02", q.Get("from"))
+	to, err2 := time.Parse("2006-01-02", q.Get("to"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid date format (yyyy-mm-dd)", http.StatusBadRequest)
+		return
+	}
+	nights, err := h.UC.Availability(r.Context(), planID, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidDates), errors.Is(err, usecase.ErrDateRangeTooLong):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, usecase.ErrPlanNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	type nightView struct {
+		Date      string    `json:"date"`
+		Remaining int       `json:"remaining"`
+		Price     moneyView `json:"price"`
 	}
-	out := make([]planView, 0, len(plans))
-	for _, p := range plans {
-		out = append(out, planView{ID: p.ID, Name: p.Name, Keyword: p.Keyword, Price: p.Price})
+	out := make([]nightView, 0, len(nights))
+	for _, n := range nights {
+		out = append(out, nightView{Date: n.Date.Format("2006-01-02"), Remaining: n.Remaining, Price: toMoneyView(n.Price)})
 	}
 	writeJSON(w, http.StatusOK, out)
 }
 
 // ここを *entity.Reservation にする（別型を作らない）
 func toView(r *entity.Reservation) reservationView {
-	return reservationView{
-		ID:       r.ID,
-		UserID:   r.UserID,
-		PlanID:   r.PlanID,
-		Number:   r.Number,
-		Checkin:  r.Checkin.Format("2006-01-02"),
-		Checkout: r.Checkout.Format("2006-01-02"),
-		Total:    r.Total,
-		Nights:   r.Nights(), // entity に既にあるメソッドを使う
+	v := reservationView{
+		ID:               r.ID,
+		UserID:           r.UserID,
+		PlanID:           r.PlanID,
+		Number:           r.Number,
+		Guests:           guestsView{Adults: r.Guests.Adults, Children: r.Guests.Children, Infants: r.Guests.Infants},
+		Checkin:          r.Checkin.Format("2006-01-02"),
+		Checkout:         r.Checkout.Format("2006-01-02"),
+		Subtotal:         toMoneyView(r.Subtotal),
+		ConsumptionTax:   toMoneyView(r.ConsumptionTax),
+		AccommodationTax: toMoneyView(r.AccommodationTax),
+		Total:            toMoneyView(r.Total),
+		Nights:           r.Nights(), // entity に既にあるメソッドを使う
+		Status:           string(r.Status),
+	}
+	if r.CouponID != 0 {
+		d := toMoneyView(r.Discount)
+		v.CouponCode, v.Discount = r.CouponCode, &d
+	}
+	if r.CancellationPolicy != nil {
+		p := toCancellationPolicyView(r.CancellationPolicy)
+		v.CancellationPolicy = &p
+	}
+	if r.Status == entity.ReservationCancelled {
+		f := toMoneyView(r.CancellationFee)
+		v.CancellationFee = &f
+	}
+	for _, n := range r.Breakdown {
+		v.Breakdown = append(v.Breakdown, nightChargeView{
+			Date:       n.Date.Format("2006-01-02"),
+			Rate:       toMoneyView(n.Rate),
+			ChildRate:  toMoneyView(n.ChildRate),
+			InfantRate: toMoneyView(n.InfantRate),
+			Amount:     toMoneyView(n.Amount),
+		})
+	}
+	return v
+}
+
+func parseListQuery(q url.Values) (usecase.ListReservationsInput, error) {
+	in := usecase.ListReservationsInput{
+		UserID: q.Get("user_id"),
+		Status: q.Get("status"),
+		Sort:   q.Get("sort"),
+		Cursor: q.Get("cursor"),
+	}
+	var err error
+	if v := q.Get("plan_id"); v != "" {
+		if in.PlanID, err = strconv.Atoi(v); err != nil {
+			return in, errors.New("invalid plan_id")
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if in.Limit, err = strconv.Atoi(v); err != nil {
+			return in, errors.New("invalid limit")
+		}
+	}
+	if v := q.Get("checkin_from"); v != "" {
+		if in.CheckinFrom, err = parseOptionalDate(&v); err != nil {
+			return in, errors.New("invalid checkin_from (yyyy-mm-dd)")
+		}
+	}
+	if v := q.Get("checkin_to"); v != "" {
+		if in.CheckinTo, err = parseOptionalDate(&v); err != nil {
+			return in, errors.New("invalid checkin_to (yyyy-mm-dd)")
+		}
+	}
+	return in, nil
+}
+
+func parseOptionalDate(s *string) (*time.Time, error) {
+	if s == nil {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", *s)
+	if err != nil {
+		return nil, err
 	}
+	return &t, nil
 }
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
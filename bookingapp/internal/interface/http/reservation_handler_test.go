@@ -0,0 +1,309 @@
+package httpi
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/gateway"
+	"bookingapp/internal/infrastructure/memory"
+	"bookingapp/internal/infrastructure/taxconfig"
+	"bookingapp/internal/usecase"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// インメモリのリポジトリで組んだ予約のハンドラーと、ゲスト 2 人・スタッフを返す
+func newReservationServer(t *testing.T) (h *ReservationHandler, owner, other, staff *usecase.Principal) {
+	t.Helper()
+	plans := memory.NewPlanRepoMemory([]*entity.Plan{
+		{ID: 1, Name: "富士プレミアム", Price: entity.Yen(10000), Capacity: 5, MinGuests: 1, MaxGuests: 4},
+	})
+	reservations := memory.NewReservationRepoMemory()
+	users := memory.NewUserRepoMemory()
+	coupons := memory.NewCouponRepoMemory()
+	payments := memory.NewPaymentRepoMemory()
+	principal := func(email string, role entity.Role) *usecase.Principal {
+		u, err := users.Create(context.Background(), &entity.User{Name: email, Email: email, Roles: []entity.Role{role}})
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		return &usecase.Principal{User: u, Permissions: entity.DefaultRolePermissions[role]}
+	}
+	h = &ReservationHandler{UC: &usecase.ReservationUsecase{
+		Plans: plans, Resv: reservations, Users: users, Coupons: coupons, Payments: payments,
+		Tx: memory.NewUnitOfWork(plans, reservations, users, coupons, payments),
+	}}
+	return h, principal("owner@example.com", entity.RoleGuest), principal("other@example.com", entity.RoleGuest), principal("staff@example.com", entity.RoleStaff)
+}
+
+// pattern に登録した handler を、p が認証済みの状態で呼ぶ
+func serve(handler http.HandlerFunc, pattern string, p *usecase.Principal, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func createReservation(t *testing.T, h *ReservationHandler, p *usecase.Principal) *entity.Reservation {
+	t.Helper()
+	in := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	r, err := h.UC.Create(context.Background(), p.User.ID, usecase.CreateReservationInput{
+		PlanID: 1, Guests: entity.Guests{Adults: 2}, Checkin: in, Checkout: in.AddDate(0, 0, 2),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return r
+}
+
+func TestReservationGetHidesOthersReservations(t *testing.T) {
+	h, owner, other, staff := newReservationServer(t)
+	res := createReservation(t, h, owner)
+	path := "/reservations/" + strconv.Itoa(res.ID)
+
+	cases := []struct {
+		name string
+		p    *usecase.Principal
+		path string
+		want int
+	}{
+		{"owner", owner, path, http.StatusOK},
+		{"staff", staff, path, http.StatusOK},
+		// 他人の予約と存在しない予約を区別できない
+		{"other guest", other, path, http.StatusNotFound},
+		{"missing", owner, "/reservations/999", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		rec := serve(h.Get, "GET /reservations/", c.p, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rec.Code != c.want {
+			t.Errorf("GET %s as %s = %d, want %d", c.path, c.name, rec.Code, c.want)
+		}
+	}
+
+	// 変更・キャンセルも同じ
+	req := httptest.NewRequest(http.MethodPost, path+"/cancel", nil)
+	req.Header.Set("If-Match", `"1"`)
+	if rec := serve(h.Cancel, "POST /reservations/{id}/cancel", other, req); rec.Code != http.StatusNotFound {
+		t.Errorf("cancel as other guest = %d, want 404", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodPatch, path, strings.NewReader(`{"adults":1}`))
+	req.Header.Set("If-Match", `"1"`)
+	if rec := serve(h.Modify, "PATCH /reservations/{id}", other, req); rec.Code != http.StatusNotFound {
+		t.Errorf("modify as other guest = %d, want 404", rec.Code)
+	}
+}
+
+func TestReservationIfMatch(t *testing.T) {
+	h, owner, _, staff := newReservationServer(t)
+	res := createReservation(t, h, owner)
+	path := "/reservations/" + strconv.Itoa(res.ID)
+
+	rec := serve(h.Get, "GET /reservations/", owner, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET = %d, ETag %q; want 200, \"1\"", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	type call struct {
+		name    string
+		handler http.HandlerFunc
+		pattern string
+		method  string
+		path    string
+		body    string
+		p       *usecase.Principal
+	}
+	modify := call{"modify", h.Modify, "PATCH /reservations/{id}", http.MethodPatch, path, `{"adults":1}`, owner}
+	cancel := call{"cancel", h.Cancel, "POST /reservations/{id}/cancel", http.MethodPost, path + "/cancel", "", owner}
+	capture := call{"capture", h.Capture, "POST /reservations/{id}/capture", http.MethodPost, path + "/capture", "", staff}
+	do := func(c call, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return serve(c.handler, c.pattern, c.p, req)
+	}
+
+	// どの更新も If-Match が無ければ 428、形式の違う・弱い ETag は 412
+	for _, c := range []call{modify, cancel, capture} {
+		if rec := do(c, ""); rec.Code != http.StatusPreconditionRequired {
+			t.Errorf("%s without If-Match = %d, want 428", c.name, rec.Code)
+		}
+		for _, v := range []string{`W/"1"`, `1`, `"x"`} {
+			if rec := do(c, v); rec.Code != http.StatusPreconditionFailed {
+				t.Errorf("%s with If-Match %s = %d, want 412", c.name, v, rec.Code)
+			}
+		}
+	}
+
+	rec = do(modify, `"1"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("modify = %d, ETag %q; want 200, \"2\"", rec.Code, rec.Header().Get("ETag"))
+	}
+	// 読み込んだ後に変更された予約は変えない
+	for _, c := range []call{modify, cancel} {
+		if rec := do(c, `"1"`); rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("%s with stale ETag = %d, want 412", c.name, rec.Code)
+		}
+	}
+	if got, _ := h.UC.Get(context.Background(), owner, res.ID); got == nil || got.Status != entity.ReservationConfirmed || got.Version != 2 {
+		t.Fatalf("stale requests changed the reservation: %+v", got)
+	}
+	rec = do(cancel, `"2"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("cancel = %d, ETag %q; want 200, \"3\"", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestReservationList(t *testing.T) {
+	h, owner, other, staff := newReservationServer(t)
+	var ids []int
+	for i := range 5 {
+		in := time.Date(2030, 1, 10-i, 0, 0, 0, 0, time.UTC)
+		r, err := h.UC.Create(context.Background(), owner.User.ID, usecase.CreateReservationInput{
+			PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: in, Checkout: in.AddDate(0, 0, 1),
+		})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		ids = append(ids, r.ID)
+	}
+	createReservation(t, h, other)
+
+	list := func(p *usecase.Principal, query string) (int, reservationListResp) {
+		rec := serve(h.List, "GET /reservations", p, httptest.NewRequest(http.MethodGet, "/reservations?"+query, nil))
+		var resp reservationListResp
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return rec.Code, resp
+	}
+	pageIDs := func(resp reservationListResp) []int {
+		out := []int{}
+		for _, v := range resp.Items {
+			out = append(out, v.ID)
+		}
+		return out
+	}
+
+	// カーソルをたどると全件を 1 回ずつ返し、最後のページにはカーソルが無い
+	var got []int
+	query := "limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("too many pages: %v", got)
+		}
+		code, resp := list(owner, query)
+		if code != http.StatusOK {
+			t.Fatalf("GET /reservations?%s = %d", query, code)
+		}
+		got = append(got, pageIDs(resp)...)
+		if resp.NextCursor == "" {
+			break
+		}
+		query = "limit=2&cursor=" + url.QueryEscape(resp.NextCursor)
+	}
+	if !slices.Equal(got, ids) {
+		t.Errorf("paged ids = %v, want %v (own reservations only, id asc)", got, ids)
+	}
+	// ちょうど limit 件で終わる場合もカーソルを返さない
+	if code, resp := list(owner, "limit=5"); code != http.StatusOK || len(resp.Items) != 5 || resp.NextCursor != "" {
+		t.Errorf("limit=5 = %d, %v, cursor %q; want 5 items and no cursor", code, pageIDs(resp), resp.NextCursor)
+	}
+	// checkin 順のカーソル
+	_, first := list(owner, "sort=-checkin&limit=3")
+	_, second := list(owner, "sort=-checkin&limit=3&cursor="+url.QueryEscape(first.NextCursor))
+	if want := []int{ids[0], ids[1], ids[2]}; !slices.Equal(pageIDs(first), want) {
+		t.Errorf("sort=-checkin page 1 = %v, want %v", pageIDs(first), want)
+	}
+	if want := []int{ids[3], ids[4]}; !slices.Equal(pageIDs(second), want) || second.NextCursor != "" {
+		t.Errorf("sort=-checkin page 2 = %v, cursor %q; want %v and no cursor", pageIDs(second), second.NextCursor, want)
+	}
+
+	bad := []string{
+		"cursor=not-base64!",
+		"cursor=" + base64.RawURLEncoding.EncodeToString([]byte(`{"s":"id","id":0}`)),
+		// 別の並び順のカーソル
+		"sort=checkin&cursor=" + url.QueryEscape(first.NextCursor),
+		"limit=0x",
+		"limit=1000",
+		"sort=price",
+		"status=unknown",
+		"checkin_from=2030-02-01&checkin_to=2030-01-01",
+	}
+	for _, q := range bad {
+		if code, _ := list(owner, q); code != http.StatusBadRequest {
+			t.Errorf("GET /reservations?%s = %d, want 400", q, code)
+		}
+	}
+
+	// ゲストは他人の予約を一覧できない。スタッフは全員分
+	if code, _ := list(owner, "user_id="+other.User.ID); code != http.StatusForbidden {
+		t.Errorf("guest listing another user = %d, want 403", code)
+	}
+	if code, resp := list(staff, ""); code != http.StatusOK || len(resp.Items) != 6 {
+		t.Errorf("staff list = %d, %d items; want 200, 6", code, len(resp.Items))
+	}
+}
+
+func TestReservationTaxMismatch(t *testing.T) {
+	h, owner, _, _ := newReservationServer(t)
+	h.UC.Taxes = taxconfig.Default()
+	// 税のルールの変更などで、保存済みのプランが今のルールと合わなくなった場合
+	ctx := context.Background()
+	for _, plan := range []*entity.Plan{
+		{Name: "ドル建て", Price: entity.NewMoney(100, entity.USD), Capacity: 5, Jurisdiction: "tokyo"},
+		{Name: "廃止された地域", Price: entity.Yen(10000), Capacity: 5, Jurisdiction: "nagoya"},
+	} {
+		saved, err := h.UC.Plans.Save(ctx, plan)
+		if err != nil {
+			t.Fatalf("Save plan: %v", err)
+		}
+		body := `{"plan_id":` + strconv.Itoa(saved.ID) + `,"adults":1,"checkin":"2030-01-01","checkout":"2030-01-02"}`
+		rec := serve(h.Create, "POST /reservations", owner, httptest.NewRequest(http.MethodPost, "/reservations", strings.NewReader(body)))
+		if rec.Code != http.StatusConflict {
+			t.Errorf("POST /reservations for %s = %d, want 409", plan.Name, rec.Code)
+		}
+	}
+}
+
+// 与信は通すが、取り消し・返金を断る決済代行
+type decliningGateway struct{}
+
+func (decliningGateway) Authorize(context.Context, gateway.AuthorizeRequest) (string, error) {
+	return "ref_1", nil
+}
+
+func (decliningGateway) Capture(context.Context, string, entity.Money) error { return nil }
+
+func (decliningGateway) Refund(context.Context, string, entity.Money) error {
+	return fmt.Errorf("%w: refund declined", gateway.ErrPaymentDeclined)
+}
+
+func (decliningGateway) Void(context.Context, string) error {
+	return fmt.Errorf("%w: void declined", gateway.ErrPaymentDeclined)
+}
+
+func TestReservationCancelDeclined(t *testing.T) {
+	h, owner, _, _ := newReservationServer(t)
+	h.UC.Gateway = decliningGateway{}
+	res := createReservation(t, h, owner)
+
+	req := httptest.NewRequest(http.MethodPost, "/reservations/"+strconv.Itoa(res.ID)+"/cancel", nil)
+	req.Header.Set("If-Match", "*")
+	if rec := serve(h.Cancel, "POST /reservations/{id}/cancel", owner, req); rec.Code != http.StatusPaymentRequired {
+		t.Errorf("cancel with a declined void = %d, want 402", rec.Code)
+	}
+}
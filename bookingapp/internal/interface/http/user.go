@@ -1,6 +1,7 @@
 package httpi
 
 import (
+	"bookingapp/internal/domain/entity"
 	"bookingapp/internal/usecase"
 	"encoding/json"
 	"errors"
@@ -19,6 +20,7 @@ type registerUserReq struct {
 	PhoneNumber string `json:"phone_number"`
 	Address     string `json:"address"`
 	DateOfBirth string `json:"date_of_birth"`
+	Password    string `json:"password"`
 }
 
 type registerUserResp struct {
@@ -26,14 +28,15 @@ type registerUserResp struct {
 }
 
 type userView struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	Email        string `json:"email"`
-	PhoneNumber  string `json:"phone_number"`
-	Address      string `json:"address"`
-	DateOfBirth  string `json:"date_of_birth"`
-	RegisteredAt string `json:"registered_at"`
-	Status       string `json:"status"`
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Email        string   `json:"email"`
+	PhoneNumber  string   `json:"phone_number"`
+	Address      string   `json:"address"`
+	DateOfBirth  string   `json:"date_of_birth"`
+	RegisteredAt string   `json:"registered_at"`
+	Status       string   `json:"status"`
+	Roles        []string `json:"roles"`
 }
 
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
@@ -48,8 +51,11 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.UC.GetUser(id)
+	user, err := h.UC.GetUser(r.Context(), PrincipalFrom(r.Context()), id)
 	if err != nil {
+		if writeAuthError(w, err) {
+			return
+		}
 		if errors.Is(err, usecase.ErrUserInvalidInput) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -62,7 +68,16 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	view := userView{
+	setETag(w, user.Version)
+	writeJSON(w, http.StatusOK, toUserView(user))
+}
+
+func toUserView(user *entity.User) userView {
+	roles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, string(role))
+	}
+	return userView{
 		ID:           user.ID,
 		Name:         user.Name,
 		Email:        user.Email,
@@ -71,9 +86,8 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		DateOfBirth:  formatDate(user.DateOfBirth),
 		RegisteredAt: user.RegisteredAt.Format(time.RFC3339),
 		Status:       user.Status,
+		Roles:        roles,
 	}
-
-	writeJSON(w, http.StatusOK, view)
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -88,12 +102,13 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.UC.Register(usecase.RegisterUserInput{
+	user, err := h.UC.Register(r.Context(), usecase.RegisterUserInput{
 		Name:        in.Name,
 		Email:       in.Email,
 		PhoneNumber: in.PhoneNumber,
 		Address:     in.Address,
 		DateOfBirth: in.DateOfBirth,
+		Password:    in.Password,
 	})
 	if err != nil {
 		switch {
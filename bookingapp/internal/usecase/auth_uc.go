@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+)
+
+// パスワードのハッシュ化（実装は infrastructure/auth）
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// 一致しなければ error
+	Compare(hash, password string) error
+}
+
+// アクセストークンに載せる情報
+type AccessClaims struct {
+	UserID    string
+	SessionID string
+	ExpiresAt time.Time
+}
+
+// アクセストークンの署名・検証（実装は infrastructure/auth）
+type TokenSigner interface {
+	Sign(c AccessClaims) (string, error)
+	// 署名不正・期限切れなら error
+	Verify(token string) (*AccessClaims, error)
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time // アクセストークンの有効期限
+}
+
+// 認証済みのリクエスト主体
+type Principal struct {
+	User        *entity.User
+	SessionID   string
+	Permissions []entity.Permission // User.Roles の権限の和集合
+}
+
+func (p *Principal) Can(perm entity.Permission) bool {
+	return p != nil && slices.Contains(p.Permissions, perm)
+}
+
+type AuthUsecase struct {
+	Users      repository.UserRepository
+	Sessions   repository.SessionRepository
+	Roles      repository.RoleRepository
+	Hasher     PasswordHasher
+	Tokens     TokenSigner
+	AccessTTL  time.Duration // 0 なら 15 分
+	RefreshTTL time.Duration // 0 なら 30 日
+	Now        func() time.Time
+}
+
+// メールアドレスとパスワードでログインし、新しいセッションのトークンを発行する
+func (u *AuthUsecase) Login(ctx context.Context, email, password string) (*TokenPair, error) {
+	user, err := u.Users.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return nil, err
+	}
+	// ユーザーの有無で応答を変えない
+	if user == nil || user.PasswordHash == "" || user.Status != "active" {
+		return nil, ErrInvalidCredentials
+	}
+	if err := u.Hasher.Compare(user.PasswordHash, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return u.startSession(ctx, user.ID)
+}
+
+// リフレッシュトークンでトークンを再発行する。使ったリフレッシュトークンは失効させる（ローテーション）。
+// 同じリフレッシュトークンで同時に呼ばれても、再発行するのは失効させた 1 回だけ
+func (u *AuthUsecase) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	s, err := u.Sessions.FindByRefreshTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || !s.Active(u.now()) {
+		return nil, ErrUnauthenticated
+	}
+	revoked, err := u.Sessions.Revoke(ctx, s.ID, u.now())
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrUnauthenticated
+	}
+	return u.startSession(ctx, s.UserID)
+}
+
+// セッションを失効させる。以後そのセッションのアクセストークン・リフレッシュトークンは使えない
+func (u *AuthUsecase) Logout(ctx context.Context, sessionID string) error {
+	_, err := u.Sessions.Revoke(ctx, sessionID, u.now())
+	return err
+}
+
+// アクセストークンを検証し、リクエスト主体を返す
+func (u *AuthUsecase) Authenticate(ctx context.Context, accessToken string) (*Principal, error) {
+	claims, err := u.Tokens.Verify(accessToken)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	// ログアウト済みのセッションのトークンは期限内でも拒否する
+	s, err := u.Sessions.Get(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || s.UserID != claims.UserID || !s.Active(u.now()) {
+		return nil, ErrUnauthenticated
+	}
+	user, err := u.Users.Get(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Status != "active" {
+		return nil, ErrUnauthenticated
+	}
+	// ロールの付与・剥奪は次のリクエストから反映される
+	var perms []entity.Permission
+	for _, role := range user.Roles {
+		ps, err := u.Roles.Permissions(ctx, role)
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range ps {
+			if !slices.Contains(perms, p) {
+				perms = append(perms, p)
+			}
+		}
+	}
+	return &Principal{User: user, SessionID: s.ID, Permissions: perms}, nil
+}
+
+func (u *AuthUsecase) startSession(ctx context.Context, userID string) (*TokenPair, error) {
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := u.now()
+	s := &entity.Session{
+		ID:               uuid.NewString(),
+		UserID:           userID,
+		RefreshTokenHash: hashToken(refresh),
+		ExpiresAt:        now.Add(ttlOr(u.RefreshTTL, 30*24*time.Hour)),
+		CreatedAt:        now,
+	}
+	if err := u.Sessions.Create(ctx, s); err != nil {
+		return nil, err
+	}
+	exp := now.Add(ttlOr(u.AccessTTL, 15*time.Minute))
+	access, err := u.Tokens.Sign(AccessClaims{UserID: userID, SessionID: s.ID, ExpiresAt: exp})
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresAt: exp}, nil
+}
+
+func (u *AuthUsecase) now() time.Time {
+	if u.Now != nil {
+		return u.Now()
+	}
+	return time.Now()
+}
+
+func ttlOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// リフレッシュトークンは推測不能な乱数。DB には SHA-256 だけを保存する
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
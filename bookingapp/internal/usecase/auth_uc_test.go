@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/infrastructure/memory"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// "hashed:" + パスワードをハッシュとして扱う
+type plainHasher struct{}
+
+func (plainHasher) Hash(password string) (string, error) { return "hashed:" + password, nil }
+
+func (plainHasher) Compare(hash, password string) error {
+	if hash != "hashed:"+password {
+		return errors.New("password mismatch")
+	}
+	return nil
+}
+
+// 発行したトークンとクレームの対応を覚えておくだけの署名
+type mapSigner struct {
+	mu     sync.Mutex
+	claims map[string]AccessClaims
+}
+
+func (s *mapSigner) Sign(c AccessClaims) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tok := "access-" + strconv.Itoa(len(s.claims)+1)
+	s.claims[tok] = c
+	return tok, nil
+}
+
+func (s *mapSigner) Verify(token string) (*AccessClaims, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.claims[token]
+	if !ok {
+		return nil, errors.New("unknown token")
+	}
+	return &c, nil
+}
+
+func newAuthFixture(t *testing.T) (*AuthUsecase, *entity.User, *time.Time) {
+	t.Helper()
+	users := memory.NewUserRepoMemory()
+	user, err := users.Create(context.Background(), &entity.User{Name: "guest", Email: "guest@example.com", PasswordHash: "hashed:secret", Status: "active"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	uc := &AuthUsecase{
+		Users: users, Sessions: memory.NewSessionRepoMemory(), Roles: memory.NewRoleRepoMemory(),
+		Hasher: plainHasher{}, Tokens: &mapSigner{claims: map[string]AccessClaims{}},
+		RefreshTTL: time.Hour, Now: func() time.Time { return now },
+	}
+	return uc, user, &now
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	uc, user, _ := newAuthFixture(t)
+
+	// 誤ったパスワードと存在しないユーザーは区別しない
+	for _, c := range []struct{ email, password string }{
+		{"guest@example.com", "wrong"},
+		{"nobody@example.com", "secret"},
+		{"", ""},
+	} {
+		if _, err := uc.Login(ctx, c.email, c.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Login(%q, %q) = %v, want ErrInvalidCredentials", c.email, c.password, err)
+		}
+	}
+
+	pair, err := uc.Login(ctx, " guest@example.com ", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	p, err := uc.Authenticate(ctx, pair.AccessToken)
+	if err != nil || p.User.ID != user.ID || !p.Can(entity.PermReservationsBook) {
+		t.Fatalf("Authenticate = %+v, %v", p, err)
+	}
+	if _, err := uc.Authenticate(ctx, "forged"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authenticate(forged) = %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	uc, user, now := newAuthFixture(t)
+	first, err := uc.Login(ctx, "guest@example.com", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	second, err := uc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatalf("Refresh did not rotate the refresh token")
+	}
+	// 使ったリフレッシュトークンと、そのセッションのアクセストークンは使えなくなる
+	if _, err := uc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Refresh(used token) = %v, want ErrUnauthenticated", err)
+	}
+	if _, err := uc.Authenticate(ctx, first.AccessToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authenticate(old access token) = %v, want ErrUnauthenticated", err)
+	}
+	if p, err := uc.Authenticate(ctx, second.AccessToken); err != nil || p.User.ID != user.ID {
+		t.Errorf("Authenticate(new access token) = %+v, %v", p, err)
+	}
+	if _, err := uc.Refresh(ctx, "unknown"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Refresh(unknown) = %v, want ErrUnauthenticated", err)
+	}
+
+	// 同じリフレッシュトークンで同時に再発行しても、通るのは 1 回だけ
+	const n = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := uc.Refresh(ctx, second.RefreshToken)
+			switch {
+			case err == nil:
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			case !errors.Is(err, ErrUnauthenticated):
+				t.Errorf("Refresh: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("%d of %d concurrent refreshes succeeded, want 1", succeeded, n)
+	}
+
+	// 期限切れのセッションは再発行できない
+	third, err := uc.Login(ctx, "guest@example.com", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	*now = now.Add(uc.RefreshTTL)
+	if _, err := uc.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Refresh(expired session) = %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	uc, _, _ := newAuthFixture(t)
+	pair, err := uc.Login(ctx, "guest@example.com", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	p, err := uc.Authenticate(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if err := uc.Logout(ctx, p.SessionID); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	// 期限内のトークンでも使えない。2 回目のログアウトはエラーにしない
+	if _, err := uc.Authenticate(ctx, pair.AccessToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authenticate after logout = %v, want ErrUnauthenticated", err)
+	}
+	if _, err := uc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Refresh after logout = %v, want ErrUnauthenticated", err)
+	}
+	if err := uc.Logout(ctx, p.SessionID); err != nil {
+		t.Errorf("Logout twice = %v, want nil", err)
+	}
+}
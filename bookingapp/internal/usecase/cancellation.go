@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"context"
+	"fmt"
+)
+
+// プランのキャンセルポリシーを予約に写す。後でポリシーを変えても予約のキャンセル料は変わらない
+func (u *ReservationUsecase) snapshotCancellationPolicy(ctx context.Context, plan *entity.Plan, r *entity.Reservation) error {
+	r.CancellationFee = entity.NewMoney(0, r.Total.Currency)
+	if plan.CancellationPolicyID == 0 || u.CancellationPolicies == nil {
+		return nil
+	}
+	policy, err := u.CancellationPolicies.FindByID(ctx, plan.CancellationPolicyID)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return fmt.Errorf("plan %d: %w", plan.ID, ErrCancellationPolicyNotFound)
+	}
+	r.CancellationPolicy = policy
+	return nil
+}
+
+// 今キャンセルした場合のキャンセル料と返金額（予約時点のポリシーで計算する）。
+// キャンセルできない状態の予約は ErrInvalidStatusTransition
+func (u *ReservationUsecase) CancellationQuote(ctx context.Context, p *Principal, id int) (*entity.Reservation, entity.CancellationQuote, error) {
+	r, err := u.Get(ctx, p, id)
+	if err != nil {
+		return nil, entity.CancellationQuote{}, err
+	}
+	if r == nil {
+		return nil, entity.CancellationQuote{}, ErrReservationNotFound
+	}
+	if !r.Status.CanTransitionTo(entity.ReservationCancelled) {
+		return nil, entity.CancellationQuote{}, entity.ErrInvalidStatusTransition
+	}
+	q, err := u.quoteCancellation(r)
+	if err != nil {
+		return nil, entity.CancellationQuote{}, err
+	}
+	return r, q, nil
+}
+
+// 日数はサーバーではなく宿のタイムゾーンの日付で数える。
+// 与信待ちの予約はまだ支払いが無いのでキャンセル料を取らない
+func (u *ReservationUsecase) quoteCancellation(r *entity.Reservation) (entity.CancellationQuote, error) {
+	q, err := r.CancellationQuote(u.now().In(u.location()))
+	if err != nil {
+		return entity.CancellationQuote{}, err
+	}
+	if r.Status == entity.ReservationPending {
+		q.FeePercent, q.Fee, q.Refund = 0, entity.NewMoney(0, r.Total.Currency), r.Total
+	}
+	return q, nil
+}
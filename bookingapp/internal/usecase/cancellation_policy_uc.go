@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+)
+
+var ErrCancellationPolicyNotFound = errors.New("cancellation policy not found")
+
+// キャンセルポリシーの登録・更新の入力。更新時もすべての項目を指定する
+type CancellationPolicyInput struct {
+	Name  string
+	Rules []entity.CancellationRule
+}
+
+// キャンセルポリシーの管理（staff / admin 向け）。更新しても既存の予約のキャンセル料は変わらない
+type CancellationPolicyUsecase struct {
+	Policies repository.CancellationPolicyRepository
+	Policy   AccessPolicy
+}
+
+func (u *CancellationPolicyUsecase) List(ctx context.Context, p *Principal) ([]*entity.CancellationPolicy, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	return u.Policies.List(ctx)
+}
+
+func (u *CancellationPolicyUsecase) Get(ctx context.Context, p *Principal, id int) (*entity.CancellationPolicy, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	return findCancellationPolicy(ctx, u.Policies, id)
+}
+
+func (u *CancellationPolicyUsecase) Create(ctx context.Context, p *Principal, in CancellationPolicyInput) (*entity.CancellationPolicy, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	policy := &entity.CancellationPolicy{Name: in.Name, Rules: in.Rules}
+	if err := policy.Normalize(); err != nil {
+		return nil, err
+	}
+	return u.Policies.Save(ctx, policy)
+}
+
+func (u *CancellationPolicyUsecase) Update(ctx context.Context, p *Principal, id int, in CancellationPolicyInput) (*entity.CancellationPolicy, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	policy, err := findCancellationPolicy(ctx, u.Policies, id)
+	if err != nil {
+		return nil, err
+	}
+	policy.Name, policy.Rules = in.Name, in.Rules
+	if err := policy.Normalize(); err != nil {
+		return nil, err
+	}
+	return u.Policies.Save(ctx, policy)
+}
+
+func findCancellationPolicy(ctx context.Context, policies repository.CancellationPolicyRepository, id int) (*entity.CancellationPolicy, error) {
+	policy, err := policies.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, ErrCancellationPolicyNotFound
+	}
+	return policy, nil
+}
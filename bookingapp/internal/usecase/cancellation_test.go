@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/infrastructure/memory"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCancellationQuoteUsesHotelDate(t *testing.T) {
+	ctx := context.Background()
+	uc, p := newReservationFixture(t)
+	uc.CancellationPolicies = memory.NewCancellationPolicyRepoMemory()
+	policy, err := uc.CancellationPolicies.Save(ctx, &entity.CancellationPolicy{Name: "標準", Rules: []entity.CancellationRule{
+		{DaysBefore: 3, FeePercent: 50}, {DaysBefore: 0, FeePercent: 100},
+	}})
+	if err != nil {
+		t.Fatalf("Save policy: %v", err)
+	}
+	plan, err := uc.Plans.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	plan.CancellationPolicyID = policy.ID
+	if _, err := uc.Plans.Save(ctx, plan); err != nil {
+		t.Fatalf("Save plan: %v", err)
+	}
+	r, err := uc.Create(ctx, p.User.ID, CreateReservationInput{PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: stayDay(10), Checkout: stayDay(11)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// チェックイン（1/11）の 4 日前の 15:30 UTC は、日本時間ではもう 3 日前（1/8 0:30）
+	uc.Now = func() time.Time { return time.Date(2030, 1, 7, 15, 30, 0, 0, time.UTC) }
+	cases := []struct {
+		name    string
+		loc     *time.Location
+		days    int
+		percent int
+	}{
+		{"default (Japan)", nil, 3, 50},
+		{"UTC", time.UTC, 4, 0},
+	}
+	for _, c := range cases {
+		uc.Location = c.loc
+		_, q, err := uc.CancellationQuote(ctx, p, r.ID)
+		if err != nil || q.DaysBefore != c.days || q.FeePercent != c.percent {
+			t.Errorf("CancellationQuote(%s) = %+v, %v; want %d days, %d%%", c.name, q, err, c.days, c.percent)
+		}
+	}
+}
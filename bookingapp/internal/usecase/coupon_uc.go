@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCouponNotFound  = errors.New("coupon not found")
+	ErrCouponExhausted = repository.ErrCouponExhausted
+	ErrCouponUserLimit = repository.ErrCouponUserLimit
+	ErrDuplicateCoupon = repository.ErrDuplicateCouponCode
+)
+
+// クーポンの発行・更新の入力。更新時もすべての項目を指定する
+type CouponInput struct {
+	Code           string
+	Name           string
+	Discount       entity.CouponDiscount
+	Percent        int
+	Amount         entity.Money // 通貨が空なら entity.DefaultCurrency
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	PlanIDs        []int
+	MaxRedemptions int
+	PerUserLimit   int
+}
+
+// クーポンの管理（staff / admin 向け）。利用は ReservationUsecase で行う
+type CouponUsecase struct {
+	Coupons repository.CouponRepository
+	Policy  AccessPolicy
+}
+
+func (u *CouponUsecase) List(ctx context.Context, p *Principal) ([]*entity.Coupon, error) {
+	if err := u.Policy.Require(p, entity.PermCouponsManage); err != nil {
+		return nil, err
+	}
+	return u.Coupons.List(ctx)
+}
+
+func (u *CouponUsecase) Get(ctx context.Context, p *Principal, id int) (*entity.Coupon, error) {
+	if err := u.Policy.Require(p, entity.PermCouponsManage); err != nil {
+		return nil, err
+	}
+	return u.find(ctx, id)
+}
+
+func (u *CouponUsecase) Create(ctx context.Context, p *Principal, in CouponInput) (*entity.Coupon, error) {
+	if err := u.Policy.Require(p, entity.PermCouponsManage); err != nil {
+		return nil, err
+	}
+	c := &entity.Coupon{}
+	if err := applyCouponInput(c, in); err != nil {
+		return nil, err
+	}
+	return u.Coupons.Save(ctx, c)
+}
+
+// 利用数はそのまま。上限を利用数より小さくすると以後の利用だけが止まる
+func (u *CouponUsecase) Update(ctx context.Context, p *Principal, id int, in CouponInput) (*entity.Coupon, error) {
+	if err := u.Policy.Require(p, entity.PermCouponsManage); err != nil {
+		return nil, err
+	}
+	c, err := u.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCouponInput(c, in); err != nil {
+		return nil, err
+	}
+	return u.Coupons.Save(ctx, c)
+}
+
+func (u *CouponUsecase) find(ctx context.Context, id int) (*entity.Coupon, error) {
+	c, err := u.Coupons.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrCouponNotFound
+	}
+	return c, nil
+}
+
+func applyCouponInput(c *entity.Coupon, in CouponInput) error {
+	c.Code = entity.NormalizeCouponCode(in.Code)
+	c.Name = strings.TrimSpace(in.Name)
+	c.Discount = entity.CouponDiscount(strings.TrimSpace(string(in.Discount)))
+	c.Percent, c.Amount = 0, entity.Money{}
+	switch c.Discount {
+	case entity.CouponPercent:
+		c.Percent = in.Percent
+	case entity.CouponAmount:
+		currency, err := entity.ParseCurrency(string(in.Amount.Currency))
+		if err != nil {
+			return err
+		}
+		c.Amount = entity.NewMoney(in.Amount.Amount, currency)
+	}
+	c.StartsAt, c.EndsAt = in.StartsAt, in.EndsAt
+	c.PlanIDs = in.PlanIDs
+	c.MaxRedemptions, c.PerUserLimit = in.MaxRedemptions, in.PerUserLimit
+	return c.Validate()
+}
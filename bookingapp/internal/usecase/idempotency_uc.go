@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyInProgress = errors.New("a request with the same idempotency key is in progress")
+)
+
+// Idempotency-Key の最大長
+const maxIdempotencyKeyLen = 255
+
+// Idempotency-Key 付きリクエストの重複実行を防ぐ。キーはユーザーごとに区別する
+type IdempotencyUsecase struct {
+	Records repository.IdempotencyRepository
+	TTL     time.Duration // 記録を残す期間。0 なら 24 時間
+	// 処理中の記録をこれより古ければ放棄されたとみなす。0 なら 1 分。
+	// 処理は ProcessingTimeout で打ち切るので、これを過ぎても処理中なのは処理していたプロセスが落ちた場合だけ
+	LockTimeout time.Duration
+	Now         func() time.Time
+}
+
+// キー付きのリクエストの処理を打ち切るまでの時間。LockTimeout の半分で、
+// 残りは打ち切られた後に結果を記録する（決済の結果を保存するなど）ための猶予
+func (u *IdempotencyUsecase) ProcessingTimeout() time.Duration {
+	return u.lockTimeout() / 2
+}
+
+// キーの利用を始める。返す記録が Completed なら同じリクエストの再送なので、保存したレスポンスを返せばよい。
+// そうでなければ新しく作った処理中の記録で、処理後に Complete か Abandon を呼ぶ
+func (u *IdempotencyUsecase) Begin(ctx context.Context, userID, key, requestHash string) (*entity.IdempotencyRecord, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLen {
+		return nil, ErrInvalidIdempotencyKey
+	}
+	now := u.now()
+	rec := &entity.IdempotencyRecord{
+		ID: uuid.NewString(), UserID: userID, Key: key, RequestHash: requestHash,
+		CreatedAt: now, ExpiresAt: now.Add(ttlOr(u.TTL, 24*time.Hour)),
+	}
+	// 期限切れ・放棄された記録を消した後にもう一度だけ作る
+	for range 2 {
+		existing, err := u.Records.Create(ctx, rec)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return rec, nil
+		}
+		stale := !existing.Completed() && !now.Before(existing.CreatedAt.Add(u.lockTimeout()))
+		if !existing.Expired(now) && !stale {
+			switch {
+			case existing.RequestHash != requestHash:
+				return nil, ErrIdempotencyKeyReused
+			case !existing.Completed():
+				return nil, ErrIdempotencyInProgress
+			}
+			return existing, nil
+		}
+		// ID 指定なので、同時に作り直された別の記録は消さない
+		if err := u.Records.Delete(ctx, existing.ID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrIdempotencyInProgress
+}
+
+// 処理結果を保存し、以後の再送に同じレスポンスを返せるようにする
+func (u *IdempotencyUsecase) Complete(ctx context.Context, rec *entity.IdempotencyRecord, statusCode int, contentType string, body []byte) error {
+	rec.StatusCode, rec.ContentType, rec.Body = statusCode, contentType, body
+	return u.Records.Complete(ctx, rec.ID, statusCode, contentType, body)
+}
+
+// 結果を保存せずに記録を消す（再送すると改めて処理する）
+func (u *IdempotencyUsecase) Abandon(ctx context.Context, rec *entity.IdempotencyRecord) error {
+	return u.Records.Delete(ctx, rec.ID)
+}
+
+// 期限切れの記録を消し、消した件数を返す
+func (u *IdempotencyUsecase) PurgeExpired(ctx context.Context) (int, error) {
+	return u.Records.DeleteExpired(ctx, u.now())
+}
+
+func (u *IdempotencyUsecase) lockTimeout() time.Duration {
+	return ttlOr(u.LockTimeout, time.Minute)
+}
+
+type commitTrackerKey struct{}
+
+// 返す ctx で呼んだユースケースが書き込みをコミットしたかを、返す関数で確かめられるようにする。
+// 失敗のレスポンスでも、コミット済みなら再送で処理し直さないよう結果を保存するのに使う
+func TrackCommits(ctx context.Context) (context.Context, func() bool) {
+	var committed atomic.Bool
+	return context.WithValue(ctx, commitTrackerKey{}, &committed), committed.Load
+}
+
+// ctx が TrackCommits の下なら、書き込みをコミットしたことを記録する
+func markCommitted(ctx context.Context) {
+	if c, ok := ctx.Value(commitTrackerKey{}).(*atomic.Bool); ok {
+		c.Store(true)
+	}
+}
+
+func (u *IdempotencyUsecase) now() time.Time {
+	if u.Now != nil {
+		return u.Now()
+	}
+	return time.Now()
+}
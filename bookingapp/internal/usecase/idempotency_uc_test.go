@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"bookingapp/internal/infrastructure/memory"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyBegin(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	u := &IdempotencyUsecase{Records: memory.NewIdempotencyRepoMemory(), TTL: time.Hour, LockTimeout: time.Minute, Now: func() time.Time { return now }}
+
+	for _, key := range []string{"", strings.Repeat("k", 256)} {
+		if _, err := u.Begin(ctx, "u1", key, "h1"); !errors.Is(err, ErrInvalidIdempotencyKey) {
+			t.Fatalf("Begin(key len %d) = %v, want ErrInvalidIdempotencyKey", len(key), err)
+		}
+	}
+
+	rec, err := u.Begin(ctx, "u1", "k", "h1")
+	if err != nil || rec.Completed() {
+		t.Fatalf("Begin = %+v, %v; want new in-progress record", rec, err)
+	}
+	if _, err := u.Begin(ctx, "u1", "k", "h1"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Fatalf("Begin(in progress) = %v, want ErrIdempotencyInProgress", err)
+	}
+	if err := u.Complete(ctx, rec, 201, "application/json", []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if got, err := u.Begin(ctx, "u1", "k", "h1"); err != nil || !got.Completed() || got.StatusCode != 201 {
+		t.Fatalf("Begin(retry) = %+v, %v; want completed record", got, err)
+	}
+	if _, err := u.Begin(ctx, "u1", "k", "h2"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("Begin(different body) = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if got, err := u.Begin(ctx, "u2", "k", "h2"); err != nil || got.Completed() {
+		t.Fatalf("Begin(other user) = %+v, %v; want new record", got, err)
+	}
+
+	// 期限切れの記録は作り直す
+	now = now.Add(time.Hour)
+	if got, err := u.Begin(ctx, "u1", "k", "h2"); err != nil || got.Completed() || got.ID == rec.ID {
+		t.Fatalf("Begin(expired) = %+v, %v; want new record", got, err)
+	}
+
+	// 処理中のまま LockTimeout を過ぎた記録は放棄されたとみなす
+	stale, _ := u.Begin(ctx, "u1", "stale", "h1")
+	now = now.Add(time.Minute)
+	if got, err := u.Begin(ctx, "u1", "stale", "h1"); err != nil || got.ID == stale.ID {
+		t.Fatalf("Begin(stale) = %+v, %v; want new record", got, err)
+	}
+
+	if err := u.Abandon(ctx, rec); err != nil {
+		t.Fatalf("Abandon: %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/gateway"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"unicode/utf8"
+)
+
+var (
+	ErrPaymentDeclined = gateway.ErrPaymentDeclined
+	// 決済代行の障害など、断られた以外の理由で決済できなかった
+	ErrPaymentFailed = errors.New("payment failed")
+	// 与信済みでない、キャンセル済みなど請求できない状態
+	ErrPaymentNotCapturable = errors.New("payment cannot be captured in its current status")
+	// 同じ決済への請求・取り消し・返金などを決済代行に依頼中
+	ErrPaymentInProgress = errors.New("another payment operation is in progress")
+)
+
+// 仮予約 r の与信を取り、成功すれば確定、失敗すれば仮押さえを戻してキャンセルする
+func (u *ReservationUsecase) authorize(ctx context.Context, r *entity.Reservation, token string) (*entity.Reservation, error) {
+	ref, authErr := u.Gateway.Authorize(ctx, gateway.AuthorizeRequest{ReservationID: r.ID, Amount: r.Total, Token: token})
+	// リクエストが打ち切られても与信の結果は記録する
+	ctx = context.WithoutCancel(ctx)
+	var saved *entity.Reservation
+	err := u.inTx(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		cur, pay, err := findWithPayment(ctx, repos, r.ID)
+		if err != nil {
+			return err
+		}
+		if authErr != nil {
+			pay.Status, pay.FailureReason = entity.PaymentFailed, truncate(authErr.Error(), 255)
+			if _, err := repos.Payments.Save(ctx, pay); err != nil {
+				return err
+			}
+			if err := cur.Cancel(); err != nil {
+				return err
+			}
+			saved, err = releaseReservation(ctx, repos, cur)
+			return err
+		}
+		// 与信を待つ間にキャンセルされていれば ErrInvalidStatusTransition
+		if err := cur.TransitionTo(entity.ReservationConfirmed); err != nil {
+			return err
+		}
+		pay.Status, pay.GatewayRef = entity.PaymentAuthorized, ref
+		if _, err := repos.Payments.Save(ctx, pay); err != nil {
+			return err
+		}
+		saved, err = repos.Reservations.Save(ctx, cur)
+		return err
+	})
+	if err != nil {
+		// 与信を待つ間のキャンセルなどで予約を確定できなければ与信を取り消し、
+		// 決済が与信待ちのまま残らないよう別のトランザクションで結果を記録する
+		status, reason := entity.PaymentFailed, ""
+		if authErr != nil {
+			reason = authErr.Error()
+		} else if vErr := u.Gateway.Void(ctx, ref); vErr != nil {
+			log.Printf("void payment %s of reservation %d: %v", ref, r.ID, vErr)
+			reason = "void: " + vErr.Error()
+		} else {
+			status = entity.PaymentVoided
+		}
+		u.abandonPayment(ctx, r.ID, ref, status, reason)
+		return nil, err
+	}
+	if authErr != nil {
+		return nil, paymentError(authErr)
+	}
+	return saved, nil
+}
+
+// 予約を確定できなかった与信待ちの決済を status で記録する（記録できなければログに残すだけ）
+func (u *ReservationUsecase) abandonPayment(ctx context.Context, reservationID int, ref string, status entity.PaymentStatus, reason string) {
+	err := u.inTx(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		pay, err := repos.Payments.FindByReservation(ctx, reservationID)
+		if err != nil || pay == nil || pay.Status != entity.PaymentPending {
+			return err
+		}
+		pay.Status, pay.GatewayRef, pay.FailureReason = status, ref, truncate(reason, 255)
+		_, err = repos.Payments.Save(ctx, pay)
+		return err
+	})
+	if err != nil {
+		log.Printf("record %s payment of reservation %d: %v", status, reservationID, err)
+	}
+}
+
+// キャンセルした予約の決済から、キャンセル料 fee だけを受け取る。pay は claimPayment で切り替えた決済、from は切り替える前の状態。
+// 未請求なら fee を請求する（無料なら与信を取り消す）。請求済みなら fee を除いた分を返金する。
+// 決済代行が失敗すれば from に戻すので、もう一度キャンセルするとやり直せる
+func (u *ReservationUsecase) settle(ctx context.Context, pay *entity.Payment, from entity.PaymentStatus, fee entity.Money) error {
+	var gwErr error
+	switch {
+	case from == entity.PaymentAuthorized && fee.IsPositive():
+		if gwErr = u.Gateway.Capture(ctx, pay.GatewayRef, fee); gwErr == nil {
+			pay.Captured, pay.Status = fee, entity.PaymentCaptured
+		}
+	case from == entity.PaymentAuthorized:
+		if gwErr = u.Gateway.Void(ctx, pay.GatewayRef); gwErr == nil {
+			pay.Status = entity.PaymentVoided
+		}
+	default:
+		// 請求済み（NeedsSettlement なので fee を除いても返金する分が残っている）
+		refund := entity.NewMoney(pay.Captured.Amount-pay.Refunded.Amount-fee.Amount, pay.Captured.Currency)
+		if gwErr = u.Gateway.Refund(ctx, pay.GatewayRef, refund); gwErr == nil {
+			pay.Refunded.Amount += refund.Amount
+			pay.Status = entity.PaymentRefunded
+		}
+	}
+	if gwErr != nil {
+		pay.Status = from
+	}
+	return u.finishPayment(ctx, pay, gwErr)
+}
+
+// 変更で合計金額が与信額を超えた場合に与信を取り直す（pay は Modify で切り替え済み）。
+// 失敗すれば変更前の予約 before と与信済みの決済に戻す
+func (u *ReservationUsecase) reauthorize(ctx context.Context, before, after *entity.Reservation, pay *entity.Payment, token string) error {
+	ref, authErr := u.Gateway.Authorize(ctx, gateway.AuthorizeRequest{ReservationID: after.ID, Amount: after.Total, Token: token})
+	ctx = context.WithoutCancel(ctx)
+	if authErr != nil {
+		err := u.inTx(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			if !before.Checkin.Equal(after.Checkin) || !before.Checkout.Equal(after.Checkout) {
+				if err := repos.Plans.RebookNights(ctx, after.PlanID, after.Checkin, after.Checkout, before.Checkin, before.Checkout); err != nil {
+					return err
+				}
+			}
+			pay.Status = entity.PaymentAuthorized
+			if _, err := repos.Payments.Save(ctx, pay); err != nil {
+				return err
+			}
+			// 変更後に保存したバージョンの上に書き戻す（その後に更新されていれば ErrConcurrentModification）
+			before.Version = after.Version
+			_, err := repos.Reservations.Save(ctx, before)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("restore reservation %d after failed authorization: %w", before.ID, err)
+		}
+		return paymentError(authErr)
+	}
+	old := pay.GatewayRef
+	pay.Amount, pay.GatewayRef, pay.Status = after.Total, ref, entity.PaymentAuthorized
+	if err := u.savePayment(ctx, pay); err != nil {
+		if vErr := u.Gateway.Void(ctx, ref); vErr != nil {
+			log.Printf("void payment %s of reservation %d: %v", ref, after.ID, vErr)
+		}
+		return err
+	}
+	// 古い与信は期限切れでも消えるので、取り消しの失敗は記録だけする
+	if err := u.Gateway.Void(ctx, old); err != nil {
+		log.Printf("void payment %s of reservation %d: %v", old, after.ID, err)
+	}
+	return nil
+}
+
+// 予約の決済（決済していなければ nil）
+func (u *ReservationUsecase) Payment(ctx context.Context, p *Principal, id int) (*entity.Payment, error) {
+	r, err := u.Get(ctx, p, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrReservationNotFound
+	}
+	if u.Payments == nil {
+		return nil, nil
+	}
+	return u.Payments.FindByReservation(ctx, id)
+}
+
+// 与信済みの決済を予約の合計金額で請求する（staff / admin）。
+// version が 0 でなければ、予約のバージョンが違う場合に ErrConcurrentModification
+func (u *ReservationUsecase) CapturePayment(ctx context.Context, p *Principal, id, version int) (*entity.Payment, error) {
+	if err := u.Policy.Require(p, entity.PermReservationsManageAll); err != nil {
+		return nil, err
+	}
+	var r *entity.Reservation
+	var pay *entity.Payment
+	err := u.inTx(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		if r, pay, err = findWithPayment(ctx, repos, id); err != nil {
+			return err
+		}
+		if err := checkVersion(version, r.Version); err != nil {
+			return err
+		}
+		if pay.Status == entity.PaymentProcessing {
+			return ErrPaymentInProgress
+		}
+		if u.Gateway == nil || pay.Status != entity.PaymentAuthorized || r.Status == entity.ReservationCancelled {
+			return ErrPaymentNotCapturable
+		}
+		// 請求を依頼する前に切り替え、同時のキャンセル・請求とは一方だけが進むようにする
+		_, err = claimPayment(ctx, repos, pay)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	capErr := u.Gateway.Capture(ctx, pay.GatewayRef, r.Total)
+	if capErr == nil {
+		pay.Captured, pay.Status = r.Total, entity.PaymentCaptured
+	} else {
+		// 請求できなければ与信済みに戻し、請求・キャンセルをやり直せるようにする
+		pay.Status = entity.PaymentAuthorized
+	}
+	if err := u.finishPayment(ctx, pay, capErr); err != nil {
+		return nil, err
+	}
+	return pay, nil
+}
+
+// 決済代行に依頼する前に決済を processing にして保存し、切り替える前の状態を返す。
+// 別の操作が依頼中、または読み込んだ後に他で更新されていれば ErrPaymentInProgress
+func claimPayment(ctx context.Context, repos repository.Repositories, pay *entity.Payment) (entity.PaymentStatus, error) {
+	if pay.Status == entity.PaymentProcessing {
+		return "", ErrPaymentInProgress
+	}
+	from := pay.Status
+	pay.Status = entity.PaymentProcessing
+	saved, err := repos.Payments.Save(ctx, pay)
+	if errors.Is(err, repository.ErrConcurrentModification) {
+		return "", ErrPaymentInProgress
+	}
+	if err != nil {
+		return "", err
+	}
+	*pay = *saved
+	return from, nil
+}
+
+// 決済代行の結果 gwErr に合わせて processing から切り替えた pay を保存する。
+// リクエストが打ち切られても保存し、gwErr があればそれを返す
+func (u *ReservationUsecase) finishPayment(ctx context.Context, pay *entity.Payment, gwErr error) error {
+	if err := u.savePayment(context.WithoutCancel(ctx), pay); err != nil {
+		if gwErr == nil {
+			return err
+		}
+		log.Printf("restore payment of reservation %d: %v", pay.ReservationID, err)
+	}
+	if gwErr != nil {
+		return paymentError(gwErr)
+	}
+	return nil
+}
+
+// pay を保存し、保存後のバージョンを反映する
+func (u *ReservationUsecase) savePayment(ctx context.Context, pay *entity.Payment) error {
+	return u.inTx(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		saved, err := repos.Payments.Save(ctx, pay)
+		if err != nil {
+			return err
+		}
+		*pay = *saved
+		return nil
+	})
+}
+
+// 予約とその決済。どちらかが無ければ ErrReservationNotFound / ErrPaymentNotCapturable
+func findWithPayment(ctx context.Context, repos repository.Repositories, id int) (*entity.Reservation, *entity.Payment, error) {
+	r, err := repos.Reservations.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r == nil {
+		return nil, nil, ErrReservationNotFound
+	}
+	if repos.Payments == nil {
+		return nil, nil, ErrPaymentNotCapturable
+	}
+	pay, err := repos.Payments.FindByReservation(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pay == nil {
+		return nil, nil, ErrPaymentNotCapturable
+	}
+	return r, pay, nil
+}
+
+// 断られた場合はそのまま、それ以外は ErrPaymentFailed で包む
+func paymentError(err error) error {
+	if errors.Is(err, gateway.ErrPaymentDeclined) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+}
+
+// 先頭の n バイト以内に収まるよう、文字の途中で切らずに詰める
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/gateway"
+	"bookingapp/internal/domain/repository"
+	"bookingapp/internal/infrastructure/payment"
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// Capture が呼ばれると started に通知し、release が閉じるまで待つ決済代行。それ以外の操作は常に成功する
+type blockingGateway struct {
+	started    chan struct{}
+	release    chan struct{}
+	captureErr error
+}
+
+func newBlockingGateway(captureErr error) *blockingGateway {
+	return &blockingGateway{started: make(chan struct{}, 1), release: make(chan struct{}), captureErr: captureErr}
+}
+
+func (g *blockingGateway) Authorize(context.Context, gateway.AuthorizeRequest) (string, error) {
+	return "ref_1", nil
+}
+
+func (g *blockingGateway) Capture(context.Context, string, entity.Money) error {
+	g.started <- struct{}{}
+	<-g.release
+	return g.captureErr
+}
+
+func (g *blockingGateway) Refund(context.Context, string, entity.Money) error { return nil }
+func (g *blockingGateway) Void(context.Context, string) error                 { return nil }
+
+// 決済付きの予約と、請求できるスタッフを返す
+func newPaidReservation(t *testing.T, gw gateway.PaymentGateway) (*ReservationUsecase, *Principal, *Principal, *entity.Reservation) {
+	t.Helper()
+	uc, p := newReservationFixture(t)
+	uc.Gateway = gw
+	r, err := uc.Create(context.Background(), p.User.ID, CreateReservationInput{
+		PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: stayDay(0), Checkout: stayDay(1), PaymentToken: "tok_visa",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	staff := &Principal{User: &entity.User{ID: "s1"}, Permissions: entity.DefaultRolePermissions[entity.RoleStaff]}
+	return uc, p, staff, r
+}
+
+func TestCapturePaymentClaimsBeforeGateway(t *testing.T) {
+	ctx := context.Background()
+	gw := newBlockingGateway(nil)
+	uc, p, staff, r := newPaidReservation(t, gw)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := uc.CapturePayment(ctx, staff, r.ID, 0)
+		done <- err
+	}()
+	<-gw.started
+	// 請求を依頼している間は、キャンセルも二重の請求も決済代行を呼ばずに断る
+	if _, err := uc.Cancel(ctx, p, r.ID, 0); !errors.Is(err, ErrPaymentInProgress) {
+		t.Errorf("Cancel during capture = %v, want ErrPaymentInProgress", err)
+	}
+	if _, err := uc.CapturePayment(ctx, staff, r.ID, 0); !errors.Is(err, ErrPaymentInProgress) {
+		t.Errorf("second CapturePayment = %v, want ErrPaymentInProgress", err)
+	}
+	close(gw.release)
+	if err := <-done; err != nil {
+		t.Fatalf("CapturePayment: %v", err)
+	}
+
+	pay, _ := uc.Payments.FindByReservation(ctx, r.ID)
+	if pay == nil || pay.Status != entity.PaymentCaptured || pay.Captured != r.Total {
+		t.Fatalf("payment = %+v, want captured %v", pay, r.Total)
+	}
+	if got, _ := uc.Get(ctx, p, r.ID); got == nil || got.Status != entity.ReservationConfirmed {
+		t.Fatalf("reservation = %+v, want confirmed", got)
+	}
+}
+
+func TestCapturePaymentFailureKeepsAuthorization(t *testing.T) {
+	ctx := context.Background()
+	gw := newBlockingGateway(errors.New("gateway down"))
+	close(gw.release)
+	uc, p, staff, r := newPaidReservation(t, gw)
+
+	if _, err := uc.CapturePayment(ctx, staff, r.ID, 0); !errors.Is(err, ErrPaymentFailed) {
+		t.Fatalf("CapturePayment = %v, want ErrPaymentFailed", err)
+	}
+	if pay, _ := uc.Payments.FindByReservation(ctx, r.ID); pay == nil || pay.Status != entity.PaymentAuthorized {
+		t.Fatalf("payment after failed capture = %+v, want authorized", pay)
+	}
+	// 与信済みに戻っているので、キャンセルすると与信を取り消せる
+	if _, err := uc.Cancel(ctx, p, r.ID, 0); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if pay, _ := uc.Payments.FindByReservation(ctx, r.ID); pay == nil || pay.Status != entity.PaymentVoided {
+		t.Fatalf("payment after cancel = %+v, want voided", pay)
+	}
+}
+
+func TestCancelDuringAuthorization(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		token string
+		want  entity.PaymentStatus
+	}{
+		// 与信が取れても予約を確定できないので取り消す
+		{"tok_visa", entity.PaymentVoided},
+		// 断られた結果も、キャンセル済みの予約を戻せなくても記録する
+		{payment.DeclineToken, entity.PaymentFailed},
+	}
+	for i, c := range cases {
+		uc, p := newReservationFixture(t)
+		uc.Gateway = payment.NewFakeGateway(payment.FakeConfig{Delay: 100 * time.Millisecond})
+		done := make(chan error, 1)
+		go func() {
+			_, err := uc.Create(ctx, p.User.ID, CreateReservationInput{
+				PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: stayDay(i), Checkout: stayDay(i + 1), PaymentToken: c.token,
+			})
+			done <- err
+		}()
+		// 与信待ちの仮予約ができたらキャンセルする
+		var pending *entity.Reservation
+		for pending == nil {
+			list, err := uc.Resv.List(ctx, repository.ReservationQuery{UserID: p.User.ID})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(list) > 0 {
+				pending = list[0]
+			}
+		}
+		if _, err := uc.Cancel(ctx, p, pending.ID, 0); err != nil {
+			t.Fatalf("Cancel pending reservation: %v", err)
+		}
+		if err := <-done; err == nil {
+			t.Fatalf("Create(%s) succeeded for a reservation cancelled during authorization", c.token)
+		}
+
+		if got, _ := uc.Get(ctx, p, pending.ID); got == nil || got.Status != entity.ReservationCancelled {
+			t.Errorf("reservation = %+v, want cancelled", got)
+		}
+		if pay, _ := uc.Payments.FindByReservation(ctx, pending.ID); pay == nil || pay.Status != c.want {
+			t.Errorf("payment with %s = %+v, want %s", c.token, pay, c.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"declined", 255, "declined"},
+		{"declined", 3, "dec"},
+		// 文字の途中では切らない（あ は 3 バイト）
+		{"あいう", 4, "あ"},
+		{"あいう", 6, "あい"},
+		{"あいう", 2, ""},
+	}
+	for _, c := range cases {
+		got := truncate(c.s, c.n)
+		if got != c.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, want %q", c.s, c.n, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidPlanName  = errors.New("plan name must not be empty")
+	ErrInvalidPrice     = errors.New("price must be > 0")
+	ErrInvalidCapacity  = errors.New("capacity must be >= 1")
+	ErrInvalidGuests    = errors.New("guests must satisfy 1 <= min_guests <= max_guests")
+	ErrInvalidChildRate = errors.New("child rates must be between 0 and 100")
+
+	ErrInvalidSearch = errors.New("invalid search")
+	// 削除済みのプランの予約は日程・人数を変えられない（キャンセルはできる）
+	ErrPlanDeleted = errors.New("plan is no longer available")
+)
+
+// プランの全文検索インデックス（実装は infrastructure/search）。
+// プランの登録・更新・削除のたびに PlanUsecase が同期し、他のプロセスでの変更は Reindex で取り込む
+type PlanSearchIndex interface {
+	// 登録・更新。削除済みのプランは索引から外す
+	Index(ctx context.Context, plan *entity.Plan) error
+	Remove(ctx context.Context, planID int) error
+	// 索引の内容を plans（削除済みを除く）だけに入れ替える
+	Replace(ctx context.Context, plans []*entity.Plan) error
+	// query の語をすべて含むプランを、スコアの高い順（同点は ID 順）に返す
+	Search(ctx context.Context, query string) ([]PlanSearchHit, error)
+}
+
+type PlanSearchHit struct {
+	PlanID int
+	Score  float64
+	// フィールド名（name / keyword）→ 一致箇所を <em> で囲んだ抜粋。一致したフィールドだけ
+	Highlights map[string]string
+}
+
+// プランの登録・更新の入力。更新時もすべての項目を指定する
+type PlanInput struct {
+	Name      string
+	Keyword   string
+	Price     entity.Money // 通貨が空なら entity.DefaultCurrency
+	Capacity  int
+	MinGuests int // 0 なら 1
+	MaxGuests int // 0 なら entity.DefaultMaxGuests
+	// 子ども料金（大人の料金に対する %）。nil なら entity.DefaultChildRate / DefaultInfantRate
+	ChildRate  *int
+	InfantRate *int
+	// 課税地域（税のルールの地域コード）。空なら既定の地域
+	Jurisdiction string
+	// キャンセルポリシー。0 ならキャンセル料なし
+	CancellationPolicyID int
+}
+
+const (
+	defaultPlanSearchLimit = 20
+	maxPlanSearchLimit     = 100
+)
+
+// プラン検索の条件。ゼロ値の項目は絞り込まない
+type SearchPlansInput struct {
+	Keyword  string
+	Currency string // 価格の範囲の通貨。指定するとこの通貨のプランだけ（価格の範囲だけなら JPY）
+	MinPrice int64
+	MaxPrice int64
+	Guests   int
+	Checkin  *time.Time // Checkout と両方指定すると全泊に空きのあるプランだけ
+	Checkout *time.Time
+	Sort     string // relevance / price / name（空なら relevance）
+	Limit    int    // 0 なら 20、最大 100
+	Offset   int
+}
+
+// 検索結果の 1 件。Score・Highlights は全文検索インデックスでキーワード検索した場合だけ
+type PlanResult struct {
+	Plan       *entity.Plan
+	Score      float64
+	Highlights map[string]string
+}
+
+type PlanPage struct {
+	Items []PlanResult
+	// 次のページの Offset。次のページが無ければ 0
+	NextOffset int
+}
+
+// プランの検索（誰でも）と管理（staff / admin 向け）
+type PlanUsecase struct {
+	Plans repository.PlanRepository
+	// nil ならキーワードはリポジトリの部分一致で絞り込む
+	Index PlanSearchIndex
+	// 課税地域の確認に使う（nil なら確認しない）
+	Taxes TaxRuleSource
+	// キャンセルポリシーの存在確認に使う（nil なら確認しない）
+	CancellationPolicies repository.CancellationPolicyRepository
+	Policy               AccessPolicy
+	Now                  func() time.Time
+}
+
+func (u *PlanUsecase) Create(ctx context.Context, p *Principal, in PlanInput) (*entity.Plan, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	plan := &entity.Plan{}
+	if err := applyPlanInput(plan, in); err != nil {
+		return nil, err
+	}
+	if err := checkJurisdiction(u.Taxes, plan); err != nil {
+		return nil, err
+	}
+	if err := u.checkCancellationPolicy(ctx, plan); err != nil {
+		return nil, err
+	}
+	return u.save(ctx, plan)
+}
+
+// プラン取得（誰でも使える）。削除済みなら ErrPlanNotFound
+func (u *PlanUsecase) Get(ctx context.Context, id int) (*entity.Plan, error) {
+	return u.find(ctx, id)
+}
+
+// version が 0 でなければ、プランのバージョンが違う場合に ErrConcurrentModification
+func (u *PlanUsecase) Update(ctx context.Context, p *Principal, id, version int, in PlanInput) (*entity.Plan, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	plan, err := u.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(version, plan.Version); err != nil {
+		return nil, err
+	}
+	if err := applyPlanInput(plan, in); err != nil {
+		return nil, err
+	}
+	if err := checkJurisdiction(u.Taxes, plan); err != nil {
+		return nil, err
+	}
+	if err := u.checkCancellationPolicy(ctx, plan); err != nil {
+		return nil, err
+	}
+	return u.save(ctx, plan)
+}
+
+// プラン検索（誰でも使える）。削除済みのプランは含めない
+func (u *PlanUsecase) Search(ctx context.Context, in SearchPlansInput) (*PlanPage, error) {
+	spec, err := in.spec()
+	if err != nil {
+		return nil, err
+	}
+	if u.Index != nil && spec.NormalizedKeyword() != "" {
+		return u.searchIndex(ctx, spec)
+	}
+	// 1件多く取り、次のページがあるかを判定する
+	limit := spec.Limit
+	spec.Limit = limit + 1
+	list, err := u.Plans.Search(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) > limit {
+		return newPlanPage(list[:limit], nil, spec.Offset+limit), nil
+	}
+	return newPlanPage(list, nil, 0), nil
+}
+
+// キーワードはインデックスで引き、残りの条件はリポジトリで絞り込んでからページに切り出す
+func (u *PlanUsecase) searchIndex(ctx context.Context, spec entity.PlanSpec) (*PlanPage, error) {
+	hits, err := u.Index.Search(ctx, spec.Keyword)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return &PlanPage{Items: []PlanResult{}}, nil
+	}
+	byID := make(map[int]PlanSearchHit, len(hits))
+	ids := make([]int, 0, len(hits))
+	for _, h := range hits {
+		byID[h.PlanID] = h
+		ids = append(ids, h.PlanID)
+	}
+	offset, limit := spec.Offset, spec.Limit
+	spec.Keyword, spec.IDs, spec.Offset, spec.Limit = "", ids, 0, 0
+	list, err := u.Plans.Search(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Sort == entity.PlanSortRelevance {
+		// リポジトリは ID 順で返すので、スコアの高い順に並べ直す
+		sort.SliceStable(list, func(i, j int) bool { return byID[list[i].ID].Score > byID[list[j].ID].Score })
+	}
+	list = list[min(offset, len(list)):]
+	next := 0
+	if len(list) > limit {
+		list = list[:limit]
+		next = offset + limit
+	}
+	return newPlanPage(list, byID, next), nil
+}
+
+func newPlanPage(list []*entity.Plan, hits map[int]PlanSearchHit, next int) *PlanPage {
+	page := &PlanPage{Items: make([]PlanResult, 0, len(list)), NextOffset: next}
+	for _, p := range list {
+		h := hits[p.ID]
+		page.Items = append(page.Items, PlanResult{Plan: p, Score: h.Score, Highlights: h.Highlights})
+	}
+	return page
+}
+
+// 索引をリポジトリの削除済みでないプランで作り直し、登録した件数を返す。
+// 起動時と、他のプロセスでの登録・更新・削除を取り込むために定期的に呼ぶ
+func (u *PlanUsecase) Reindex(ctx context.Context) (int, error) {
+	if u.Index == nil {
+		return 0, nil
+	}
+	plans, err := u.Plans.Search(ctx, entity.PlanSpec{})
+	if err != nil {
+		return 0, err
+	}
+	if err := u.Index.Replace(ctx, plans); err != nil {
+		return 0, err
+	}
+	return len(plans), nil
+}
+
+func (in SearchPlansInput) spec() (entity.PlanSpec, error) {
+	spec := entity.PlanSpec{
+		Keyword:  in.Keyword,
+		MinPrice: in.MinPrice,
+		MaxPrice: in.MaxPrice,
+		Guests:   in.Guests,
+		Checkin:  in.Checkin,
+		Checkout: in.Checkout,
+		Sort:     entity.PlanSort(strings.TrimSpace(in.Sort)),
+		Offset:   in.Offset,
+		Limit:    in.Limit,
+	}
+	if spec.Sort == "" {
+		spec.Sort = entity.PlanSortRelevance
+	}
+	if c := strings.TrimSpace(in.Currency); c != "" {
+		cur, err := entity.ParseCurrency(c)
+		if err != nil {
+			return spec, fmt.Errorf("%w: %w", ErrInvalidSearch, err)
+		}
+		spec.Currency = cur
+	}
+	switch {
+	case !spec.Sort.Valid():
+		return spec, fmt.Errorf("%w: unknown sort %q", ErrInvalidSearch, in.Sort)
+	case spec.MinPrice < 0 || spec.MaxPrice < 0 || spec.Guests < 0 || spec.Offset < 0:
+		return spec, fmt.Errorf("%w: negative value", ErrInvalidSearch)
+	case spec.MaxPrice > 0 && spec.MinPrice > spec.MaxPrice:
+		return spec, fmt.Errorf("%w: min_price is greater than max_price", ErrInvalidSearch)
+	case (spec.Checkin == nil) != (spec.Checkout == nil):
+		return spec, fmt.Errorf("%w: checkin and checkout must be given together", ErrInvalidSearch)
+	}
+	if spec.Checkin != nil {
+		if !spec.Checkout.After(*spec.Checkin) {
+			return spec, ErrInvalidDates
+		}
+		if entity.NightsBetween(*spec.Checkin, *spec.Checkout) > maxAvailabilityNights {
+			return spec, ErrDateRangeTooLong
+		}
+	}
+	switch {
+	case spec.Limit == 0:
+		spec.Limit = defaultPlanSearchLimit
+	case spec.Limit < 0 || spec.Limit > maxPlanSearchLimit:
+		return spec, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSearch, maxPlanSearchLimit)
+	}
+	return spec, nil
+}
+
+// 論理削除。既存の予約はそのまま参照・キャンセルできる。version は Update と同じ
+func (u *PlanUsecase) Delete(ctx context.Context, p *Principal, id, version int) error {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return err
+	}
+	plan, err := u.find(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(version, plan.Version); err != nil {
+		return err
+	}
+	now := time.Now
+	if u.Now != nil {
+		now = u.Now
+	}
+	// 読み込んでから削除するまでに更新されていれば ErrConcurrentModification
+	if err := u.Plans.Delete(ctx, id, plan.Version, now()); err != nil {
+		return err
+	}
+	if u.Index != nil {
+		// 削除は済んでいるので失敗にはしない（索引は次の Reindex で直る）
+		if err := u.Index.Remove(ctx, id); err != nil {
+			log.Printf("remove plan %d from search index: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// 保存してから索引に反映する。索引への反映に失敗しても保存は済んでいるので成功として返す
+// （索引は次の Reindex で直る）
+func (u *PlanUsecase) save(ctx context.Context, plan *entity.Plan) (*entity.Plan, error) {
+	saved, err := u.Plans.Save(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+	if u.Index != nil {
+		if err := u.Index.Index(ctx, saved); err != nil {
+			log.Printf("index plan %d: %v", saved.ID, err)
+		}
+	}
+	return saved, nil
+}
+
+// 削除済みのプランは見つからない扱い
+func (u *PlanUsecase) find(ctx context.Context, id int) (*entity.Plan, error) {
+	plan, err := u.Plans.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil || plan.Deleted() {
+		return nil, ErrPlanNotFound
+	}
+	return plan, nil
+}
+
+func applyPlanInput(plan *entity.Plan, in PlanInput) error {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return ErrInvalidPlanName
+	}
+	if !in.Price.IsPositive() {
+		return ErrInvalidPrice
+	}
+	currency, err := entity.ParseCurrency(string(in.Price.Currency))
+	if err != nil {
+		return err
+	}
+	if in.Capacity < 1 {
+		return ErrInvalidCapacity
+	}
+	minGuests, maxGuests := in.MinGuests, in.MaxGuests
+	if minGuests == 0 {
+		minGuests = 1
+	}
+	if maxGuests == 0 {
+		maxGuests = entity.DefaultMaxGuests
+	}
+	if minGuests < 1 || maxGuests < minGuests {
+		return ErrInvalidGuests
+	}
+	childRate, infantRate := entity.DefaultChildRate, entity.DefaultInfantRate
+	if in.ChildRate != nil {
+		childRate = *in.ChildRate
+	}
+	if in.InfantRate != nil {
+		infantRate = *in.InfantRate
+	}
+	if childRate < 0 || childRate > 100 || infantRate < 0 || infantRate > 100 {
+		return ErrInvalidChildRate
+	}
+	plan.Name = name
+	plan.Keyword = strings.TrimSpace(in.Keyword)
+	plan.Price = entity.NewMoney(in.Price.Amount, currency)
+	plan.Capacity = in.Capacity
+	plan.MinGuests = minGuests
+	plan.MaxGuests = maxGuests
+	plan.ChildRate = childRate
+	plan.InfantRate = infantRate
+	plan.Jurisdiction = strings.ToLower(strings.TrimSpace(in.Jurisdiction))
+	plan.CancellationPolicyID = in.CancellationPolicyID
+	return nil
+}
+
+func (u *PlanUsecase) checkCancellationPolicy(ctx context.Context, plan *entity.Plan) error {
+	if plan.CancellationPolicyID == 0 || u.CancellationPolicies == nil {
+		return nil
+	}
+	_, err := findCancellationPolicy(ctx, u.CancellationPolicies, plan.CancellationPolicyID)
+	return err
+}
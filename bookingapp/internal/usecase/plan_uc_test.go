@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/infrastructure/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSearchPlansInputSpec(t *testing.T) {
+	in, out, far := stayDay(0), stayDay(maxAvailabilityNights+1), time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		in   SearchPlansInput
+		want error
+	}{
+		{"defaults", SearchPlansInput{}, nil},
+		{"currency", SearchPlansInput{Currency: "usd", MaxPrice: 100}, nil},
+		{"unknown currency", SearchPlansInput{Currency: "GBP"}, ErrInvalidSearch},
+		{"range too long", SearchPlansInput{Checkin: &in, Checkout: &out}, ErrDateRangeTooLong},
+		// 日付の一覧を作らずに弾く
+		{"far future", SearchPlansInput{Checkin: &in, Checkout: &far}, ErrDateRangeTooLong},
+	}
+	for _, c := range cases {
+		if _, err := c.in.spec(); !errors.Is(err, c.want) {
+			t.Errorf("spec(%s) = %v, want %v", c.name, err, c.want)
+		}
+	}
+	spec, _ := SearchPlansInput{Currency: "usd", MaxPrice: 100}.spec()
+	if spec.Currency != entity.USD || spec.Limit != defaultPlanSearchLimit || spec.Sort != entity.PlanSortRelevance {
+		t.Errorf("spec = %+v", spec)
+	}
+}
+
+// 索引への反映が常に失敗するインデックス
+type failingIndex struct{ PlanSearchIndex }
+
+func (failingIndex) Index(context.Context, *entity.Plan) error { return errors.New("index down") }
+func (failingIndex) Remove(context.Context, int) error         { return errors.New("index down") }
+
+func TestPlanSaveIgnoresIndexFailure(t *testing.T) {
+	ctx := context.Background()
+	plans := memory.NewPlanRepoMemory(nil)
+	uc := &PlanUsecase{Plans: plans, Index: failingIndex{}}
+	staff := &Principal{User: &entity.User{ID: "s1"}, Permissions: entity.DefaultRolePermissions[entity.RoleStaff]}
+
+	// 保存は済んでいるので成功として返す
+	plan, err := uc.Create(ctx, staff, PlanInput{Name: "湖畔の宿", Price: entity.Yen(9000), Capacity: 2})
+	if err != nil {
+		t.Fatalf("Create = %v, want success despite index failure", err)
+	}
+	if _, err := plans.FindByID(ctx, plan.ID); err != nil {
+		t.Fatalf("plan not saved: %v", err)
+	}
+	if err := uc.Delete(ctx, staff, plan.ID, plan.Version); err != nil {
+		t.Fatalf("Delete = %v, want success despite index failure", err)
+	}
+	if _, err := uc.Get(ctx, plan.ID); !errors.Is(err, ErrPlanNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrPlanNotFound", err)
+	}
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"errors"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// 認可ポリシー。本人のものは扱え、他人のものは権限（ロール経由で付与）があれば扱える
+type AccessPolicy struct{}
+
+// 全ユーザーの予約を見られるか（false なら自分の予約に絞る）
+func (AccessPolicy) CanListAllReservations(p *Principal) bool {
+	return p.Can(entity.PermReservationsManageAll)
+}
+
+// 予約の参照・変更・キャンセル
+func (AccessPolicy) AuthorizeReservation(p *Principal, r *entity.Reservation) error {
+	if p == nil {
+		return ErrUnauthenticated
+	}
+	if r.UserID == p.User.ID || p.Can(entity.PermReservationsManageAll) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// ユーザー情報（メール・電話番号・住所を含む）の参照
+func (AccessPolicy) AuthorizeUser(p *Principal, userID string) error {
+	if p == nil {
+		return ErrUnauthenticated
+	}
+	if userID == p.User.ID || p.Can(entity.PermUsersReadAll) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// 権限そのものを要求する操作（プラン管理・ロール管理など）
+func (AccessPolicy) Require(p *Principal, perm entity.Permission) error {
+	if p == nil {
+		return ErrUnauthenticated
+	}
+	if p.Can(perm) {
+		return nil
+	}
+	return ErrForbidden
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"errors"
+	"testing"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	perms := entity.DefaultRolePermissions
+	guest := &Principal{User: &entity.User{ID: "u1"}, Permissions: perms[entity.RoleGuest]}
+	other := &Principal{User: &entity.User{ID: "u2"}, Permissions: perms[entity.RoleGuest]}
+	staff := &Principal{User: &entity.User{ID: "s1"}, Permissions: perms[entity.RoleStaff]}
+	r := &entity.Reservation{ID: 1, UserID: "u1"}
+
+	cases := []struct {
+		name string
+		p    *Principal
+		want error
+	}{
+		{"owner", guest, nil},
+		{"other guest", other, ErrForbidden},
+		{"staff", staff, nil},
+		{"anonymous", nil, ErrUnauthenticated},
+	}
+	var pol AccessPolicy
+	for _, c := range cases {
+		if err := pol.AuthorizeReservation(c.p, r); !errors.Is(err, c.want) {
+			t.Errorf("AuthorizeReservation(%s) = %v, want %v", c.name, err, c.want)
+		}
+		if err := pol.AuthorizeUser(c.p, "u1"); !errors.Is(err, c.want) {
+			t.Errorf("AuthorizeUser(%s) = %v, want %v", c.name, err, c.want)
+		}
+	}
+	if pol.CanListAllReservations(guest) || !pol.CanListAllReservations(staff) || pol.CanListAllReservations(nil) {
+		t.Error("CanListAllReservations: only staff may list all reservations")
+	}
+	if err := pol.Require(staff, entity.PermRolesManage); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Require(staff, roles:manage) = %v, want ErrForbidden", err)
+	}
+	admin := &Principal{User: &entity.User{ID: "a1"}, Permissions: perms[entity.RoleAdmin]}
+	if err := pol.Require(admin, entity.PermRolesManage); err != nil {
+		t.Errorf("Require(admin, roles:manage) = %v", err)
+	}
+}
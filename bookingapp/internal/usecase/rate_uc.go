@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrRateRuleNotFound = errors.New("rate rule not found")
+
+// プランの料金ルールの管理（staff / admin 向け）。ルールの検証はエンティティ側
+
+func (u *PlanUsecase) ListRates(ctx context.Context, p *Principal, planID int) ([]entity.RateRule, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	if _, err := u.find(ctx, planID); err != nil {
+		return nil, err
+	}
+	return u.Plans.RateRules(ctx, planID)
+}
+
+func (u *PlanUsecase) CreateRate(ctx context.Context, p *Principal, planID int, rule entity.RateRule) (*entity.RateRule, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	if _, err := u.find(ctx, planID); err != nil {
+		return nil, err
+	}
+	rule.ID, rule.PlanID = 0, planID
+	return u.saveRate(ctx, &rule)
+}
+
+// 全項目を置き換える
+func (u *PlanUsecase) UpdateRate(ctx context.Context, p *Principal, planID, ruleID int, rule entity.RateRule) (*entity.RateRule, error) {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return nil, err
+	}
+	if _, err := u.findRate(ctx, planID, ruleID); err != nil {
+		return nil, err
+	}
+	rule.ID, rule.PlanID = ruleID, planID
+	return u.saveRate(ctx, &rule)
+}
+
+func (u *PlanUsecase) DeleteRate(ctx context.Context, p *Principal, planID, ruleID int) error {
+	if err := u.Policy.Require(p, entity.PermPlansManage); err != nil {
+		return err
+	}
+	if _, err := u.findRate(ctx, planID, ruleID); err != nil {
+		return err
+	}
+	return u.Plans.DeleteRateRule(ctx, planID, ruleID)
+}
+
+func (u *PlanUsecase) saveRate(ctx context.Context, rule *entity.RateRule) (*entity.RateRule, error) {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return u.Plans.SaveRateRule(ctx, rule)
+}
+
+// 削除済みのプランのルールも見つからない扱い
+func (u *PlanUsecase) findRate(ctx context.Context, planID, ruleID int) (*entity.RateRule, error) {
+	if _, err := u.find(ctx, planID); err != nil {
+		return nil, err
+	}
+	rules, err := u.Plans.RateRules(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].ID == ruleID {
+			return &rules[i], nil
+		}
+	}
+	return nil, ErrRateRuleNotFound
+}
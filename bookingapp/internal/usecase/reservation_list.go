@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReservationListLimit = 50
+	maxReservationListLimit     = 200
+)
+
+// 予約一覧の条件。ゼロ値の項目は絞り込まない
+type ListReservationsInput struct {
+	UserID      string
+	PlanID      int
+	Status      string
+	CheckinFrom *time.Time // 含む
+	CheckinTo   *time.Time // 含む
+	Sort        string     // id / -id / checkin / -checkin（空なら id）
+	Limit       int        // 0 なら 50、最大 200
+	Cursor      string     // 前ページの NextCursor
+}
+
+type ReservationPage struct {
+	Items []*entity.Reservation
+	// 次のページが無ければ空
+	NextCursor string
+}
+
+func (in ListReservationsInput) query() (repository.ReservationQuery, error) {
+	q := repository.ReservationQuery{
+		UserID:      strings.TrimSpace(in.UserID),
+		PlanID:      in.PlanID,
+		Status:      entity.ReservationStatus(strings.TrimSpace(in.Status)),
+		CheckinFrom: in.CheckinFrom,
+		CheckinTo:   in.CheckinTo,
+		Sort:        repository.ReservationSort(strings.TrimSpace(in.Sort)),
+		Limit:       in.Limit,
+	}
+	if q.Sort == "" {
+		q.Sort = repository.SortByIDAsc
+	}
+	if !q.Sort.Valid() {
+		return q, fmt.Errorf("%w: unknown sort %q", ErrInvalidListQuery, in.Sort)
+	}
+	if q.Status != "" && !q.Status.Valid() {
+		return q, fmt.Errorf("%w: unknown status %q", ErrInvalidListQuery, in.Status)
+	}
+	if q.PlanID < 0 {
+		return q, fmt.Errorf("%w: plan_id must be positive", ErrInvalidListQuery)
+	}
+	if q.CheckinFrom != nil && q.CheckinTo != nil && q.CheckinTo.Before(*q.CheckinFrom) {
+		return q, fmt.Errorf("%w: checkin_to is before checkin_from", ErrInvalidListQuery)
+	}
+	switch {
+	case q.Limit == 0:
+		q.Limit = defaultReservationListLimit
+	case q.Limit < 0 || q.Limit > maxReservationListLimit:
+		return q, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, maxReservationListLimit)
+	}
+	if in.Cursor != "" {
+		c, err := decodeReservationCursor(in.Cursor, q.Sort)
+		if err != nil {
+			return q, err
+		}
+		q.After = &c
+	}
+	return q, nil
+}
+
+// カーソルは並び順と最後の予約の位置を詰めた不透明な文字列
+type reservationCursor struct {
+	Sort    repository.ReservationSort `json:"s"`
+	ID      int                        `json:"id"`
+	Checkin string                     `json:"ci,omitempty"`
+}
+
+func encodeReservationCursor(sort repository.ReservationSort, c repository.ReservationCursor) string {
+	rc := reservationCursor{Sort: sort, ID: c.ID}
+	if sort == repository.SortByCheckinAsc || sort == repository.SortByCheckinDesc {
+		rc.Checkin = c.Checkin.Format("2006-01-02")
+	}
+	b, _ := json.Marshal(rc)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// 別の並び順で発行されたカーソルは使えない
+func decodeReservationCursor(s string, sort repository.ReservationSort) (repository.ReservationCursor, error) {
+	var rc reservationCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &rc) != nil || rc.ID <= 0 || rc.Sort != sort {
+		return repository.ReservationCursor{}, ErrInvalidCursor
+	}
+	c := repository.ReservationCursor{ID: rc.ID}
+	if rc.Checkin != "" {
+		if c.Checkin, err = time.Parse("2006-01-02", rc.Checkin); err != nil {
+			return repository.ReservationCursor{}, ErrInvalidCursor
+		}
+	} else if sort == repository.SortByCheckinAsc || sort == repository.SortByCheckinDesc {
+		return repository.ReservationCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
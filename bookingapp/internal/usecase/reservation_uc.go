@@ -2,8 +2,11 @@ package usecase
 
 import (
 	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/gateway"
 	"bookingapp/internal/domain/repository"
+	"context"
 	"errors"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,20 +15,93 @@ import (
 
 var (
 	ErrInvalidDates  = errors.New("invalid dates: checkout must be after checkin")
-	ErrPlanNotFound  = errors.New("plan not found")
-	ErrInvalidNumber = errors.New("number must be >= 1")
+	ErrPlanNotFound  = repository.ErrPlanNotFound
+	ErrInvalidNumber = errors.New("at least one adult is required")
+	ErrTooManyGuests = errors.New("number exceeds the plan's max guests")
+	ErrTooFewGuests  = errors.New("number is below the plan's min guests")
 	ErrInvalidUserID = errors.New("invalid user id")
 	ErrUserNotFound  = errors.New("user not found")
+
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrSoldOut             = repository.ErrSoldOut
+	ErrDateRangeTooLong    = errors.New("date range too long")
+	ErrStayTooLong         = errors.New("stay is too long")
+
+	ErrReservationNotModifiable = errors.New("reservation cannot be modified in its current status")
+
+	ErrInvalidListQuery = errors.New("invalid list query")
+	ErrInvalidCursor    = errors.New("invalid cursor")
 )
 
+// 予約作成の入力
+type CreateReservationInput struct {
+	PlanID     int
+	Guests     entity.Guests
+	Checkin    time.Time
+	Checkout   time.Time
+	CouponCode string // 空ならクーポンを使わない
+	// 決済代行に渡す支払い手段（カードのトークンなど）
+	PaymentToken string
+}
+
+// 予約変更の入力。nil の項目は現在の値を引き継ぐ
+type ModifyReservationInput struct {
+	Checkin  *time.Time
+	Checkout *time.Time
+	Adults   *int
+	// 子ども（12歳以下）の年齢。指定すると子どもの内訳を置き換える
+	ChildAges *[]int
+	// 合計金額が与信額を超える場合に与信を取り直す支払い手段
+	PaymentToken string
+}
+
+// 空き状況を一度に問い合わせられる最大泊数
+const maxAvailabilityNights = 366
+
+// 1予約で泊まれる最大泊数（在庫の確保・料金計算は泊数に比例する）
+const maxStayNights = 30
+
 type ReservationUsecase struct {
 	Users repository.UserRepository
 	Plans repository.PlanRepository
 	Resv  repository.ReservationRepository
+	// クーポンを使わないなら nil でもよい
+	Coupons repository.CouponRepository
+	// 更新系をまとめて1トランザクションで実行する。nil の場合は上記リポジトリを直接使う（原子性なし）
+	Tx     repository.UnitOfWork
+	Policy AccessPolicy
+	// 泊ごとの料金計算（ゼロ値なら日本の祝日で判定）
+	Pricing entity.PricingEngine
+	// 消費税・宿泊税のルール（nil なら課税しない）
+	Taxes TaxRuleSource
+	// 決済。Gateway が nil なら決済せずに予約を確定する
+	Payments repository.PaymentRepository
+	Gateway  gateway.PaymentGateway
+	// プランのキャンセルポリシーを予約に写すのに使う（nil ならキャンセル料なし）
+	CancellationPolicies repository.CancellationPolicyRepository
+	// クーポンの期間・キャンセル料の判定に使う（nil なら time.Now）
+	Now func() time.Time
+	// 宿のタイムゾーン。キャンセルする日（チェックインまでの日数）をこの地域の日付で数える（nil なら日本時間）
+	Location *time.Location
+}
+
+// コミットしたら TrackCommits に知らせる
+func (u *ReservationUsecase) inTx(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	var err error
+	if u.Tx == nil {
+		err = fn(ctx, repository.Repositories{Plans: u.Plans, Reservations: u.Resv, Users: u.Users, Coupons: u.Coupons, Payments: u.Payments})
+	} else {
+		err = u.Tx.Do(ctx, fn)
+	}
+	if err == nil {
+		markCommitted(ctx)
+	}
+	return err
 }
 
 // 　予約作成
-func (u *ReservationUsecase) Create(userID string, planID, number int, checkin, checkout time.Time) (*entity.Reservation, error) {
+func (u *ReservationUsecase) Create(ctx context.Context, userID string, in CreateReservationInput) (*entity.Reservation, error) {
+	planID, guests, checkin, checkout := in.PlanID, in.Guests, in.Checkin, in.Checkout
 	userID = strings.TrimSpace(userID)
 	if userID == "" {
 		return nil, ErrInvalidUserID
@@ -33,53 +109,464 @@ func (u *ReservationUsecase) Create(userID string, planID, number int, checkin,
 	if _, err := uuid.Parse(userID); err != nil {
 		return nil, ErrInvalidUserID
 	}
-	user, err := u.Users.Get(userID)
+	if !checkout.After(checkin) {
+		return nil, ErrInvalidDates
+	}
+	if entity.NightsBetween(checkin, checkout) > maxStayNights {
+		return nil, ErrStayTooLong
+	}
+	if !guests.Valid() {
+		return nil, ErrInvalidNumber
+	}
+	var saved *entity.Reservation
+	err := u.inTx(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		user, err := repos.Users.Get(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return ErrUserNotFound
+		}
+		plan, err := repos.Plans.FindByID(ctx, planID)
+		if err != nil {
+			return err
+		}
+		if plan == nil || plan.Deleted() {
+			return ErrPlanNotFound
+		}
+		if err := checkOccupancy(plan, guests); err != nil {
+			return err
+		}
+		r := &entity.Reservation{
+			UserID:   user.ID,
+			PlanID:   planID,
+			Number:   guests.Total(),
+			Guests:   guests,
+			Checkin:  checkin,
+			Checkout: checkout,
+			Status:   entity.ReservationConfirmed,
+		}
+		//決済する場合は与信が取れるまで仮予約にする
+		if u.Gateway != nil {
+			r.Status = entity.ReservationPending
+		}
+		//料金ルールから泊ごとの料金と合計を計算してセット（桁あふれは entity.ErrMoneyOverflow）
+		if err := u.quote(ctx, repos.Plans, plan, r); err != nil {
+			return err
+		}
+		//予約時点のキャンセルポリシーを写す
+		if err := u.snapshotCancellationPolicy(ctx, plan, r); err != nil {
+			return err
+		}
+		//クーポンの割引を合計から引く
+		var coupon *entity.Coupon
+		if code := entity.NormalizeCouponCode(in.CouponCode); code != "" {
+			if coupon, err = u.findCoupon(ctx, repos, code); err != nil {
+				return err
+			}
+			if err := coupon.CheckApplicable(planID, u.now()); err != nil {
+				return err
+			}
+			r.CouponID, r.CouponCode = coupon.ID, coupon.Code
+			if err := applyDiscount(coupon, r); err != nil {
+				return err
+			}
+		}
+		//割引後の料金に税をかけて Total を税込みにする
+		if err := u.applyTax(plan, r); err != nil {
+			return err
+		}
+		//宿泊する全泊の在庫を確保（1泊でも満室なら ErrSoldOut）
+		if err := repos.Plans.ReserveNights(ctx, planID, checkin, checkout); err != nil {
+			return err
+		}
+		//保存してID付きの予約情報を返す
+		if saved, err = repos.Reservations.Save(ctx, r); err != nil {
+			return err
+		}
+		//クーポンの利用枠を確保（上限に達していれば予約ごと取り消す）
+		if coupon != nil {
+			if err := repos.Coupons.Redeem(ctx, coupon.ID, user.ID, saved.ID); err != nil {
+				return err
+			}
+		}
+		//決済を与信待ちとして記録
+		if u.Gateway != nil {
+			_, err := repos.Payments.Save(ctx, &entity.Payment{
+				ReservationID: saved.ID, Amount: saved.Total, Status: entity.PaymentPending,
+				Captured: entity.NewMoney(0, saved.Total.Currency), Refunded: entity.NewMoney(0, saved.Total.Currency),
+			})
+			return err
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if user == nil {
-		return nil, ErrUserNotFound
+	if u.Gateway == nil {
+		return saved, nil
 	}
-	if !checkout.After(checkin) {
-		return nil, ErrInvalidDates
+	//在庫を仮押さえしたまま決済代行に問い合わせる（DB のトランザクションの外で待つ）
+	return u.authorize(ctx, saved, in.PaymentToken)
+}
+
+// 予約の日程・人数から内訳と合計を計算し直す
+func (u *ReservationUsecase) quote(ctx context.Context, plans repository.PlanRepository, plan *entity.Plan, r *entity.Reservation) error {
+	rules, err := plans.RateRules(ctx, plan.ID)
+	if err != nil {
+		return err
 	}
-	if number < 1 {
-		return nil, ErrInvalidNumber
+	r.Breakdown, r.Total, err = u.Pricing.Quote(plan, rules, r.Guests, r.Checkin, r.Checkout)
+	return err
+}
+
+// 割引前の合計（quote 済みの Total）からクーポンの割引額を引く
+func applyDiscount(c *entity.Coupon, r *entity.Reservation) error {
+	discount, err := c.DiscountFor(r.Total)
+	if err != nil {
+		return err
+	}
+	r.Discount = discount
+	r.Total, err = r.Total.Add(entity.NewMoney(-discount.Amount, discount.Currency))
+	return err
+}
+
+func (u *ReservationUsecase) findCoupon(ctx context.Context, repos repository.Repositories, code string) (*entity.Coupon, error) {
+	if repos.Coupons == nil {
+		return nil, ErrCouponNotFound
 	}
-	plan, err := u.Plans.FindByID(planID)
+	c, err := repos.Coupons.FindByCode(ctx, code)
 	if err != nil {
 		return nil, err
 	}
-	if plan == nil {
-		return nil, ErrPlanNotFound
+	if c == nil {
+		return nil, ErrCouponNotFound
+	}
+	return c, nil
+}
+
+func (u *ReservationUsecase) now() time.Time {
+	if u.Now != nil {
+		return u.Now()
+	}
+	return time.Now()
+}
+
+// 日本には夏時間が無いので、tzdata の無い環境でも固定の時差で足りる
+var japanTime = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+func (u *ReservationUsecase) location() *time.Location {
+	if u.Location != nil {
+		return u.Location
+	}
+	return japanTime
+}
+
+// 合計人数がプランの人数の範囲に収まるか
+func checkOccupancy(plan *entity.Plan, g entity.Guests) error {
+	switch {
+	case g.Total() > plan.MaxGuests:
+		return ErrTooManyGuests
+	case g.Total() < plan.MinGuests:
+		return ErrTooFewGuests
+	}
+	return nil
+}
+
+// 予約取得（見つからない・他人の予約なら nil）
+func (u *ReservationUsecase) Get(ctx context.Context, p *Principal, id int) (*entity.Reservation, error) {
+	if p == nil {
+		return nil, ErrUnauthenticated
+	}
+	r, err := u.Resv.FindByID(ctx, id)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	if err := u.authorizeOwner(p, r); err != nil {
+		if errors.Is(err, ErrReservationNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// 他人の予約は ErrForbidden ではなく ErrReservationNotFound にする（ID が存在するかを漏らさない）
+func (u *ReservationUsecase) authorizeOwner(p *Principal, r *entity.Reservation) error {
+	err := u.Policy.AuthorizeReservation(p, r)
+	if errors.Is(err, ErrForbidden) {
+		return ErrReservationNotFound
+	}
+	return err
+}
+
+// 予約変更（日程・人数を差し替えて料金を再計算）。
+// version が 0 でなければ、予約のバージョンが違う場合に ErrConcurrentModification
+func (u *ReservationUsecase) Modify(ctx context.Context, p *Principal, id, version int, in ModifyReservationInput) (*entity.Reservation, error) {
+	var saved, before *entity.Reservation
+	var pay *entity.Payment
+	err := u.inTx(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		r, err := repos.Reservations.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return ErrReservationNotFound
+		}
+		if err := u.authorizeOwner(p, r); err != nil {
+			return err
+		}
+		if err := checkVersion(version, r.Version); err != nil {
+			return err
+		}
+		if !r.CanModify() {
+			return ErrReservationNotModifiable
+		}
+		if repos.Payments != nil {
+			if pay, err = repos.Payments.FindByReservation(ctx, r.ID); err != nil {
+				return err
+			}
+			// 与信の結果待ち・請求済みの予約は金額を変えられない
+			if pay != nil && pay.Status != entity.PaymentAuthorized {
+				return ErrReservationNotModifiable
+			}
+		}
+		before = cloneReservation(r)
+		oldCheckin, oldCheckout := r.Checkin, r.Checkout
+		if in.Checkin != nil {
+			r.Checkin = *in.Checkin
+		}
+		if in.Checkout != nil {
+			r.Checkout = *in.Checkout
+		}
+		if in.Adults != nil {
+			r.Guests.Adults = *in.Adults
+		}
+		if in.ChildAges != nil {
+			children, err := entity.GuestsOf(0, *in.ChildAges)
+			if err != nil {
+				return err
+			}
+			r.Guests.Children, r.Guests.Infants = children.Children, children.Infants
+		}
+		r.Number = r.Guests.Total()
+		// 新規作成と同じルールで検証
+		if !r.Checkout.After(r.Checkin) {
+			return ErrInvalidDates
+		}
+		if entity.NightsBetween(r.Checkin, r.Checkout) > maxStayNights {
+			return ErrStayTooLong
+		}
+		if !r.Guests.Valid() {
+			return ErrInvalidNumber
+		}
+		plan, err := repos.Plans.FindByID(ctx, r.PlanID)
+		if err != nil {
+			return err
+		}
+		if plan == nil {
+			return ErrPlanNotFound
+		}
+		if plan.Deleted() {
+			return ErrPlanDeleted
+		}
+		if err := checkOccupancy(plan, r.Guests); err != nil {
+			return err
+		}
+		if err := u.quote(ctx, repos.Plans, plan, r); err != nil {
+			return err
+		}
+		// 使用済みのクーポンは期間外になっていても同じ条件で割り引き直す
+		if r.CouponID != 0 {
+			coupon, err := repos.Coupons.FindByID(ctx, r.CouponID)
+			if err != nil {
+				return err
+			}
+			if coupon != nil {
+				if err := applyDiscount(coupon, r); err != nil {
+					return err
+				}
+			}
+		}
+		// 税は変更時点のルールでかけ直す
+		if err := u.applyTax(plan, r); err != nil {
+			return err
+		}
+
+		if !r.Checkin.Equal(oldCheckin) || !r.Checkout.Equal(oldCheckout) {
+			if err := repos.Plans.RebookNights(ctx, r.PlanID, oldCheckin, oldCheckout, r.Checkin, r.Checkout); err != nil {
+				return err
+			}
+		}
+		//与信額を超えた場合だけ取り直す（下回る分は請求時に合計金額だけ請求する）
+		if pay != nil && u.Gateway != nil && r.Total.Amount > pay.Amount.Amount {
+			if _, err := claimPayment(ctx, repos, pay); err != nil {
+				return err
+			}
+		}
+		saved, err = repos.Reservations.Save(ctx, r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pay != nil && pay.Status == entity.PaymentProcessing {
+		if err := u.reauthorize(ctx, before, saved, pay, in.PaymentToken); err != nil {
+			return nil, err
+		}
+	}
+	return saved, nil
+}
+
+func cloneReservation(r *entity.Reservation) *entity.Reservation {
+	cp := *r
+	cp.Breakdown = slices.Clone(r.Breakdown)
+	return &cp
+}
+
+// 予約キャンセル（状態遷移のルールはエンティティ側で判定）。
+// 決済済みなら与信の取り消しか返金も行う。決済代行の失敗で後始末が残った予約は、もう一度キャンセルすると再実行する。
+// version は Modify と同じ
+func (u *ReservationUsecase) Cancel(ctx context.Context, p *Principal, id, version int) (*entity.Reservation, error) {
+	var saved *entity.Reservation
+	var pay *entity.Payment
+	var from entity.PaymentStatus
+	err := u.inTx(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		r, err := repos.Reservations.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return ErrReservationNotFound
+		}
+		if err := u.authorizeOwner(p, r); err != nil {
+			return err
+		}
+		if err := checkVersion(version, r.Version); err != nil {
+			return err
+		}
+		if repos.Payments != nil {
+			if pay, err = repos.Payments.FindByReservation(ctx, r.ID); err != nil {
+				return err
+			}
+		}
+		// 請求などを決済代行に依頼中の予約は、結果が出るまでキャンセルしない
+		if pay != nil && pay.Status == entity.PaymentProcessing {
+			return ErrPaymentInProgress
+		}
+		//キャンセル済みで決済の後始末だけが残っている
+		if r.Status == entity.ReservationCancelled && pay != nil && pay.NeedsSettlement(r.CancellationFee) {
+			saved = r
+		} else {
+			//予約時点のポリシーでキャンセル料を確定する
+			q, err := u.quoteCancellation(r)
+			if err != nil {
+				return err
+			}
+			if err := r.Cancel(); err != nil {
+				return err
+			}
+			r.CancellationFee = q.Fee
+			if saved, err = releaseReservation(ctx, repos, r); err != nil {
+				return err
+			}
+		}
+		if pay == nil || u.Gateway == nil || !pay.NeedsSettlement(saved.CancellationFee) {
+			pay = nil
+			return nil
+		}
+		// 取り消し・返金を依頼する前に切り替え、同時の請求とは一方だけが進むようにする
+		from, err = claimPayment(ctx, repos, pay)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	r := &entity.Reservation{
-		ID:       u.Resv.NextID(),
-		UserID:   user.ID,
-		PlanID:   planID,
-		Number:   number,
-		Checkin:  checkin,
-		Checkout: checkout,
+	if pay != nil {
+		if err := u.settle(ctx, pay, from, saved.CancellationFee); err != nil {
+			return nil, err
+		}
 	}
-	//ドメイン層のメソッドを使って宿泊数を計算
-	nights := r.Nights()
-	//合計金額を計算してセット
-	r.Total = plan.Price * number * nights
-	//保存してID付きの予約情報を返す
-	return u.Resv.Save(r)
+	return saved, nil
 }
 
-// 予約取得
-func (u *ReservationUsecase) Get(id int) (*entity.Reservation, error) {
-	return u.Resv.FindByID(id)
+// キャンセルした予約を保存し、クーポンの利用枠と在庫を戻す
+func releaseReservation(ctx context.Context, repos repository.Repositories, r *entity.Reservation) (*entity.Reservation, error) {
+	saved, err := repos.Reservations.Save(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	//クーポンの利用枠を戻す
+	if r.CouponID != 0 && repos.Coupons != nil {
+		if err := repos.Coupons.Release(ctx, r.ID); err != nil {
+			return nil, err
+		}
+	}
+	//キャンセルした分の在庫を戻す
+	if err := repos.Plans.ReleaseNights(ctx, r.PlanID, r.Checkin, r.Checkout); err != nil {
+		return nil, err
+	}
+	return saved, nil
 }
 
-// 予約一覧取得
-func (u *ReservationUsecase) List() ([]*entity.Reservation, error) {
-	return u.Resv.List()
+// 予約一覧取得。reservations:manage_all を持たないユーザーは自分の予約だけ
+func (u *ReservationUsecase) List(ctx context.Context, p *Principal, in ListReservationsInput) (*ReservationPage, error) {
+	if p == nil {
+		return nil, ErrUnauthenticated
+	}
+	q, err := in.query()
+	if err != nil {
+		return nil, err
+	}
+	if !u.Policy.CanListAllReservations(p) {
+		if q.UserID != "" && q.UserID != p.User.ID {
+			return nil, ErrForbidden
+		}
+		q.UserID = p.User.ID
+	}
+	// 1件多く取り、次のページがあるかを判定する
+	limit := q.Limit
+	q.Limit = limit + 1
+	list, err := u.Resv.List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	page := &ReservationPage{Items: list}
+	if len(list) > limit {
+		page.Items = list[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeReservationCursor(q.Sort, repository.ReservationCursor{ID: last.ID, Checkin: last.Checkin})
+	}
+	return page, nil
 }
 
-// プラン検索
-func (u *ReservationUsecase) SearchPlans(keyword string) ([]*entity.Plan, error) {
-	return u.Plans.SearchByKeyword(keyword)
+// プランの空き状況（from〜to前日の各泊の残室数と実効価格）
+func (u *ReservationUsecase) Availability(ctx context.Context, planID int, from, to time.Time) ([]entity.NightAvailability, error) {
+	if !to.After(from) {
+		return nil, ErrInvalidDates
+	}
+	if entity.NightsBetween(from, to) > maxAvailabilityNights {
+		return nil, ErrDateRangeTooLong
+	}
+	plan, err := u.Plans.FindByID(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil || plan.Deleted() {
+		return nil, ErrPlanNotFound
+	}
+	nights, err := u.Plans.Availability(ctx, planID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := u.Plans.RateRules(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nights {
+		if nights[i].Price, err = u.Pricing.NightlyRate(plan, rules, nights[i].Date); err != nil {
+			return nil, err
+		}
+	}
+	return nights, nil
 }
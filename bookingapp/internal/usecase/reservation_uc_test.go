@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/infrastructure/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// インメモリのリポジトリで組んだ予約ユースケースと、予約できるゲストを返す
+func newReservationFixture(t *testing.T) (*ReservationUsecase, *Principal) {
+	t.Helper()
+	plans := memory.NewPlanRepoMemory([]*entity.Plan{
+		{ID: 1, Name: "富士プレミアム", Price: entity.Yen(10000), Capacity: 5, MinGuests: 1, MaxGuests: 3},
+		{ID: 2, Name: "北の宿", Price: entity.Yen(8000), Capacity: 5, MinGuests: 2, MaxGuests: 4},
+	})
+	reservations := memory.NewReservationRepoMemory()
+	users := memory.NewUserRepoMemory()
+	coupons := memory.NewCouponRepoMemory()
+	payments := memory.NewPaymentRepoMemory()
+	user, err := users.Create(context.Background(), &entity.User{Name: "guest", Email: "guest@example.com"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	uc := &ReservationUsecase{
+		Plans: plans, Resv: reservations, Users: users, Coupons: coupons, Payments: payments,
+		Tx: memory.NewUnitOfWork(plans, reservations, users, coupons, payments),
+	}
+	return uc, &Principal{User: user, Permissions: entity.DefaultRolePermissions[entity.RoleGuest]}
+}
+
+func stayDay(n int) time.Time {
+	return time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func TestReservationStayLength(t *testing.T) {
+	ctx := context.Background()
+	uc, p := newReservationFixture(t)
+
+	cases := []struct {
+		name     string
+		checkout time.Time
+		want     error
+	}{
+		{"max nights", stayDay(maxStayNights), nil},
+		{"one night over", stayDay(maxStayNights + 1), ErrStayTooLong},
+		// 日付の一覧や在庫の行を作る前に弾く
+		{"far future", time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC), ErrStayTooLong},
+	}
+	for _, c := range cases {
+		_, err := uc.Create(ctx, p.User.ID, CreateReservationInput{PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: stayDay(0), Checkout: c.checkout})
+		if !errors.Is(err, c.want) {
+			t.Errorf("Create(%s) = %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	r, err := uc.Create(ctx, p.User.ID, CreateReservationInput{PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: stayDay(100), Checkout: stayDay(101)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	far := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	if _, err := uc.Modify(ctx, p, r.ID, 0, ModifyReservationInput{Checkout: &far}); !errors.Is(err, ErrStayTooLong) {
+		t.Fatalf("Modify(far future checkout) = %v, want ErrStayTooLong", err)
+	}
+}
+
+func TestReservationAvailabilityRange(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newReservationFixture(t)
+
+	nights, err := uc.Availability(ctx, 1, stayDay(0), stayDay(maxAvailabilityNights))
+	if err != nil || len(nights) != maxAvailabilityNights {
+		t.Fatalf("Availability(max range) = %d nights, %v; want %d", len(nights), err, maxAvailabilityNights)
+	}
+	// 範囲が大きくても日付の一覧を作らずに弾く
+	for _, to := range []time.Time{stayDay(maxAvailabilityNights + 1), time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)} {
+		if _, err := uc.Availability(ctx, 1, stayDay(0), to); !errors.Is(err, ErrDateRangeTooLong) {
+			t.Errorf("Availability(to %s) = %v, want ErrDateRangeTooLong", to.Format("2006-01-02"), err)
+		}
+	}
+}
+
+func TestReservationOccupancy(t *testing.T) {
+	ctx := context.Background()
+	uc, p := newReservationFixture(t)
+
+	cases := []struct {
+		name   string
+		planID int
+		guests entity.Guests
+		want   error
+	}{
+		{"within range", 1, entity.Guests{Adults: 2, Children: 1}, nil},
+		// 子ども・幼児も人数に数える
+		{"too many with children", 1, entity.Guests{Adults: 2, Children: 1, Infants: 1}, ErrTooManyGuests},
+		{"too few", 2, entity.Guests{Adults: 1}, ErrTooFewGuests},
+		{"min guests", 2, entity.Guests{Adults: 1, Infants: 1}, nil},
+	}
+	for i, c := range cases {
+		_, err := uc.Create(ctx, p.User.ID, CreateReservationInput{PlanID: c.planID, Guests: c.guests, Checkin: stayDay(i * 2), Checkout: stayDay(i*2 + 1)})
+		if !errors.Is(err, c.want) {
+			t.Errorf("Create(%s) = %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	r, err := uc.Create(ctx, p.User.ID, CreateReservationInput{PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: stayDay(20), Checkout: stayDay(21)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	adults := 4
+	if _, err := uc.Modify(ctx, p, r.ID, 0, ModifyReservationInput{Adults: &adults}); !errors.Is(err, ErrTooManyGuests) {
+		t.Fatalf("Modify(4 adults) = %v, want ErrTooManyGuests", err)
+	}
+	if got, _ := uc.Get(ctx, p, r.ID); got == nil || got.Guests.Adults != 1 {
+		t.Fatalf("rejected Modify changed the reservation: %+v", got)
+	}
+}
+
+func TestReservationOnDeletedPlan(t *testing.T) {
+	ctx := context.Background()
+	uc, p := newReservationFixture(t)
+	r, err := uc.Create(ctx, p.User.ID, CreateReservationInput{PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: stayDay(0), Checkout: stayDay(1)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := uc.Plans.Delete(ctx, 1, 1, time.Now()); err != nil {
+		t.Fatalf("Delete plan: %v", err)
+	}
+
+	// 既存の予約は参照・キャンセルできるが、日程・人数は変えられない。新しい予約は受け付けない
+	if got, err := uc.Get(ctx, p, r.ID); err != nil || got == nil {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	adults := 2
+	if _, err := uc.Modify(ctx, p, r.ID, 0, ModifyReservationInput{Adults: &adults}); !errors.Is(err, ErrPlanDeleted) {
+		t.Errorf("Modify = %v, want ErrPlanDeleted", err)
+	}
+	if _, err := uc.Create(ctx, p.User.ID, CreateReservationInput{PlanID: 1, Guests: entity.Guests{Adults: 1}, Checkin: stayDay(2), Checkout: stayDay(3)}); !errors.Is(err, ErrPlanNotFound) {
+		t.Errorf("Create = %v, want ErrPlanNotFound", err)
+	}
+	if got, err := uc.Cancel(ctx, p, r.ID, 0); err != nil || got.Status != entity.ReservationCancelled {
+		t.Errorf("Cancel = %+v, %v", got, err)
+	}
+}
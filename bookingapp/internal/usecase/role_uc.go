@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"bookingapp/internal/domain/entity"
+	"bookingapp/internal/domain/repository"
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	// 管理者がいなくなるのを防ぐため、自分の admin は外せない
+	ErrCannotRevokeOwnAdmin = errors.New("cannot revoke your own admin role")
+)
+
+// ロールの付与・剥奪（管理者向け）
+type RoleUsecase struct {
+	Users  repository.UserRepository
+	Roles  repository.RoleRepository
+	Policy AccessPolicy
+}
+
+// 付与後のユーザーを返す。version が 0 でなければ、ユーザーのバージョンが違う場合に ErrConcurrentModification
+func (u *RoleUsecase) Grant(ctx context.Context, p *Principal, userID string, role entity.Role, version int) (*entity.User, error) {
+	user, err := u.prepare(ctx, p, userID, role, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.Users.GrantRole(ctx, user.ID, role, user.Version); err != nil {
+		return nil, err
+	}
+	return u.Users.Get(ctx, user.ID)
+}
+
+// 剥奪後のユーザーを返す。version は Grant と同じ
+func (u *RoleUsecase) Revoke(ctx context.Context, p *Principal, userID string, role entity.Role, version int) (*entity.User, error) {
+	user, err := u.prepare(ctx, p, userID, role, version)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == p.User.ID && role == entity.RoleAdmin {
+		return nil, ErrCannotRevokeOwnAdmin
+	}
+	if err := u.Users.RevokeRole(ctx, user.ID, role, user.Version); err != nil {
+		return nil, err
+	}
+	return u.Users.Get(ctx, user.ID)
+}
+
+// 権限・ロール・対象ユーザーとそのバージョンを確認する
+func (u *RoleUsecase) prepare(ctx context.Context, p *Principal, userID string, role entity.Role, version int) (*entity.User, error) {
+	if err := u.Policy.Require(p, entity.PermRolesManage); err != nil {
+		return nil, err
+	}
+	if _, err := u.Roles.Permissions(ctx, role); err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			return nil, ErrInvalidRole
+		}
+		return nil, err
+	}
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return nil, ErrUserNotFound
+	}
+	user, err := u.Users.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := checkVersion(version, user.Version); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
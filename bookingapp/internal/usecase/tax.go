@@ -0,0 +1,49 @@
+package usecase
+
+import "bookingapp/internal/domain/entity"
+
+// 消費税・宿泊税のルールの取得元（実装は infrastructure/taxconfig）。
+// 設定ファイルの変更を反映できるよう、料金を計算するたびに呼ぶ
+type TaxRuleSource interface {
+	TaxRules() (*entity.TaxRules, error)
+}
+
+// 割引後の Total を税抜の小計として税をかけ、Total を税込みにする
+func (u *ReservationUsecase) applyTax(plan *entity.Plan, r *entity.Reservation) error {
+	r.Subtotal = r.Total
+	r.ConsumptionTax = entity.NewMoney(0, r.Total.Currency)
+	r.AccommodationTax = entity.NewMoney(0, r.Total.Currency)
+	if u.Taxes == nil {
+		return nil
+	}
+	rules, err := u.Taxes.TaxRules()
+	if err != nil {
+		return err
+	}
+	j, err := rules.Jurisdiction(plan.Jurisdiction)
+	if err != nil {
+		return err
+	}
+	tax, err := j.Calculate(r.Subtotal, r.Breakdown, r.Guests)
+	if err != nil {
+		return err
+	}
+	r.ConsumptionTax, r.AccommodationTax, r.Total = tax.ConsumptionTax, tax.AccommodationTax, tax.Total
+	return nil
+}
+
+// プランの課税地域が税のルールにあり、プランの通貨で宿泊税を計算できるか（ルールが無ければ確認しない）
+func checkJurisdiction(taxes TaxRuleSource, plan *entity.Plan) error {
+	if taxes == nil {
+		return nil
+	}
+	rules, err := taxes.TaxRules()
+	if err != nil {
+		return err
+	}
+	j, err := rules.Jurisdiction(plan.Jurisdiction)
+	if err != nil {
+		return err
+	}
+	return j.CheckCurrency(plan.Price.Currency)
+}
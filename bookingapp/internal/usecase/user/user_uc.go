@@ -3,6 +3,7 @@ package usecase
 import (
 	"bookingapp/internal/domain/entity"
 	"bookingapp/internal/domain/repository"
+	"context"
 )
 
 type UserUsecase struct {
@@ -10,6 +11,6 @@ type UserUsecase struct {
 }
 
 // ユーザー情報取得
-func (u *UserUsecase) GetUser(id string) (*entity.User, error) {
-	return u.Users.Get(id)
+func (u *UserUsecase) GetUser(ctx context.Context, id string) (*entity.User, error) {
+	return u.Users.Get(ctx, id)
 }
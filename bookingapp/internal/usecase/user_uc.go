@@ -3,6 +3,7 @@ package usecase
 import (
 	"bookingapp/internal/domain/entity"
 	"bookingapp/internal/domain/repository"
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -13,21 +14,26 @@ var (
 	ErrUserEmailAlreadyExists = errors.New("user email already exists")
 )
 
+const minPasswordLength = 8
+
 type RegisterUserInput struct {
 	Name        string
 	Email       string
 	PhoneNumber string
 	Address     string
 	DateOfBirth string
+	Password    string
 }
 
 // ユースケース層からrepository層のinterfaceを使えるようにする
 type UserUsecase struct {
-	Users repository.UserRepository
-	Now   func() time.Time
+	Users  repository.UserRepository
+	Hasher PasswordHasher
+	Now    func() time.Time
+	Policy AccessPolicy
 }
 
-func (u *UserUsecase) Register(in RegisterUserInput) (*entity.User, error) {
+func (u *UserUsecase) Register(ctx context.Context, in RegisterUserInput) (*entity.User, error) {
 	if u.Users == nil {
 		return nil, errors.New("user repository is nil")
 	}
@@ -37,8 +43,14 @@ func (u *UserUsecase) Register(in RegisterUserInput) (*entity.User, error) {
 	if name == "" || email == "" {
 		return nil, ErrUserInvalidInput
 	}
+	if len(in.Password) < minPasswordLength {
+		return nil, ErrUserInvalidInput
+	}
+	if u.Hasher == nil {
+		return nil, errors.New("password hasher is nil")
+	}
 
-	existing, err := u.Users.FindByEmail(email)
+	existing, err := u.Users.FindByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +66,11 @@ func (u *UserUsecase) Register(in RegisterUserInput) (*entity.User, error) {
 		}
 	}
 
+	hash, err := u.Hasher.Hash(in.Password)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now
 	if u.Now != nil {
 		now = u.Now
@@ -67,12 +84,20 @@ func (u *UserUsecase) Register(in RegisterUserInput) (*entity.User, error) {
 		DateOfBirth:  dob,
 		RegisteredAt: now(),
 		Status:       "active",
+		PasswordHash: hash,
+		Roles:        []entity.Role{entity.RoleGuest},
 	}
 
-	return u.Users.Create(user)
+	created, err := u.Users.Create(ctx, user)
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		// FindByEmail の後に同じメールで登録された場合
+		return nil, ErrUserEmailAlreadyExists
+	}
+	return created, err
 }
 
-func (u *UserUsecase) GetUser(id string) (*entity.User, error) {
+// 本人かスタッフだけが参照できる
+func (u *UserUsecase) GetUser(ctx context.Context, p *Principal, id string) (*entity.User, error) {
 	if u.Users == nil {
 		return nil, errors.New("user repository is nil")
 	}
@@ -81,6 +106,9 @@ func (u *UserUsecase) GetUser(id string) (*entity.User, error) {
 	if trimmed == "" {
 		return nil, ErrUserInvalidInput
 	}
+	if err := u.Policy.AuthorizeUser(p, trimmed); err != nil {
+		return nil, err
+	}
 
-	return u.Users.Get(trimmed)
+	return u.Users.Get(ctx, trimmed)
 }
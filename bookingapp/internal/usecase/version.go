@@ -0,0 +1,14 @@
+package usecase
+
+import "bookingapp/internal/domain/repository"
+
+// 更新しようとしたリソースが、読み込んだ（If-Match で指定された）時点から変わっていた
+var ErrConcurrentModification = repository.ErrConcurrentModification
+
+// expected が 0 でなければ現在のバージョン current と一致するかを確認する
+func checkVersion(expected, current int) error {
+	if expected != 0 && expected != current {
+		return ErrConcurrentModification
+	}
+	return nil
+}